@@ -0,0 +1,64 @@
+package gracefulhttp
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithAccessLog logs a line per request through the logger, reporting the method, path, status code,
+// response size, duration and client IP. The client IP is taken from the X-Forwarded-For or X-Real-IP
+// header, when set by a reverse proxy, and from the remote address otherwise: as the clients can set
+// the headers as well, it is only trustworthy behind a proxy overwriting them.
+func WithAccessLog(logger *slog.Logger) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.accessLogger = logger
+	}
+}
+
+// accessLogHandler logs a line per request once served.
+func (s *GracefulServer) accessLogHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := s.clock.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		defer func() {
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			s.accessLogger.LogAttrs(r.Context(), slog.LevelInfo, "gracefulhttp: request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rw.written),
+				slog.Duration("duration", s.clock.Now().Sub(start)),
+				slog.String("client_ip", clientIP(r)),
+			)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// clientIP returns the IP address of the client, as reported by a reverse proxy through the
+// X-Forwarded-For or X-Real-IP header, if any, or else the remote address of the request.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,50 @@
+package gracefulhttp
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAccessLog(t *testing.T) {
+	var logs lockedBuffer
+	s := Bind("", &delayedHandler{})
+	s.initialize([]GracefulServerOption{WithAccessLog(slog.New(slog.NewTextHandler(&logs, nil)))})
+
+	r := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	s.Handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := logs.String()
+	assert.Contains(t, line, "method=POST")
+	assert.Contains(t, line, "path=/orders")
+	assert.Contains(t, line, "status=200")
+	assert.Contains(t, line, "bytes=2")
+	assert.Contains(t, line, "client_ip=203.0.113.7")
+}
+
+func Test_clientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{name: "remote address", want: "192.0.2.1"},
+		{name: "forwarded for", header: http.Header{"X-Forwarded-For": {"203.0.113.7, 10.0.0.1"}}, want: "203.0.113.7"},
+		{name: "real ip", header: http.Header{"X-Real-Ip": {"203.0.113.8"}}, want: "203.0.113.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+			if r.Header == nil {
+				r.Header = http.Header{}
+			}
+
+			assert.Equal(t, tt.want, clientIP(r))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package gracefulhttp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// WithAdminServer serves the operational endpoints on a separate address, e.g. "127.0.0.1:9090",
+// rather than on the public one: /healthz answers 503 Service Unavailable once the drain begins,
+// /readyz reports the readiness through [GracefulServer.ReadinessHandler], /metrics exposes the
+// connection statistics in the Prometheus text format, and /shutdown reports the progress of the
+// shutdown through [GracefulServer.StatusHandler]. The profiles enabled by [WithPprof] are served there as well.
+//
+// The admin server is started with the server, and shut down once the shutdown of the server completes,
+// so that the drain can be observed until the end.
+func WithAdminServer(addr string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.adminAddr = addr
+	}
+}
+
+// AdminAddr returns the address the admin server is listening on, or nil if it is not enabled
+// or its listener has not been created yet.
+func (s *GracefulServer) AdminAddr() net.Addr {
+	if s.admin == nil {
+		return nil
+	}
+
+	return s.admin.ListenerAddr()
+}
+
+// initializeAdmin creates the admin server, if enabled.
+func (s *GracefulServer) initializeAdmin() {
+	if s.adminAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.serveHealth)
+	mux.Handle("GET /readyz", s.ReadinessHandler())
+	mux.HandleFunc("GET /metrics", s.serveMetrics)
+	mux.Handle("GET /shutdown", s.StatusHandler())
+
+	var h http.Handler = mux
+	if s.pprofPrefix != "" {
+		h = pprofHandler(h, s.pprofPrefix)
+	}
+
+	s.admin = Bind(s.adminAddr, h)
+}
+
+// serveHealth answers 200 OK while serving, and 503 Service Unavailable once the drain begins.
+func (s *GracefulServer) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	if s.isDraining() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "ok")
+}
+
+// serveMetrics exposes the connection statistics in the Prometheus text format.
+func (s *GracefulServer) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	stats := s.ConnStats()
+	draining := 0
+	if s.isDraining() {
+		draining = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = fmt.Fprintf(w, `# HELP gracefulhttp_connections Connections handled by the server, by state.
+# TYPE gracefulhttp_connections gauge
+gracefulhttp_connections{state="new"} %d
+gracefulhttp_connections{state="active"} %d
+gracefulhttp_connections{state="idle"} %d
+# HELP gracefulhttp_hijacked_connections_total Connections taken over by handlers.
+# TYPE gracefulhttp_hijacked_connections_total counter
+gracefulhttp_hijacked_connections_total %d
+# HELP gracefulhttp_draining Whether the shutdown has begun.
+# TYPE gracefulhttp_draining gauge
+gracefulhttp_draining %d
+# HELP gracefulhttp_handler_panics_total Panics recovered from the handler.
+# TYPE gracefulhttp_handler_panics_total counter
+gracefulhttp_handler_panics_total %d
+# HELP gracefulhttp_shed_requests_total Requests rejected in degraded mode.
+# TYPE gracefulhttp_shed_requests_total counter
+gracefulhttp_shed_requests_total %d
+`, stats.New, stats.Active, stats.Idle, stats.Hijacked, draining, s.panics.Load(), s.shedRequests.Load())
+}
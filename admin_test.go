@@ -0,0 +1,80 @@
+package gracefulhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getAdmin sends a GET request to the admin server, returning the status code and the body.
+func getAdmin(t *testing.T, s *GracefulServer, path string) (int, string) {
+	t.Helper()
+
+	r, err := http.Get("http://" + s.AdminAddr().String() + path)
+	require.NoError(t, err)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+
+	return r.StatusCode, string(body)
+}
+
+func TestWithAdminServer(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(ctx, WithAdminServer("localhost:0"), WithPprof(""))
+	}()
+
+	<-s.Ready()
+	<-s.admin.Ready()
+
+	status, body := getAdmin(t, s, "/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok\n", body)
+
+	status, _ = getAdmin(t, s, "/debug/pprof/")
+	assert.Equal(t, http.StatusOK, status)
+
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String() + "/")
+		if err == nil {
+			_ = r.Body.Close()
+		}
+	}()
+	<-started
+
+	_, body = getAdmin(t, s, "/metrics")
+	assert.Contains(t, body, `gracefulhttp_connections{state="active"} 1`)
+	assert.Contains(t, body, "gracefulhttp_draining 0")
+	assert.Contains(t, body, "gracefulhttp_shed_requests_total 0")
+
+	cancel()
+	<-s.Draining()
+
+	status, _ = getAdmin(t, s, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	_, body = getAdmin(t, s, "/shutdown")
+	var shutdown serverStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &shutdown))
+	assert.Equal(t, "draining", shutdown.State)
+	assert.NotNil(t, shutdown.Deadline)
+	assert.Equal(t, 1, shutdown.Connections.Active)
+
+	close(release)
+	assert.NoError(t, <-errCh)
+}
@@ -0,0 +1,104 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"slices"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultACMEChallengeAddr is the default address of the server answering the ACME HTTP-01 challenges.
+const defaultACMEChallengeAddr = ":http"
+
+// CertManager obtains and renews the certificates on behalf of the server,
+// such as an [autocert.Manager] or a CertMagic *certmagic.Config.
+type CertManager interface {
+	// GetCertificate returns the certificate for the given ClientHello.
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// HTTPChallengeHandler is implemented by the [CertManager] able to answer the ACME HTTP-01 challenges,
+// such as [autocert.Manager]. Any request that is not a challenge is passed to the fallback handler,
+// or redirected to HTTPS if the fallback is nil.
+type HTTPChallengeHandler interface {
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// certMagicManager adapts a CertMagic configuration and its ACME issuer to a [CertManager].
+type certMagicManager struct {
+	CertManager
+
+	issuer interface {
+		HTTPChallengeHandler(h http.Handler) http.Handler
+	}
+}
+
+// HTTPHandler answers the HTTP-01 challenges through the ACME issuer.
+func (m certMagicManager) HTTPHandler(fallback http.Handler) http.Handler {
+	if fallback == nil {
+		fallback = http.HandlerFunc(redirectToHTTPS)
+	}
+
+	return m.issuer.HTTPChallengeHandler(fallback)
+}
+
+// CertMagic adapts a CertMagic configuration and its ACME issuer to a [CertManager] able to answer
+// the HTTP-01 challenges, without depending on the CertMagic module:
+//
+//	cfg := certmagic.NewDefault()
+//	issuer := certmagic.NewACMEIssuer(cfg, certmagic.DefaultACME)
+//	cfg.Issuers = []certmagic.Issuer{issuer}
+//	err := cfg.ManageSync(ctx, []string{"example.com"})
+//	// ...
+//	err = srv.ListenAndServeTLSWithShutdown(ctx, "", "", gracefulhttp.WithCertificateManager(gracefulhttp.CertMagic(cfg, issuer)))
+func CertMagic(cfg CertManager, issuer interface {
+	HTTPChallengeHandler(h http.Handler) http.Handler
+}) CertManager {
+	return certMagicManager{
+		CertManager: cfg,
+		issuer:      issuer,
+	}
+}
+
+// redirectToHTTPS redirects the request to the same URL using the https scheme.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "https://"+stripPort(r.Host)+r.URL.RequestURI(), http.StatusFound)
+}
+
+// ListenAndServeAutocertWithShutdown starts a [http.Server] serving HTTPS with certificates obtained
+// automatically via ACME (e.g. from Let's Encrypt) for the provided hosts, and for the virtual hosts
+// without a certificate set by [WithVirtualHosts], cached in cacheDir.
+// A companion server, listening on port 80 by default, answers the HTTP-01 challenges and redirects
+// any other request to HTTPS; both servers are shut down gracefully together.
+// By using this method, you accept the terms of service of the certificate authority.
+// For additional details, refer to the documentation of [ListenAndServeWithShutdown].
+func (s *GracefulServer) ListenAndServeAutocertWithShutdown(ctx context.Context, hosts []string, cacheDir string, opts ...GracefulServerOption) error {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: s.acmeHostPolicy(hosts),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+
+	return s.ListenAndServeTLSWithShutdown(ctx, "", "", append([]GracefulServerOption{WithCertificateManager(m)}, opts...)...)
+}
+
+// initializeCertManager delegates the certificates to the certificate manager, if any,
+// and registers the companion server answering the HTTP-01 challenges when supported.
+func (s *GracefulServer) initializeCertManager() {
+	if s.certManager == nil {
+		return
+	}
+
+	cfg := s.ownTLSConfig()
+	cfg.GetCertificate = s.certManager.GetCertificate
+	if !slices.Contains(cfg.NextProtos, acme.ALPNProto) {
+		cfg.NextProtos = append(cfg.NextProtos, acme.ALPNProto)
+	}
+
+	if h, ok := s.certManager.(HTTPChallengeHandler); ok && s.acmeChallengeAddr != "" {
+		s.companions = append(s.companions, Bind(s.acmeChallengeAddr, h.HTTPHandler(nil)))
+	}
+}
@@ -0,0 +1,117 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_ListenAndServeAutocertWithShutdown(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeAutocertWithShutdown(
+			ctx,
+			[]string{"example.com"},
+			t.TempDir(),
+			WithACMEChallengeAddr("localhost:0"),
+		)
+	}()
+
+	<-s.Ready()
+
+	assert.Contains(t, s.TLSConfig.NextProtos, "acme-tls/1")
+
+	// Hosts outside the whitelist are rejected during the handshake.
+	_, err := tls.Dial("tcp", s.ListenerAddr().String(), &tls.Config{
+		ServerName:         "unknown.example.org",
+		InsecureSkipVerify: true,
+	})
+	require.Error(t, err)
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+type fakeCertManager struct{}
+
+func (m *fakeCertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return &tls.Certificate{}, nil
+}
+
+type fakeIssuer struct{}
+
+func (fakeIssuer) HTTPChallengeHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+			_, _ = w.Write([]byte("token"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func TestWithCertificateManager(t *testing.T) {
+	tests := []struct {
+		name           string
+		manager        CertManager
+		opts           []GracefulServerOption
+		wantCompanions int
+	}{
+		{
+			name:           "manager without challenges",
+			manager:        &fakeCertManager{},
+			wantCompanions: 0,
+		},
+		{
+			name:           "manager with challenges",
+			manager:        CertMagic(&fakeCertManager{}, fakeIssuer{}),
+			wantCompanions: 1,
+		},
+		{
+			name:           "manager with challenges disabled",
+			manager:        CertMagic(&fakeCertManager{}, fakeIssuer{}),
+			opts:           []GracefulServerOption{WithACMEChallengeAddr("")},
+			wantCompanions: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", nil)
+			s.initialize(append([]GracefulServerOption{WithCertificateManager(tt.manager)}, tt.opts...))
+			s.initializeCertManager()
+
+			require.NotNil(t, s.TLSConfig)
+			assert.NotNil(t, s.TLSConfig.GetCertificate)
+			assert.Contains(t, s.TLSConfig.NextProtos, "acme-tls/1")
+			assert.Len(t, s.companions, tt.wantCompanions)
+		})
+	}
+}
+
+func TestCertMagic(t *testing.T) {
+	m := CertMagic(&fakeCertManager{}, fakeIssuer{})
+
+	h, ok := m.(HTTPChallengeHandler)
+	require.True(t, ok)
+
+	w := httptest.NewRecorder()
+	h.HTTPHandler(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/.well-known/acme-challenge/x", nil))
+	assert.Equal(t, "token", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.HTTPHandler(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com:8080/path?q=1", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/path?q=1", w.Header().Get("Location"))
+}
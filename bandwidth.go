@@ -0,0 +1,92 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/time/rate"
+)
+
+// maxBandwidthBurst is the maximum number of bytes written at once by the connections limited by [WithBandwidthLimit].
+const maxBandwidthBurst = 64 << 10
+
+// WithBandwidthLimit caps the bytes written to all the connections together, TLS records included, at
+// bytesPerSecond, through a token bucket shared by all the listeners, e.g. for servers whose upstream link
+// is metered or shared with latency-sensitive traffic. The writes wait for their tokens, which does not count
+// against [WithMinDataRate]; a connection closed meanwhile stops waiting. A non-positive rate means no limit.
+func WithBandwidthLimit(bytesPerSecond int64) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if bytesPerSecond < 0 {
+			s.invalidOption("WithBandwidthLimit", "negative rate %d", bytesPerSecond)
+		}
+
+		if bytesPerSecond <= 0 {
+			s.bandwidthLimiter = nil
+			return
+		}
+
+		s.bandwidthLimiter = rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, maxBandwidthBurst)))
+	}
+}
+
+// bandwidthListener wraps the accepted connections to throttle their writes through the shared limiter.
+type bandwidthListener struct {
+	net.Listener
+
+	limiter *rate.Limiter
+}
+
+// Accept accepts the next connection, throttling its writes.
+func (l *bandwidthListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &bandwidthConn{Conn: c, limiter: l.limiter, ctx: ctx, cancel: cancel}, nil
+}
+
+// bandwidthConn waits for the tokens of the shared limiter before each write.
+type bandwidthConn struct {
+	net.Conn
+
+	limiter *rate.Limiter
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// Write writes the data in chunks no larger than the burst of the limiter, waiting for their tokens.
+// Once the connection is closed, the wait is interrupted and [net.ErrClosed] is returned.
+func (c *bandwidthConn) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), c.limiter.Burst())]
+		if err := c.limiter.WaitN(c.ctx, len(chunk)); err != nil {
+			return written, net.ErrClosed
+		}
+
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+// Close interrupts the pending wait, if any, and closes the connection.
+func (c *bandwidthConn) Close() error {
+	c.cancel()
+
+	return c.Conn.Close()
+}
+
+// NetConn returns the wrapped connection.
+func (c *bandwidthConn) NetConn() net.Conn {
+	return c.Conn
+}
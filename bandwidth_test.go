@@ -0,0 +1,84 @@
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestWithBandwidthLimit(t *testing.T) {
+	body := strings.Repeat("x", 50_000)
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithBandwidthLimit(50_000))
+	}()
+	<-s.Ready()
+
+	// The connections share the bandwidth: the second response waits for the bucket to refill.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			r, err := http.Get("http://" + s.ListenerAddr().String())
+			require.NoError(t, err)
+			defer r.Body.Close()
+
+			got, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Len(t, got, len(body))
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.ErrorIs(t, ValidateOptions(WithBandwidthLimit(-1)), ErrInvalidOption)
+}
+
+func Test_bandwidthConn_Close(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &bandwidthConn{Conn: server, limiter: rate.NewLimiter(1, 1), ctx: ctx, cancel: cancel}
+
+	go func() {
+		_, _ = io.Copy(io.Discard, client)
+	}()
+
+	// The write waiting for its tokens is interrupted by the close.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.Write([]byte("waiting"))
+		errCh <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, c.Close())
+
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, net.ErrClosed)
+	case <-time.After(time.Second):
+		t.Fatal("the write was not interrupted")
+	}
+}
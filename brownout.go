@@ -0,0 +1,85 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+)
+
+// brownout is the fraction of the new requests rejected in degraded mode, overall and by class.
+type brownout struct {
+	ratio   float64
+	classes map[string]float64
+}
+
+// WithBrownout enables the degraded mode entered through [GracefulServer.SetBrownout], in which a fraction
+// of the new requests is answered with 503 Service Unavailable and a Retry-After header, so that an overloaded
+// server sheds its load progressively. The requests are sorted into classes by classify, e.g. by path prefix,
+// so that the less important ones are shed first; a nil classify puts them all in the same class.
+// The requests for the paths exempt from the drain rejection are never shed.
+func WithBrownout(classify func(r *http.Request) string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.brownoutEnabled = true
+		s.classify = classify
+	}
+}
+
+// SetBrownout sheds the given fraction, between 0 and 1, of the new requests of the classes set by the
+// classify function of [WithBrownout], or of all the requests if no class is given, replacing the fractions
+// set for the classes before. A zero fraction for all the requests leaves the degraded mode.
+// It has no effect unless the server was started with [WithBrownout].
+func (s *GracefulServer) SetBrownout(ratio float64, classes ...string) {
+	ratio = min(max(ratio, 0), 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(classes) == 0 {
+		s.brownout.Store(&brownout{ratio: ratio})
+		return
+	}
+
+	next := &brownout{classes: make(map[string]float64)}
+	if prev := s.brownout.Load(); prev != nil {
+		next.ratio = prev.ratio
+		maps.Copy(next.classes, prev.classes)
+	}
+
+	for _, class := range classes {
+		next.classes[class] = ratio
+	}
+
+	s.brownout.Store(next)
+}
+
+// brownoutHandler answers with 503 Service Unavailable the fraction of the requests of their class
+// set through [GracefulServer.SetBrownout].
+func (s *GracefulServer) brownoutHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b := s.brownout.Load(); b != nil && s.shed(b, r) {
+			s.shedRequests.Add(1)
+			s.addCounter(MetricShedRequests, 1)
+			serviceUnavailable(w, cmp.Or(s.retryAfter, defaultRetryAfter))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// shed reports whether the request is to be rejected in the degraded mode.
+func (s *GracefulServer) shed(b *brownout, r *http.Request) bool {
+	if _, exempt := s.drainExemptPaths[r.URL.Path]; exempt {
+		return false
+	}
+
+	ratio := b.ratio
+	if s.classify != nil && len(b.classes) > 0 {
+		if classRatio, ok := b.classes[s.classify(r)]; ok {
+			ratio = classRatio
+		}
+	}
+
+	return ratio > 0 && rand.Float64() < ratio
+}
@@ -0,0 +1,111 @@
+package gracefulhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// classifyByPrefix puts the requests for /batch/ in the "batch" class, and the others in the "api" one.
+func classifyByPrefix(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/batch/") {
+		return "batch"
+	}
+
+	return "api"
+}
+
+func TestWithBrownout(t *testing.T) {
+	tests := []struct {
+		name        string
+		setBrownout func(s *GracefulServer)
+		path        string
+		wantStatus  int
+	}{
+		{
+			name:        "serve outside of the degraded mode",
+			setBrownout: func(*GracefulServer) {},
+			path:        "/api",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "shed all the requests",
+			setBrownout: func(s *GracefulServer) { s.SetBrownout(1) },
+			path:        "/api",
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		{
+			name:        "shed the requests of a class",
+			setBrownout: func(s *GracefulServer) { s.SetBrownout(1, "batch") },
+			path:        "/batch/report",
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		{
+			name:        "serve the requests of the other classes",
+			setBrownout: func(s *GracefulServer) { s.SetBrownout(1, "batch") },
+			path:        "/api",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name: "override the overall fraction for a class",
+			setBrownout: func(s *GracefulServer) {
+				s.SetBrownout(1)
+				s.SetBrownout(0, "api")
+			},
+			path:       "/api",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "leave the degraded mode",
+			setBrownout: func(s *GracefulServer) {
+				s.SetBrownout(1, "batch")
+				s.SetBrownout(0)
+			},
+			path:       "/batch/report",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "serve the exempt paths",
+			setBrownout: func(s *GracefulServer) { s.SetBrownout(1) },
+			path:        "/healthz",
+			wantStatus:  http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			require.NoError(t, s.initialize([]GracefulServerOption{WithBrownout(classifyByPrefix), WithDrainExemptPaths("/healthz")}))
+			tt.setBrownout(s)
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				assert.Equal(t, "1", w.Header().Get("Retry-After"))
+				assert.Equal(t, int64(1), s.shedRequests.Load())
+			}
+		})
+	}
+}
+
+func TestGracefulServer_SetBrownout_Fraction(t *testing.T) {
+	s := Bind("", &delayedHandler{})
+	require.NoError(t, s.initialize([]GracefulServerOption{WithBrownout(nil)}))
+	s.SetBrownout(0.5)
+
+	shed := 0
+	for range 1000 {
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code == http.StatusServiceUnavailable {
+			shed++
+		}
+	}
+
+	assert.InDelta(t, 500, shed, 100)
+}
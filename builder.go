@@ -0,0 +1,114 @@
+package gracefulhttp
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// A Builder configures a [GracefulServer] through chained method calls, as an alternative
+// to the functional options. It is created by [New] and consumed by [Builder.Build].
+type Builder struct {
+	cfg   Config
+	addrs []string
+	opts  []GracefulServerOption
+}
+
+// New returns a [Builder] of a [GracefulServer].
+func New() *Builder {
+	return &Builder{}
+}
+
+// Addr sets the address to listen on.
+func (b *Builder) Addr(addr string) *Builder {
+	b.cfg.Addr = addr
+	return b
+}
+
+// Addrs sets several addresses to listen on, as with [BindMulti].
+func (b *Builder) Addrs(addrs ...string) *Builder {
+	b.addrs = slices.Clone(addrs)
+	return b
+}
+
+// Handler sets the handler to invoke, [http.DefaultServeMux] if nil.
+func (b *Builder) Handler(h http.Handler) *Builder {
+	b.cfg.Handler = h
+	return b
+}
+
+// ReadTimeout sets the [http.Server] ReadTimeout.
+func (b *Builder) ReadTimeout(d time.Duration) *Builder {
+	b.cfg.ReadTimeout = Duration(d)
+	return b
+}
+
+// ReadHeaderTimeout sets the [http.Server] ReadHeaderTimeout.
+func (b *Builder) ReadHeaderTimeout(d time.Duration) *Builder {
+	b.cfg.ReadHeaderTimeout = Duration(d)
+	return b
+}
+
+// WriteTimeout sets the [http.Server] WriteTimeout.
+func (b *Builder) WriteTimeout(d time.Duration) *Builder {
+	b.cfg.WriteTimeout = Duration(d)
+	return b
+}
+
+// IdleTimeout sets the [http.Server] IdleTimeout.
+func (b *Builder) IdleTimeout(d time.Duration) *Builder {
+	b.cfg.IdleTimeout = Duration(d)
+	return b
+}
+
+// ShutdownTimeout sets the timeout of the graceful shutdown, as with [WithShutdownTimeout].
+func (b *Builder) ShutdownTimeout(d time.Duration) *Builder {
+	b.cfg.ShutdownTimeout = Duration(d)
+	return b
+}
+
+// Certificate sets the certificate and private key files to serve.
+// Pass empty certFile and keyFile to [GracefulServer.ListenAndServeTLSWithShutdown] when using it.
+func (b *Builder) Certificate(certFile, keyFile string) *Builder {
+	b.cfg.TLS.CertFile = certFile
+	b.cfg.TLS.KeyFile = keyFile
+	return b
+}
+
+// TLSProfile applies one of the TLS profiles recommended by Mozilla, as with [WithMozillaTLSProfile].
+func (b *Builder) TLSProfile(profile MozillaTLSProfile) *Builder {
+	b.cfg.TLS.Profile = profile.String()
+	return b
+}
+
+// Preset applies best practice configurations by name, as with the presets of a [Config].
+func (b *Builder) Preset(names ...string) *Builder {
+	b.cfg.Presets = append(b.cfg.Presets, names...)
+	return b
+}
+
+// Options adds functional options, applied after the settings of the builder.
+func (b *Builder) Options(opts ...GracefulServerOption) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build returns the configured [GracefulServer], or the error of an invalid combination of settings.
+func (b *Builder) Build() (*GracefulServer, error) {
+	err := errors.Join(b.cfg.Validate(), ValidateOptions(b.opts...))
+	if b.cfg.Addr != "" && len(b.addrs) > 0 {
+		err = errors.Join(err, errors.New("gracefulhttp: Addr and Addrs are mutually exclusive"))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts := slices.Concat(b.cfg.options(), b.opts)
+	if len(b.addrs) > 0 {
+		return BindMulti(b.addrs, b.cfg.Handler, opts...), nil
+	}
+
+	return Bind(b.cfg.Addr, b.cfg.Handler, opts...), nil
+}
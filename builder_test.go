@@ -0,0 +1,71 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Run("build and serve", func(t *testing.T) {
+		s, err := New().
+			Addr("localhost:0").
+			Handler(&delayedHandler{}).
+			ShutdownTimeout(10 * time.Second).
+			WriteTimeout(time.Minute).
+			Build()
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx)
+		}()
+
+		<-s.Ready()
+
+		assert.Equal(t, 10*time.Second, s.gracefulTimeout)
+		assert.Equal(t, time.Minute, s.WriteTimeout)
+
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_ = r.Body.Close()
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+
+		cancel()
+
+		require.NoError(t, <-done)
+	})
+
+	t.Run("apply the options last", func(t *testing.T) {
+		s, err := New().
+			Addrs("localhost:8080", "localhost:8081").
+			TLSProfile(MozillaModern).
+			ShutdownTimeout(10 * time.Second).
+			Options(WithShutdownTimeout(time.Minute)).
+			Build()
+		require.NoError(t, err)
+		require.NoError(t, s.initialize(nil))
+
+		assert.Equal(t, []string{"localhost:8080", "localhost:8081"}, s.addrs)
+		assert.Equal(t, uint16(tls.VersionTLS13), s.TLSConfig.MinVersion)
+		assert.Equal(t, time.Minute, s.gracefulTimeout)
+	})
+
+	t.Run("reject an invalid combination", func(t *testing.T) {
+		s, err := New().
+			Addr("localhost:8080").
+			Addrs("localhost:8081").
+			Certificate("cert.pem", "").
+			Build()
+		assert.Nil(t, s)
+		assert.ErrorContains(t, err, "mutually exclusive")
+		assert.ErrorContains(t, err, "cert_file")
+	})
+}
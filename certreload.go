@@ -0,0 +1,154 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultCertReloadInterval is the interval between the checks for changes of the certificate files.
+const defaultCertReloadInterval = 1 * time.Minute
+
+// certReloader serves a certificate loaded from files, reloading it when the files change.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod fileVersion
+	keyMod  fileVersion
+}
+
+// fileVersion identifies a version of a file through the path it resolves to, its size and modification time.
+// Kubernetes updates the secret volumes by swapping the ..data symlink to a new directory, leaving the symlinks
+// of the files untouched, so the resolved path tells a rotation apart even if the size and time match.
+type fileVersion struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// newCertReloader returns a certReloader with the certificate loaded from the files.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns the last loaded certificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// reload loads the certificate from the files, keeping the previous one on failure.
+// The files are read through their resolved paths, so that the certificate and the key
+// come from the same version of a Kubernetes secret volume, even if it is swapped meanwhile.
+func (r *certReloader) reload() error {
+	certMod, err := statVersion(r.certFile)
+	if err != nil {
+		return err
+	}
+
+	keyMod, err := statVersion(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(certMod.path, keyMod.path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+
+	return nil
+}
+
+// changed reports whether any of the files changed since the last reload.
+func (r *certReloader) changed() bool {
+	certMod, err := statVersion(r.certFile)
+	if err != nil {
+		return false
+	}
+
+	keyMod, err := statVersion(r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return certMod != r.certMod || keyMod != r.keyMod
+}
+
+// watch reloads the certificate every time the files change, until the context is done.
+// A certificate that fails to load is ignored and the previous one keeps being served.
+func (r *certReloader) watch(ctx context.Context, clock Clock, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+			if r.changed() {
+				_ = r.reload()
+			}
+		}
+	}
+}
+
+// watchSignals reloads the certificate every time one of the signals is received, until the context is done.
+// A certificate that fails to load is ignored and the previous one keeps being served.
+func (r *certReloader) watchSignals(ctx context.Context, sigs []os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			_ = r.reload()
+		}
+	}
+}
+
+// statVersion returns the current version of the file, following the symlinks.
+func statVersion(name string) (fileVersion, error) {
+	path, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return fileVersion{}, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+
+	return fileVersion{
+		path:    path,
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+	}, nil
+}
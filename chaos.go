@@ -0,0 +1,56 @@
+package gracefulhttp
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChaosConfig sets the delays and the failures injected by [WithChaos] at the points of the lifecycle.
+type ChaosConfig struct {
+	// ListenDelay delays the creation of the listeners.
+	ListenDelay time.Duration
+	// ListenErr, if not nil, fails the creation of the listeners after the delay, as a port already in use would.
+	ListenErr error
+	// DrainDelay delays the start of the drain once the shutdown begins, the server serving as usual meanwhile,
+	// as a slow deregistration would.
+	DrainDelay time.Duration
+	// DrainErr, if not nil, is reported as a failure of the drain.
+	DrainErr error
+	// CleanupDelay delays the run of the cleanup hooks.
+	CleanupDelay time.Duration
+	// CleanupErr, if not nil, is reported as a failure of the cleanup.
+	CleanupErr error
+}
+
+// WithChaos injects the delays and the failures of the configuration at the points of the lifecycle, e.g. so
+// that the deploy tooling and the alerts can be verified against slow or failing startups and shutdowns in
+// a staging environment, without writing slow handlers on purpose. It is meant for testing only.
+func WithChaos(cfg ChaosConfig) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if cfg.ListenDelay < 0 || cfg.DrainDelay < 0 || cfg.CleanupDelay < 0 {
+			s.invalidOption("WithChaos", "negative delay")
+			return
+		}
+
+		s.chaos = cfg
+	}
+}
+
+// injectChaos waits for the delay, if positive, then reports the error, if any, as a failure of the phase.
+func (s *GracefulServer) injectChaos(phase Phase, delay time.Duration, err error) error {
+	if delay > 0 {
+		<-s.clock.After(delay)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("gracefulhttp: chaos: %w", err)
+	if phase != PhaseListen {
+		// The listen failures are reported by listenFailed.
+		s.reportError(phase, err)
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithChaos(t *testing.T) {
+	t.Run("fail the listen", func(t *testing.T) {
+		listenErr := errors.New("address in use")
+
+		s := Bind("localhost:0", nil)
+		err := s.ListenAndServeWithShutdown(context.Background(), WithChaos(ChaosConfig{ListenErr: listenErr}))
+		assert.ErrorIs(t, err, listenErr)
+
+		var gerr *GracefulError
+		require.ErrorAs(t, err, &gerr)
+		assert.Equal(t, PhaseListen, gerr.Phase)
+		assert.Nil(t, s.ListenerAddr())
+	})
+
+	t.Run("delay and fail the shutdown", func(t *testing.T) {
+		drainErr := errors.New("drain failed")
+		cleanupErr := errors.New("cleanup failed")
+
+		s := Bind("localhost:0", nil)
+		var phases []Phase
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx,
+				WithChaos(ChaosConfig{
+					ListenDelay:  50 * time.Millisecond,
+					DrainDelay:   50 * time.Millisecond,
+					DrainErr:     drainErr,
+					CleanupDelay: 50 * time.Millisecond,
+					CleanupErr:   cleanupErr,
+				}),
+				WithOnError(func(phase Phase, err error) {
+					phases = append(phases, phase)
+				}),
+			)
+		}()
+
+		start := time.Now()
+		<-s.Ready()
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		start = time.Now()
+		cancel()
+
+		err := <-done
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		assert.ErrorIs(t, err, drainErr)
+		assert.ErrorIs(t, err, cleanupErr)
+		assert.Equal(t, []Phase{PhaseDrain, PhaseCleanup}, phases)
+	})
+
+	assert.ErrorIs(t, ValidateOptions(WithChaos(ChaosConfig{DrainDelay: -time.Second})), ErrInvalidOption)
+}
@@ -0,0 +1,126 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CleanupHook is a hook releasing a resource once the server is drained.
+type CleanupHook struct {
+	// Name identifies the hook in the errors.
+	Name string
+	// Timeout bounds the run of the hook, if positive.
+	Timeout time.Duration
+	// Fn releases the resource, within the deadline of the context.
+	Fn func(ctx context.Context) error
+}
+
+// RegisterCleanup registers a hook releasing a resource once the drain completes, such as closing a database
+// pool, stopping a message consumer or flushing the telemetry. The hooks run one after the other, in the order
+// they were registered, each one with a context expiring after its own timeout, if positive. Their failures
+// are reported by the serving method, joined with the error of the shutdown, if any.
+func (s *GracefulServer) RegisterCleanup(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	s.RegisterCleanupStage(CleanupHook{Name: name, Timeout: timeout, Fn: fn})
+}
+
+// RegisterCleanupStage registers hooks run in parallel, as a stage following the hooks registered before,
+// and preceding those registered after, e.g. to stop the consumers first, then close the database and
+// flush the traces in parallel. The following stage starts once all the hooks of the stage have returned,
+// even if some of them failed. See [GracefulServer.RegisterCleanup] for the details.
+func (s *GracefulServer) RegisterCleanupStage(hooks ...CleanupHook) {
+	if len(hooks) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanups = append(s.cleanups, hooks)
+}
+
+// shutdownHook is a hook run when the graceful shutdown begins.
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// RegisterShutdownHook registers a hook run when the graceful shutdown begins, alongside the drain, such as
+// deregistering the instance from a service discovery. Unlike the callbacks of [http.Server.RegisterOnShutdown],
+// which run concurrently, without a deadline and cannot fail, the hooks run one after the other, in the order
+// they were registered, with the context of the shutdown, which is done once the connections are forcibly closed.
+// Their failures are reported by the serving method, joined with the error of the shutdown, if any.
+// They do not run when the server is stopped without draining.
+func (s *GracefulServer) RegisterShutdownHook(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// runShutdownHooks runs the shutdown hooks one after the other, returning their failures.
+func (s *GracefulServer) runShutdownHooks(ctx context.Context) error {
+	s.mu.Lock()
+	hooks := s.shutdownHooks
+	s.mu.Unlock()
+
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook.fn(ctx); err != nil {
+			err = fmt.Errorf("gracefulhttp: shutdown hook %s: %w", hook.name, err)
+			s.reportError(PhaseDrain, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return phaseError(PhaseDrain, errors.Join(errs...))
+}
+
+// cleanUp runs the stages of cleanup hooks, returning their failures.
+func (s *GracefulServer) cleanUp() error {
+	s.mu.Lock()
+	stages := s.cleanups
+	s.mu.Unlock()
+
+	errs := []error{s.injectChaos(PhaseCleanup, s.chaos.CleanupDelay, s.chaos.CleanupErr)}
+	for _, stage := range stages {
+		errs = append(errs, s.runCleanupStage(stage)...)
+	}
+
+	return phaseError(PhaseCleanup, errors.Join(errs...))
+}
+
+// runCleanupStage runs the hooks of a stage in parallel, returning their failures in order.
+func (s *GracefulServer) runCleanupStage(hooks []CleanupHook) []error {
+	errs := make([]error, len(hooks))
+
+	var wg sync.WaitGroup
+	for i, hook := range hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := hook.run(); err != nil {
+				errs[i] = fmt.Errorf("gracefulhttp: cleanup %s: %w", hook.Name, err)
+				s.reportError(PhaseCleanup, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// run runs the hook within its timeout, if any.
+func (h CleanupHook) run() error {
+	ctx := context.Background()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	return h.Fn(ctx)
+}
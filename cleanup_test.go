@@ -0,0 +1,138 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGracefulServer_RegisterCleanup(t *testing.T) {
+	var order []string
+	closeErr := errors.New("pool busy")
+
+	s := Bind("localhost:0", nil)
+	s.RegisterCleanup("consumer", time.Second, func(ctx context.Context) error {
+		order = append(order, "consumer")
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return nil
+	})
+	s.RegisterCleanup("db", 10*time.Millisecond, func(ctx context.Context) error {
+		order = append(order, "db")
+		<-ctx.Done()
+		return errors.Join(closeErr, ctx.Err())
+	})
+	s.RegisterCleanup("telemetry", 0, func(ctx context.Context) error {
+		order = append(order, "telemetry")
+		return nil
+	})
+
+	var rec errorRecorder
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithOnError(rec.record))
+	}()
+
+	<-s.Ready()
+	assert.Empty(t, order)
+	cancel()
+
+	err := <-done
+	assert.ErrorIs(t, err, closeErr)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "gracefulhttp: cleanup db")
+
+	var gerr *GracefulError
+	assert.ErrorAs(t, err, &gerr)
+	assert.Equal(t, PhaseCleanup, gerr.Phase)
+	assert.Equal(t, []string{"consumer", "db", "telemetry"}, order)
+	assert.Equal(t, []Phase{PhaseCleanup}, rec.phases)
+}
+
+func TestGracefulServer_RegisterCleanupStage(t *testing.T) {
+	consumersStopped := make(chan struct{})
+	started := make(chan string, 2)
+	release := make(chan struct{})
+
+	// The hooks of the parallel stage are released only once both of them have started.
+	parallel := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			select {
+			case <-consumersStopped:
+			default:
+				t.Errorf("%s cleaned up before the consumers", name)
+			}
+
+			started <- name
+			<-release
+			return nil
+		}
+	}
+
+	s := Bind("localhost:0", nil)
+	s.RegisterCleanup("consumers", 0, func(ctx context.Context) error {
+		close(consumersStopped)
+		return nil
+	})
+	s.RegisterCleanupStage(
+		CleanupHook{Name: "db", Fn: parallel("db")},
+		CleanupHook{Name: "traces", Fn: parallel("traces")},
+	)
+	s.RegisterCleanupStage()
+
+	go func() {
+		<-started
+		<-started
+		close(release)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx)
+	}()
+
+	<-s.Ready()
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Len(t, s.cleanups, 2)
+}
+
+func TestGracefulServer_RegisterShutdownHook(t *testing.T) {
+	var order []string
+	deregisterErr := errors.New("registry unavailable")
+
+	s := Bind("localhost:0", nil)
+	s.RegisterShutdownHook("registry", func(ctx context.Context) error {
+		order = append(order, "registry")
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return deregisterErr
+	})
+	s.RegisterShutdownHook("jobs", func(ctx context.Context) error {
+		order = append(order, "jobs")
+		return nil
+	})
+
+	var rec errorRecorder
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithOnError(rec.record))
+	}()
+
+	<-s.Ready()
+	assert.Empty(t, order)
+	cancel()
+
+	err := <-done
+	assert.ErrorIs(t, err, deregisterErr)
+	assert.ErrorContains(t, err, "gracefulhttp: shutdown hook registry")
+	assert.Equal(t, []string{"registry", "jobs"}, order)
+	assert.Equal(t, []Phase{PhaseDrain}, rec.phases)
+}
@@ -0,0 +1,203 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownClientCA is returned during the TLS handshake when a client certificate
+// is not signed by any of the CAs loaded through [WithClientCADir].
+var ErrUnknownClientCA = errors.New("gracefulhttp: client certificate signed by an unknown authority")
+
+// WithClientCADir requires the clients to present a certificate signed by one of the CAs read from the
+// PEM files of dir, so that a new internal CA can be trusted without restarting the servers: the directory
+// is scanned again at every refresh interval, if positive, and every time one of the signals is received,
+// e.g. SIGHUP. The hidden files are skipped, as the ..data directory of the Kubernetes volumes.
+// A scan failing or finding no CA is ignored and the previous CAs keep being trusted.
+//
+// The client certificates are verified by the server rather than by [crypto/tls], so that the CAs can be
+// swapped safely while serving: the TLS configuration requires a certificate through [tls.RequireAnyClientCert],
+// and [tls.ConnectionState.VerifiedChains] is left empty.
+func WithClientCADir(dir string, refresh time.Duration, sig ...os.Signal) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if refresh < 0 {
+			s.invalidOption("WithClientCADir", "negative refresh interval %v", refresh)
+		}
+
+		s.clientCADir = dir
+		s.clientCARefresh = max(refresh, 0)
+		s.clientCASignals = sig
+	}
+}
+
+// clientCAs verifies the client certificates against the CAs loaded from a directory.
+type clientCAs struct {
+	dir string
+
+	mu   sync.RWMutex
+	pool *x509.CertPool
+}
+
+// newClientCAs returns a clientCAs with the CAs loaded from the directory.
+func newClientCAs(dir string) (*clientCAs, error) {
+	c := &clientCAs{
+		dir: dir,
+	}
+
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// reload loads the CAs from the PEM files of the directory, keeping the previous ones on failure.
+func (c *clientCAs) reload() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	found := false
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			// Directories and dangling symlinks hold no CA.
+			continue
+		}
+
+		if pool.AppendCertsFromPEM(data) {
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("gracefulhttp: no CA certificate found in %s", c.dir)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pool = pool
+
+	return nil
+}
+
+// verify verifies the certificate chain presented by a client, returning the verified chains.
+func (c *clientCAs) verify(rawCerts [][]byte) ([][]*x509.Certificate, error) {
+	if len(rawCerts) == 0 {
+		return nil, ErrUnknownClientCA
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	c.mu.RLock()
+	roots := c.pool
+	c.mu.RUnlock()
+
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownClientCA, err)
+	}
+
+	return chains, nil
+}
+
+// refresh reloads the CAs at every interval, until the context is done.
+func (c *clientCAs) refresh(ctx context.Context, clock Clock, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+			_ = c.reload()
+		}
+	}
+}
+
+// watchSignals reloads the CAs every time one of the signals is received, until the context is done.
+func (c *clientCAs) watchSignals(ctx context.Context, sigs []os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			_ = c.reload()
+		}
+	}
+}
+
+// initializeClientCAs verifies the client certificates against the CAs of the directory, passing the
+// verified chains to the VerifyPeerCertificate callback of the configuration, if any, e.g. the CRL check.
+func (s *GracefulServer) initializeClientCAs() error {
+	c, err := newClientCAs(s.clientCADir)
+	if err != nil {
+		return err
+	}
+
+	cfg := s.ownTLSConfig()
+	cfg.ClientAuth = tls.RequireAnyClientCert
+	verify := cfg.VerifyPeerCertificate
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		chains, err := c.verify(rawCerts)
+		if err != nil {
+			return err
+		}
+
+		if verify != nil {
+			return verify(rawCerts, chains)
+		}
+
+		return nil
+	}
+
+	if s.clientCARefresh > 0 {
+		s.background = append(s.background, func(ctx context.Context) {
+			c.refresh(ctx, s.clock, s.clientCARefresh)
+		})
+	}
+
+	if len(s.clientCASignals) > 0 {
+		s.background = append(s.background, func(ctx context.Context) {
+			c.watchSignals(ctx, s.clientCASignals)
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCA writes the certificate of the CA as a PEM file in the directory.
+func writeCA(t *testing.T, dir, name string, ca *testCA) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func TestWithClientCADir(t *testing.T) {
+	ca := newTestCA(t)
+	newCA := newTestCA(t)
+	valid := ca.issue(t, 10)
+	revoked := ca.issue(t, 11)
+	later := newCA.issue(t, 12)
+
+	dir := t.TempDir()
+	writeCA(t, dir, "ca.pem", ca)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden.pem"), []byte("not a certificate"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0o700))
+
+	crlFile := filepath.Join(t.TempDir(), "crl.der")
+	require.NoError(t, os.WriteFile(crlFile, ca.revocationList(t, 11), 0o600))
+
+	clock := newManualClock()
+
+	s := Bind("", nil)
+	require.NoError(t, s.initialize([]GracefulServerOption{
+		WithClientCADir(dir, time.Minute),
+		WithClientCRL(time.Hour, crlFile),
+		WithClock(clock),
+	}))
+
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+	// The revocation list is refreshed in the background, ahead of the directory.
+	require.Len(t, s.background, 2)
+	assert.Equal(t, tls.RequireAnyClientCert, s.TLSConfig.ClientAuth)
+
+	verify := s.TLSConfig.VerifyPeerCertificate
+	assert.NoError(t, verify([][]byte{valid.Raw}, nil))
+	assert.ErrorIs(t, verify([][]byte{revoked.Raw}, nil), ErrCertificateRevoked)
+	assert.ErrorIs(t, verify([][]byte{later.Raw}, nil), ErrUnknownClientCA)
+	assert.ErrorIs(t, verify(nil, nil), ErrUnknownClientCA)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[1](ctx)
+
+	writeCA(t, dir, "new-ca.pem", newCA)
+	clock.fire()
+	clock.fire()
+
+	assert.NoError(t, verify([][]byte{later.Raw}, nil))
+	assert.NoError(t, verify([][]byte{valid.Raw}, nil))
+}
+
+func TestWithClientCADir_Errors(t *testing.T) {
+	empty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(empty, "readme.txt"), []byte("no CA here"), 0o600))
+
+	for _, dir := range []string{filepath.Join(empty, "missing"), empty} {
+		s := Bind("", nil)
+		require.NoError(t, s.initialize([]GracefulServerOption{WithClientCADir(dir, 0)}))
+
+		_, _, err := s.initializeTLS("", "")
+		assert.Error(t, err, dir)
+	}
+
+	assert.ErrorIs(t, ValidateOptions(WithClientCADir(empty, -time.Second)), ErrInvalidOption)
+}
+
+func TestWithCloudflareOriginPull(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+
+	s := Bind("", nil)
+	require.NoError(t, s.initialize([]GracefulServerOption{WithCloudflareOriginPull(caPEM)}))
+
+	assert.Equal(t, tls.RequireAndVerifyClientCert, s.TLSConfig.ClientAuth)
+	require.NotNil(t, s.TLSConfig.ClientCAs)
+
+	verify := func(cert *x509.Certificate) error {
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:     s.TLSConfig.ClientCAs,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		return err
+	}
+	assert.NoError(t, verify(ca.issue(t, 10)))
+	assert.Error(t, verify(otherCA.issue(t, 11)))
+
+	assert.ErrorIs(t, ValidateOptions(WithCloudflareOriginPull([]byte("not a certificate"))), ErrInvalidOption)
+}
@@ -0,0 +1,182 @@
+package gracefulhttp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passing of time for the shutdown sequence,
+// so that tests can simulate the graceful timeout expiring without real sleeps.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the [Clock] backed by the time package.
+type realClock struct{}
+
+// Now returns the current local time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After calls [time.After].
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// now returns the current time of the clock of the server, or of the real clock until it is set when serving.
+func (s *GracefulServer) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+
+	return s.clock.Now()
+}
+
+// withTimeout returns a context canceled with [context.DeadlineExceeded]
+// once the clock reports that the timeout, and the extensions of the deadline, if any, have elapsed.
+func withTimeout(clock Clock, timeout time.Duration) (*deadlineContext, context.CancelFunc) {
+	ctx := &deadlineContext{
+		deadline: clock.Now().Add(timeout),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		timer := clock.After(timeout)
+		for {
+			select {
+			case <-timer:
+				extension := ctx.expire()
+				if extension <= 0 {
+					return
+				}
+
+				timer = clock.After(extension)
+			case <-ctx.done:
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		ctx.cancel(context.Canceled)
+	}
+}
+
+// withoutTimeout returns a context with no deadline, canceled with [context.DeadlineExceeded]
+// once stop is done, so that its expiration is handled as the one of a timeout.
+func withoutTimeout(stop context.Context) (*deadlineContext, context.CancelFunc) {
+	ctx := &deadlineContext{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-stop.Done():
+			ctx.cancel(context.DeadlineExceeded)
+		case <-ctx.done:
+		}
+	}()
+
+	return ctx, func() {
+		ctx.cancel(context.Canceled)
+	}
+}
+
+// withStopDeadline returns a context canceled with [context.DeadlineExceeded] once stop is done,
+// as [withoutTimeout] does, reporting the deadline of stop, if any, which cannot be extended.
+func withStopDeadline(stop context.Context) (*deadlineContext, context.CancelFunc) {
+	ctx, cancel := withoutTimeout(stop)
+	ctx.deadline, _ = stop.Deadline()
+	ctx.fixed = true
+
+	return ctx, cancel
+}
+
+// deadlineContext is a context whose expiration is driven by a [Clock].
+type deadlineContext struct {
+	done chan struct{}
+
+	mu        sync.Mutex
+	deadline  time.Time
+	fixed     bool
+	extension time.Duration
+	err       error
+}
+
+// Deadline returns the time when the context expires, if any.
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.deadline, !c.deadline.IsZero()
+}
+
+// Done returns a channel closed when the context is canceled or expires.
+func (c *deadlineContext) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns [context.DeadlineExceeded] if the context expired,
+// or [context.Canceled] if it was canceled.
+func (c *deadlineContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Value returns nil, as the context carries no values.
+func (c *deadlineContext) Value(any) any {
+	return nil
+}
+
+// cancel closes the done channel, recording the error. It is safe to call multiple times.
+func (c *deadlineContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.err = err
+	close(c.done)
+}
+
+// extend pushes out the deadline by d, unless the context is already done, has no deadline or a fixed one.
+// It returns the new deadline and whether it was extended.
+func (c *deadlineContext) extend(d time.Duration) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil || c.deadline.IsZero() || c.fixed {
+		return c.deadline, false
+	}
+
+	c.deadline = c.deadline.Add(d)
+	c.extension += d
+
+	return c.deadline, true
+}
+
+// expire cancels the context with [context.DeadlineExceeded], unless the deadline was extended
+// in the meantime, in which case it returns the extension left to wait.
+func (c *deadlineContext) expire() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	extension := c.extension
+	c.extension = 0
+
+	if extension <= 0 && c.err == nil {
+		c.err = context.DeadlineExceeded
+		close(c.done)
+	}
+
+	return extension
+}
@@ -0,0 +1,124 @@
+package gracefulhttp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// manualClock is a Clock whose timers fire only when the test sends on them.
+type manualClock struct {
+	timers chan chan time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{
+		timers: make(chan chan time.Time, 1),
+	}
+}
+
+func (c *manualClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *manualClock) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.timers <- ch
+
+	return ch
+}
+
+// fire waits for the next timer to be created and expires it.
+func (c *manualClock) fire() {
+	timer := <-c.timers
+	timer <- time.Now()
+}
+
+// steppedClock is a Clock whose current time moves only when the test advances it.
+type steppedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *steppedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *steppedClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// advance moves the current time forward by d.
+func (c *steppedClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func Test_withTimeout(t *testing.T) {
+	t.Run("expire when the clock fires", func(t *testing.T) {
+		clock := newManualClock()
+
+		ctx, cancel := withTimeout(clock, time.Hour)
+		defer cancel()
+
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Second)
+		assert.NoError(t, ctx.Err())
+
+		clock.fire()
+		<-ctx.Done()
+
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+
+	t.Run("cancel before the clock fires", func(t *testing.T) {
+		clock := newManualClock()
+
+		ctx, cancel := withTimeout(clock, time.Hour)
+		cancel()
+		<-ctx.Done()
+
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	t.Run("extend the deadline", func(t *testing.T) {
+		clock := newManualClock()
+
+		ctx, cancel := withTimeout(clock, time.Hour)
+		defer cancel()
+
+		before, _ := ctx.Deadline()
+		after, ok := ctx.extend(time.Minute)
+		assert.True(t, ok)
+		assert.Equal(t, before.Add(time.Minute), after)
+
+		clock.fire()
+		assert.NoError(t, ctx.Err())
+
+		clock.fire()
+		<-ctx.Done()
+
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+
+		_, ok = ctx.extend(time.Minute)
+		assert.False(t, ok)
+	})
+
+	t.Run("use the real clock", func(t *testing.T) {
+		ctx, cancel := withTimeout(realClock{}, time.Millisecond)
+		defer cancel()
+
+		<-ctx.Done()
+
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,51 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// cloudRunDefaultPort is the port listened on by [WithCloudRunDefaults] when PORT is not set,
+	// as it is by the platform.
+	cloudRunDefaultPort = "8080"
+	// cloudRunGracefulTimeout leaves room to close the connections within the 10 seconds between
+	// the SIGTERM and the SIGKILL sent to a Cloud Run instance.
+	cloudRunGracefulTimeout = 8 * time.Second
+	// cloudRunForceCloseTimeout bounds the forced close within the rest of the 10 seconds.
+	cloudRunForceCloseTimeout = time.Second
+)
+
+// WithCloudRunDefaults bundles the behavior expected from a server running on Cloud Run and the similar
+// serverless platforms: the server listens on all the interfaces, on the port set by the PORT environment
+// variable, 8080 by default, and a SIGTERM starts the shutdown right away, as the instance no longer
+// receives traffic, with a graceful timeout of 8 seconds and a forced close bounded to 1 second, within the
+// 10 seconds given before the instance is killed. The read and write timeouts are disabled, leaving the
+// requests to be bounded by the request timeout of the service, and the idle timeout outlasts the keepalive
+// of the Google front end. The shutdown delay and the disabled keep-alives, useless behind the front end,
+// are reset. Apply the options after it to tune any of these settings.
+func WithCloudRunDefaults() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.Addr = net.JoinHostPort("0.0.0.0", cmp.Or(os.Getenv("PORT"), cloudRunDefaultPort))
+		s.addrs = nil
+
+		s.claimSetting("timeouts.read", "WithCloudRunDefaults")
+		s.claimSetting("timeouts.read_header", "WithCloudRunDefaults")
+		s.claimSetting("timeouts.write", "WithCloudRunDefaults")
+		s.claimSetting("timeouts.idle", "WithCloudRunDefaults")
+
+		s.ReadTimeout = 0
+		s.ReadHeaderTimeout = gcpReadHeaderTimeout
+		s.WriteTimeout = 0
+		s.IdleTimeout = gcpIdleTimeout
+
+		WithShutdownSignals(syscall.SIGTERM)(s)
+		s.shutdownDelay = 0
+		s.disableKeepAlives = false
+		s.gracefulTimeout = cloudRunGracefulTimeout
+		s.forceTimeout = cloudRunForceCloseTimeout
+	}
+}
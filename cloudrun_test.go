@@ -0,0 +1,35 @@
+package gracefulhttp
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCloudRunDefaults(t *testing.T) {
+	t.Run("port from the environment", func(t *testing.T) {
+		t.Setenv("PORT", "9090")
+
+		s := GracefulServer{}
+		WithCloudRunDefaults()(&s)
+
+		assert.Equal(t, "0.0.0.0:9090", s.Addr)
+		assert.Equal(t, []os.Signal{syscall.SIGTERM}, s.shutdownSignals)
+		assert.Zero(t, s.WriteTimeout)
+		assert.LessOrEqual(t, s.shutdownDelay+s.gracefulTimeout+s.forceTimeout, 10*time.Second)
+	})
+
+	t.Run("default port", func(t *testing.T) {
+		t.Setenv("PORT", "")
+
+		s := GracefulServer{}
+		WithShutdownDelay(5 * time.Second)(&s)
+		WithCloudRunDefaults()(&s)
+
+		assert.Equal(t, "0.0.0.0:8080", s.Addr)
+		assert.Zero(t, s.shutdownDelay)
+	})
+}
@@ -0,0 +1,170 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Config is a declarative configuration of a [GracefulServer], e.g. decoded from a JSON or YAML file.
+// The zero values keep the defaults.
+type Config struct {
+	// Addr is the address to listen on.
+	Addr string `json:"addr,omitempty" yaml:"addr,omitempty"`
+	// Handler is the handler to invoke, [http.DefaultServeMux] if nil.
+	Handler http.Handler `json:"-" yaml:"-"`
+
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout and IdleTimeout are the [http.Server] timeouts.
+	ReadTimeout       Duration `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout,omitempty" yaml:"read_header_timeout,omitempty"`
+	WriteTimeout      Duration `json:"write_timeout,omitempty" yaml:"write_timeout,omitempty"`
+	IdleTimeout       Duration `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	// ShutdownTimeout is the timeout of the graceful shutdown.
+	ShutdownTimeout Duration `json:"shutdown_timeout,omitempty" yaml:"shutdown_timeout,omitempty"`
+
+	// TLS configures the certificate and the protocol settings.
+	TLS TLSSettings `json:"tls,omitzero" yaml:"tls,omitempty"`
+
+	// Presets are the names of the best practice configurations applied before the settings above:
+	// "cloudflare-timeouts", "cloudflare-tls", "gcp-lb-timeouts", "nginx-upstream-timeouts" and "heroku-timeouts".
+	Presets []string `json:"presets,omitempty" yaml:"presets,omitempty"`
+}
+
+// TLSSettings configures the TLS settings of a [Config].
+type TLSSettings struct {
+	// CertFile and KeyFile are the certificate and private key files, served as with [WithKeyPairPEM].
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	// Profile is the name of a [MozillaTLSProfile]: "modern", "intermediate" or "old".
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
+// Duration is a [time.Duration] encoded as a string, such as "1m30s", in the configuration files.
+type Duration time.Duration
+
+// MarshalText encodes the duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText decodes a string parsed by [time.ParseDuration].
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// configPresets are the options applied by the presets of a [Config].
+var configPresets = map[string]GracefulServerOption{
+	"cloudflare-timeouts":     WithCloudflareTimeouts(),
+	"cloudflare-tls":          WithCloudflareTLSConfig(),
+	"gcp-lb-timeouts":         WithGCPLoadBalancerTimeouts(),
+	"nginx-upstream-timeouts": WithNginxUpstreamTimeouts(),
+	"heroku-timeouts":         WithHerokuTimeouts(),
+}
+
+// Validate reports the invalid settings of the configuration.
+func (c Config) Validate() error {
+	var errs []error
+
+	durations := []struct {
+		name  string
+		value Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"read_header_timeout", c.ReadHeaderTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("gracefulhttp: %s must not be negative", d.name))
+		}
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("gracefulhttp: tls.cert_file and tls.key_file must be set together"))
+	}
+
+	if c.TLS.Profile != "" {
+		if _, err := parseMozillaTLSProfile(c.TLS.Profile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, preset := range c.Presets {
+		if _, ok := configPresets[preset]; !ok {
+			errs = append(errs, fmt.Errorf("gracefulhttp: unknown preset %q", preset))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// options returns the options applying the configuration, which must be valid.
+func (c Config) options() []GracefulServerOption {
+	var opts []GracefulServerOption
+
+	for _, preset := range c.Presets {
+		opts = append(opts, configPresets[preset])
+	}
+
+	timeouts := []struct {
+		value   Duration
+		setting string
+		set     func(s *GracefulServer, d time.Duration)
+	}{
+		{c.ReadTimeout, "timeouts.read", func(s *GracefulServer, d time.Duration) { s.ReadTimeout = d }},
+		{c.ReadHeaderTimeout, "timeouts.read_header", func(s *GracefulServer, d time.Duration) { s.ReadHeaderTimeout = d }},
+		{c.WriteTimeout, "timeouts.write", func(s *GracefulServer, d time.Duration) { s.WriteTimeout = d }},
+		{c.IdleTimeout, "timeouts.idle", func(s *GracefulServer, d time.Duration) { s.IdleTimeout = d }},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value > 0 {
+			opts = append(opts, func(s *GracefulServer) {
+				// The settings override the presets by design.
+				s.overrideSetting(timeout.setting, "NewFromConfig")
+				timeout.set(s, time.Duration(timeout.value))
+			})
+		}
+	}
+
+	if c.ShutdownTimeout > 0 {
+		opts = append(opts, WithShutdownTimeout(time.Duration(c.ShutdownTimeout)))
+	}
+
+	if c.TLS.Profile != "" {
+		profile, _ := parseMozillaTLSProfile(c.TLS.Profile)
+		opts = append(opts, WithMozillaTLSProfile(profile))
+	}
+
+	if c.TLS.CertFile != "" {
+		certFile, keyFile := c.TLS.CertFile, c.TLS.KeyFile
+		opts = append(opts, func(s *GracefulServer) {
+			s.certLoaders = append(s.certLoaders, func() (tls.Certificate, error) {
+				return tls.LoadX509KeyPair(certFile, keyFile)
+			})
+		})
+	}
+
+	return opts
+}
+
+// NewFromConfig returns a new [GracefulServer] configured declaratively, or the validation error
+// of the configuration. The options passed when serving are applied after the configuration.
+// When a certificate is configured, pass empty certFile and keyFile to [GracefulServer.ListenAndServeTLSWithShutdown].
+func NewFromConfig(cfg Config) (*GracefulServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return Bind(cfg.Addr, cfg.Handler, cfg.options()...), nil
+}
@@ -0,0 +1,69 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	t.Run("apply the configuration", func(t *testing.T) {
+		certFile, keyFile := testKeyPairFiles(t)
+
+		var cfg Config
+		require.NoError(t, json.Unmarshal(fmt.Appendf(nil, `{
+			"addr": "localhost:8443",
+			"read_timeout": "3s",
+			"write_timeout": "1m",
+			"shutdown_timeout": "30s",
+			"tls": {"cert_file": %q, "key_file": %q, "profile": "modern"},
+			"presets": ["cloudflare-timeouts"]
+		}`, certFile, keyFile), &cfg))
+
+		s, err := NewFromConfig(cfg)
+		require.NoError(t, err)
+		require.NoError(t, s.initialize([]GracefulServerOption{WithShutdownTimeout(time.Minute)}))
+
+		assert.Equal(t, "localhost:8443", s.Addr)
+		assert.Equal(t, 3*time.Second, s.ReadTimeout)
+		assert.Equal(t, defaultReadHeaderTimeout, s.ReadHeaderTimeout)
+		assert.Equal(t, time.Minute, s.WriteTimeout)
+		assert.Equal(t, defaultIdleTimeout, s.IdleTimeout)
+		assert.Equal(t, time.Minute, s.gracefulTimeout, "options passed when serving apply last")
+		assert.Equal(t, uint16(tls.VersionTLS13), s.TLSConfig.MinVersion)
+		assert.Len(t, s.certLoaders, 1)
+	})
+
+	t.Run("reject an invalid configuration", func(t *testing.T) {
+		s, err := NewFromConfig(Config{
+			ShutdownTimeout: Duration(-time.Second),
+			TLS:             TLSSettings{CertFile: "cert.pem", Profile: "paranoid"},
+			Presets:         []string{"unknown"},
+		})
+		assert.Nil(t, s)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "shutdown_timeout")
+		assert.ErrorContains(t, err, "tls.cert_file and tls.key_file")
+		assert.ErrorContains(t, err, `"paranoid"`)
+		assert.ErrorContains(t, err, `"unknown"`)
+	})
+}
+
+func TestDuration(t *testing.T) {
+	d := Duration(90 * time.Second)
+
+	text, err := json.Marshal(d)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"1m30s"`, string(text))
+
+	var got Duration
+	require.NoError(t, json.Unmarshal(text, &got))
+	assert.Equal(t, d, got)
+
+	require.Error(t, json.Unmarshal([]byte(`"soon"`), &got))
+}
@@ -0,0 +1,410 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnStats is a snapshot of the connections handled by a [GracefulServer].
+type ConnStats struct {
+	// New is the number of connections that have not sent a request yet.
+	New int
+	// Active is the number of connections currently serving a request.
+	Active int
+	// Idle is the number of keep-alive connections waiting for a new request.
+	Idle int
+	// Hijacked is the total number of connections taken over by handlers.
+	Hijacked int
+}
+
+// Stats is a snapshot of the activity of a [GracefulServer] since it started serving.
+type Stats struct {
+	ConnStats
+
+	// Accepted is the total number of connections accepted.
+	Accepted int64
+	// Requests is the total number of requests received, including the rejected ones.
+	Requests int64
+	// BytesWritten is the total number of bytes written in the response bodies,
+	// if tracked through [WithBytesTracking].
+	BytesWritten int64
+
+	// GracefulShutdowns is the number of shutdowns completed without closing any connection forcibly.
+	GracefulShutdowns int64
+	// ForcedShutdowns is the number of shutdowns which had to close the connections forcibly,
+	// either because the graceful timeout expired or because of an immediate stop.
+	ForcedShutdowns int64
+	// ForceClosedConns is the total number of connections closed forcibly, hijacked ones included.
+	ForceClosedConns int64
+}
+
+// ConnInfo describes a connection handled by a [GracefulServer].
+type ConnInfo struct {
+	// RemoteAddr is the network address of the client.
+	RemoteAddr net.Addr
+	// State is the last observed state of the connection.
+	State http.ConnState
+	// Age is the time elapsed since the connection was established.
+	Age time.Duration
+	// Tag is the tag set through [GracefulServer.TagConn], if any.
+	Tag string
+	// Inactive is the time elapsed since the connection last started or completed a request,
+	// or since it was established if it has not sent any.
+	Inactive time.Duration
+	// Requests is the number of requests served on the connection, including the one in progress.
+	Requests int64
+	// BytesRead is the number of bytes read from the connection, TLS records included,
+	// if tracked through [WithBytesTracking].
+	BytesRead int64
+	// BytesWritten is the number of bytes written to the connection, TLS records included,
+	// if tracked through [WithBytesTracking].
+	BytesWritten int64
+	// Conn is the connection, e.g. to close the oldest idle ones first from a [DrainStrategy].
+	Conn net.Conn
+}
+
+// trackedConn holds the state of a connection observed through [http.Server.ConnState].
+type trackedConn struct {
+	state       http.ConnState
+	established time.Time
+	lastActive  time.Time
+	requests    int64
+	tag         string
+	pending     bool
+}
+
+// connTracker maintains the state of the open connections. The zero value is ready to use,
+// timing the connections with the real clock.
+type connTracker struct {
+	clock    Clock
+	mu       sync.Mutex
+	conns    map[net.Conn]*trackedConn
+	accepted int64
+	hijacked int
+}
+
+// track records a connection state transition.
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conns == nil {
+		t.conns = make(map[net.Conn]*trackedConn)
+	}
+
+	switch state {
+	case http.StateNew:
+		// The connection may already be tracked from the ConnContext callback.
+		if _, ok := t.conns[c]; !ok {
+			now := t.now()
+			t.accepted++
+			t.conns[c] = &trackedConn{
+				state:       state,
+				established: now,
+				lastActive:  now,
+			}
+		}
+	case http.StateActive, http.StateIdle:
+		if tc, ok := t.conns[c]; ok {
+			tc.state = state
+			tc.lastActive = t.now()
+		}
+	case http.StateHijacked:
+		delete(t.conns, c)
+		t.hijacked++
+	case http.StateClosed:
+		delete(t.conns, c)
+	}
+}
+
+// stats returns a snapshot of the tracked connections, and the total number of accepted ones.
+func (t *connTracker) stats() (ConnStats, int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := ConnStats{
+		Hijacked: t.hijacked,
+	}
+
+	for _, tc := range t.conns {
+		switch tc.state {
+		case http.StateNew:
+			stats.New++
+		case http.StateActive:
+			stats.Active++
+		case http.StateIdle:
+			stats.Idle++
+		}
+	}
+
+	return stats, t.accepted
+}
+
+// open returns the number of tracked connections.
+func (t *connTracker) open() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.conns)
+}
+
+// snapshot returns the details of the tracked connections.
+func (t *connTracker) snapshot() []ConnInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	infos := make([]ConnInfo, 0, len(t.conns))
+	for c, tc := range t.conns {
+		info := ConnInfo{
+			RemoteAddr: c.RemoteAddr(),
+			State:      tc.state,
+			Age:        now.Sub(tc.established),
+			Tag:        tc.tag,
+			Inactive:   now.Sub(tc.lastActive),
+			Requests:   tc.requests,
+			Conn:       c,
+		}
+
+		if cc, ok := findConn[*meteredConn](c); ok {
+			info.BytesRead, info.BytesWritten = cc.read.Load(), cc.written.Load()
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// now returns the current time of the clock of the tracker, if any.
+func (t *connTracker) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+
+	return t.clock.Now()
+}
+
+// recordRequest records a request served on a tracked connection.
+func (t *connTracker) recordRequest(c net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tc, ok := t.conns[c]; ok {
+		tc.requests++
+		tc.lastActive = t.now()
+	}
+}
+
+// meteredConn counts the bytes read from and written to the connection.
+type meteredConn struct {
+	net.Conn
+
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+// Read reads from the connection, counting the bytes read.
+func (c *meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+
+	return n, err
+}
+
+// Write writes to the connection, counting the bytes written.
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+
+	return n, err
+}
+
+// NetConn returns the wrapped connection.
+func (c *meteredConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// meteredListener wraps the accepted connections to count their bytes.
+type meteredListener struct {
+	net.Listener
+}
+
+// Accept accepts the next connection, counting its bytes.
+func (l meteredListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &meteredConn{Conn: c}, nil
+}
+
+// setTag tags a tracked connection, reporting whether it is tracked.
+func (t *connTracker) setTag(c net.Conn, tag string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.conns[c]
+	if ok {
+		tc.tag = tag
+	}
+
+	return ok
+}
+
+// tagged returns the tracked connections with the given tag.
+func (t *connTracker) tagged(tag string) []net.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var conns []net.Conn
+	for c, tc := range t.conns {
+		if tc.tag == tag {
+			conns = append(conns, c)
+		}
+	}
+
+	return conns
+}
+
+// Conns returns the details of the connections handled by the server, hijacked ones excluded, e.g. for
+// a [DrainStrategy] or a [WithOnForceClose] callback to decide which connections to close first.
+// The connections are observed through a [http.Server.ConnState] callback installed when serving.
+func (s *GracefulServer) Conns() []ConnInfo {
+	return s.conns.snapshot()
+}
+
+// ConnStats returns a snapshot of the connections handled by the server,
+// observed through a [http.Server.ConnState] callback installed when serving.
+// A ConnState callback set by the user keeps being invoked.
+func (s *GracefulServer) ConnStats() ConnStats {
+	stats, _ := s.conns.stats()
+
+	return stats
+}
+
+// Stats returns a snapshot of the activity of the server since it started serving, to be fed
+// to any monitoring system: the connections, as reported by [GracefulServer.ConnStats], the total
+// numbers of accepted connections and of requests, the bytes written, if tracked, and the outcomes
+// of the shutdowns, which tell whether the deployments drain the traffic within their budget.
+func (s *GracefulServer) Stats() Stats {
+	conns, accepted := s.conns.stats()
+
+	return Stats{
+		ConnStats:    conns,
+		Accepted:     accepted,
+		Requests:     s.requestCount.Load(),
+		BytesWritten: s.bytesWritten.Load(),
+
+		GracefulShutdowns: s.cleanShutdowns.Load(),
+		ForcedShutdowns:   s.forcedShutdowns.Load(),
+		ForceClosedConns:  s.forceClosedConns.Load(),
+	}
+}
+
+// WithBytesTracking tracks the number of bytes written in the response bodies, reported by [GracefulServer.Stats],
+// and the bytes read from and written to each connection, reported by [GracefulServer.Conns].
+func WithBytesTracking() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.trackBytes = true
+	}
+}
+
+// statsHandler counts the requests, the ones in flight and, if tracked, the bytes written in the responses.
+func (s *GracefulServer) statsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requestCount.Add(1)
+		s.inFlightRequests.Add(1)
+		defer s.inFlightRequests.Add(-1)
+		if c, ok := r.Context().Value(connContextKey{}).(net.Conn); ok {
+			s.conns.recordRequest(c)
+		}
+
+		if !s.trackBytes {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			s.bytesWritten.Add(rw.written)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// installConnState installs the connection tracking callback, composing it
+// with the one provided by the user, if any.
+func (s *GracefulServer) installConnState() {
+	connState := s.ConnState
+	s.conns.clock = s.clock
+
+	s.ConnState = func(c net.Conn, state http.ConnState) {
+		s.conns.track(c, state)
+
+		if state == http.StateActive && s.handshakeTimeout > 0 {
+			if hc, ok := findConn[*handshakeConn](c); ok {
+				hc.established()
+			}
+		}
+
+		if s.minDataRate > 0 {
+			if dc, ok := findConn[*dataRateConn](c); ok {
+				dc.setActive(state == http.StateActive)
+			}
+		}
+
+		if s.metrics != nil {
+			if state == http.StateNew {
+				s.addCounter(MetricConnectionsAccepted, 1)
+			}
+			s.setGauge(MetricConnectionsOpen, float64(s.conns.open()))
+		}
+
+		if connState != nil {
+			connState(c, state)
+		}
+	}
+}
+
+// connContextKey is the context key of the connection serving a request.
+type connContextKey struct{}
+
+// installConnContext stores the connection in the context of the requests it serves,
+// so that it can be tagged, composing with the callback provided by the user, if any.
+// As net/http invokes the callback before reporting [http.StateNew], the connection
+// is tracked from there, letting the user callback tag it as well.
+func (s *GracefulServer) installConnContext() {
+	connContext := s.ConnContext
+
+	s.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		s.conns.track(c, http.StateNew)
+		ctx = context.WithValue(ctx, connContextKey{}, c)
+		ctx = context.WithValue(ctx, serverContextKey{}, s)
+
+		if connContext != nil {
+			return connContext(ctx, c)
+		}
+
+		return ctx
+	}
+}
+
+// TagConn tags the connection serving the request with the context, e.g. "batch" or "interactive",
+// from a handler or a [http.Server.ConnContext] callback. When the graceful timeout expires,
+// the tagged connections are closed in the order set by [WithForceCloseOrder].
+// It reports whether the connection was tagged.
+func (s *GracefulServer) TagConn(ctx context.Context, tag string) bool {
+	c, ok := ctx.Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return false
+	}
+
+	return s.conns.setTag(c, tag)
+}
@@ -0,0 +1,287 @@
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_ConnStats(t *testing.T) {
+	var userStates []http.ConnState
+
+	s := Bind("", nil)
+	s.ConnState = func(c net.Conn, state http.ConnState) {
+		userStates = append(userStates, state)
+	}
+	s.initialize(nil)
+
+	c1, _ := net.Pipe()
+	c2, _ := net.Pipe()
+	c3, _ := net.Pipe()
+
+	s.ConnState(c1, http.StateNew)
+	s.ConnState(c2, http.StateNew)
+	s.ConnState(c3, http.StateNew)
+	assert.Equal(t, ConnStats{New: 3}, s.ConnStats())
+
+	s.ConnState(c1, http.StateActive)
+	s.ConnState(c2, http.StateActive)
+	s.ConnState(c2, http.StateIdle)
+	assert.Equal(t, ConnStats{New: 1, Active: 1, Idle: 1}, s.ConnStats())
+
+	s.ConnState(c1, http.StateHijacked)
+	s.ConnState(c2, http.StateClosed)
+	assert.Equal(t, ConnStats{New: 1, Hijacked: 1}, s.ConnStats())
+
+	s.ConnState(c3, http.StateClosed)
+	assert.Equal(t, ConnStats{Hijacked: 1}, s.ConnStats())
+
+	assert.Len(t, userStates, 9)
+}
+
+func TestGracefulServer_Stats(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithBytesTracking())
+	}()
+	<-s.Ready()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	for range 3 {
+		resp, err := client.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	stats := s.Stats()
+	assert.Equal(t, int64(1), stats.Accepted)
+	assert.Equal(t, int64(3), stats.Requests)
+	assert.Equal(t, int64(15), stats.BytesWritten)
+
+	client.CloseIdleConnections()
+	cancel()
+	require.NoError(t, <-done)
+
+	stats = s.Stats()
+	assert.Equal(t, int64(1), stats.GracefulShutdowns)
+	assert.Zero(t, stats.ForcedShutdowns)
+	assert.Zero(t, stats.ForceClosedConns)
+}
+
+func TestGracefulServer_Stats_Untracked(t *testing.T) {
+	s := Bind("", &delayedHandler{})
+	require.NoError(t, s.initialize(nil))
+
+	s.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, Stats{Requests: 1}, s.Stats())
+}
+
+func TestWithOnForceClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	s := Bind("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	reported := make(chan []ConnInfo, 1)
+	s.initialize([]GracefulServerOption{
+		WithShutdownTimeout(100 * time.Millisecond),
+		WithOnForceClose(func(conns []ConnInfo) {
+			reported <- conns
+		}),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.serve(ctx, func() error {
+			return s.Serve(ln)
+		})
+	}()
+
+	go func() {
+		_, _ = http.Get("http://" + ln.Addr().String())
+	}()
+
+	<-started
+	cancel()
+
+	require.NoError(t, <-done)
+
+	conns := <-reported
+	require.Len(t, conns, 1)
+	assert.Equal(t, http.StateActive, conns[0].State)
+	assert.NotNil(t, conns[0].RemoteAddr)
+	assert.Positive(t, conns[0].Age)
+
+	stats := s.Stats()
+	assert.Zero(t, stats.GracefulShutdowns)
+	assert.Equal(t, int64(1), stats.ForcedShutdowns)
+	assert.Equal(t, int64(1), stats.ForceClosedConns)
+}
+
+func Test_connTracker_clock(t *testing.T) {
+	clock := &steppedClock{now: time.Unix(0, 0)}
+	tracker := connTracker{clock: clock}
+
+	c, peer := net.Pipe()
+	defer c.Close()
+	defer peer.Close()
+
+	tracker.track(c, http.StateNew)
+	clock.advance(time.Minute)
+
+	infos := tracker.snapshot()
+	require.Len(t, infos, 1)
+	assert.Equal(t, time.Minute, infos[0].Age)
+}
+
+func TestWithForceCloseOrder(t *testing.T) {
+	var s *GracefulServer
+	s = Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.TagConn(r.Context(), r.URL.Query().Get("tag"))
+		<-r.Context().Done()
+	}))
+
+	clock := newManualClock()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithForceCloseOrder(time.Second, "batch", "interactive"))
+	}()
+
+	<-s.Ready()
+
+	get := func(tag string) <-chan error {
+		errs := make(chan error, 1)
+		go func() {
+			r, err := http.Get("http://" + s.ListenerAddr().String() + "/?tag=" + tag)
+			if err == nil {
+				_ = r.Body.Close()
+			}
+			errs <- err
+		}()
+
+		return errs
+	}
+
+	batch := get("batch")
+	interactive := get("interactive")
+
+	for len(s.conns.tagged("batch")) == 0 || len(s.conns.tagged("interactive")) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	clock.fire()
+
+	require.Error(t, <-batch)
+
+	select {
+	case <-interactive:
+		t.Fatal("interactive connection closed before the batch interval")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.fire()
+
+	require.Error(t, <-interactive)
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_TagConn(t *testing.T) {
+	var s *GracefulServer
+	s = Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, info := range s.conns.snapshot() {
+			_, _ = w.Write([]byte(info.Tag))
+		}
+	}))
+
+	tagged := make(chan bool, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			tagged <- s.TagConn(ctx, "batch")
+			return ctx
+		}))
+	}()
+
+	<-s.Ready()
+	assert.False(t, s.TagConn(context.Background(), "batch"))
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	require.NoError(t, err)
+
+	assert.True(t, <-tagged)
+	assert.Equal(t, "batch", string(body))
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_Conns(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithBytesTracking())
+	}()
+	<-s.Ready()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	for range 2 {
+		r, err := client.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, r.Body)
+		_ = r.Body.Close()
+	}
+
+	require.Eventually(t, func() bool { return s.ConnStats().Idle == 1 }, time.Second, 10*time.Millisecond)
+
+	conns := s.Conns()
+	require.Len(t, conns, 1)
+	assert.Equal(t, http.StateIdle, conns[0].State)
+	assert.Equal(t, int64(2), conns[0].Requests)
+	assert.Positive(t, conns[0].BytesRead)
+	assert.Positive(t, conns[0].BytesWritten)
+	assert.LessOrEqual(t, conns[0].Inactive, conns[0].Age)
+
+	// The connection can be closed by a drain strategy.
+	require.NotNil(t, conns[0].Conn)
+	require.NoError(t, conns[0].Conn.Close())
+	require.Eventually(t, func() bool { return len(s.Conns()) == 0 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
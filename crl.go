@@ -0,0 +1,228 @@
+package gracefulhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCertificateRevoked is returned during the TLS handshake when a client certificate has been revoked.
+	ErrCertificateRevoked = errors.New("gracefulhttp: client certificate revoked")
+	// ErrCRLExpired is returned when a certificate revocation list is past its next update, so that the
+	// revocations issued since are unknown: it fails the loading of the list, and the TLS handshakes of
+	// the client certificates it covers if it expires while in use.
+	ErrCRLExpired = errors.New("gracefulhttp: certificate revocation list expired")
+)
+
+const (
+	// crlFetchTimeout bounds the download of a revocation list.
+	crlFetchTimeout = 10 * time.Second
+	// maxCRLSize bounds the size of a downloaded revocation list, so that an endpoint serving
+	// an oversized one cannot exhaust the memory.
+	maxCRLSize = 32 << 20
+	// crlRetryInterval is the interval between the reloads of the revocation lists once one is
+	// past its next update, until a newer one is published.
+	crlRetryInterval = time.Minute
+)
+
+// crlChecker rejects the client certificates listed in the certificate revocation lists
+// loaded from files or URLs.
+type crlChecker struct {
+	sources []string
+	clock   Clock
+
+	mu   sync.RWMutex
+	crls []*x509.RevocationList
+}
+
+// newCRLChecker returns a crlChecker with the revocation lists loaded from the sources.
+func newCRLChecker(ctx context.Context, clock Clock, sources []string) (*crlChecker, error) {
+	c := &crlChecker{
+		sources: sources,
+		clock:   clock,
+	}
+
+	if err := c.reload(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// reload loads the revocation lists from the sources, keeping the previous ones on failure.
+// The lists past their next update are rejected.
+func (c *crlChecker) reload(ctx context.Context) error {
+	crls := make([]*x509.RevocationList, 0, len(c.sources))
+
+	for _, source := range c.sources {
+		crl, err := loadCRL(ctx, source)
+		if err == nil && c.expired(crl) {
+			err = ErrCRLExpired
+		}
+		if err != nil {
+			return fmt.Errorf("gracefulhttp: loading CRL from %s: %w", source, err)
+		}
+
+		crls = append(crls, crl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.crls = crls
+
+	return nil
+}
+
+// refresh reloads the revocation lists at every interval, if positive, and once the next update of
+// a list is due, until the context is done. Revocation lists that fail to load are ignored and the
+// previous ones keep being used.
+func (c *crlChecker) refresh(ctx context.Context, interval time.Duration) {
+	for {
+		wait := c.nextRefresh(interval)
+		if wait <= 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(wait):
+			_ = c.reload(ctx)
+		}
+	}
+}
+
+// nextRefresh returns the time until the next reload of the revocation lists: the interval,
+// or the time until the next update of a list, if sooner, or zero if they need no reload.
+func (c *crlChecker) nextRefresh(interval time.Duration) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	wait := interval
+	for _, crl := range c.crls {
+		if crl.NextUpdate.IsZero() {
+			continue
+		}
+
+		due := crl.NextUpdate.Sub(c.clock.Now())
+		if due <= 0 {
+			due = crlRetryInterval
+		}
+		if wait <= 0 || due < wait {
+			wait = due
+		}
+	}
+
+	return wait
+}
+
+// expired reports whether the revocation list is past its next update, if any.
+func (c *crlChecker) expired(crl *x509.RevocationList) bool {
+	return !crl.NextUpdate.IsZero() && !c.clock.Now().Before(crl.NextUpdate)
+}
+
+// VerifyPeerCertificate rejects the verified chains containing a revoked certificate, or a certificate
+// whose revocation list has expired. Only the revocation lists signed by the issuer of the certificate
+// are taken into account.
+func (c *crlChecker) VerifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, chain := range verifiedChains {
+		for i := 0; i < len(chain)-1; i++ {
+			if err := c.check(chain[i], chain[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// check returns an error if the certificate is listed in a revocation list signed by its issuer,
+// or if such a list has expired.
+func (c *crlChecker) check(cert, issuer *x509.Certificate) error {
+	for _, crl := range c.crls {
+		if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) || crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+
+		if c.expired(crl) {
+			return ErrCRLExpired
+		}
+
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return ErrCertificateRevoked
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadCRL loads a DER or PEM encoded revocation list from a file or an http(s) URL.
+func loadCRL(ctx context.Context, source string) (*x509.RevocationList, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err = fetchCRL(ctx, source, crlFetchTimeout, maxCRLSize)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	return x509.ParseRevocationList(data)
+}
+
+// fetchCRL downloads a revocation list of at most maxSize bytes within the timeout.
+func fetchCRL(ctx context.Context, url string, timeout time.Duration, maxSize int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", r.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("revocation list larger than %d bytes", maxSize)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,256 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func (ca *testCA) revocationList(t *testing.T, serials ...int64) []byte {
+	t.Helper()
+
+	return ca.revocationListUntil(t, time.Now().Add(time.Hour), serials...)
+}
+
+func (ca *testCA) revocationListUntil(t *testing.T, nextUpdate time.Time, serials ...int64) []byte {
+	t.Helper()
+
+	entries := make([]x509.RevocationListEntry, 0, len(serials))
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now(),
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                nextUpdate.Add(-2 * time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+
+	return der
+}
+
+func TestWithClientCRL(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	valid := ca.issue(t, 10)
+	revoked := ca.issue(t, 11)
+
+	dir := t.TempDir()
+	derFile := filepath.Join(dir, "crl.der")
+	require.NoError(t, os.WriteFile(derFile, ca.revocationList(t, 11), 0o600))
+
+	pemFile := filepath.Join(dir, "crl.pem")
+	require.NoError(t, os.WriteFile(pemFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "X509 CRL",
+		Bytes: ca.revocationList(t, 11),
+	}), 0o600))
+
+	// A list with the same issuer name but signed by another key is ignored.
+	forged := otherCA.revocationList(t, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(ca.revocationList(t, 11))
+	}))
+	defer srv.Close()
+
+	forgedFile := filepath.Join(dir, "forged.der")
+	require.NoError(t, os.WriteFile(forgedFile, forged, 0o600))
+
+	tests := []struct {
+		name    string
+		sources []string
+	}{
+		{name: "der file", sources: []string{derFile, forgedFile}},
+		{name: "pem file", sources: []string{pemFile}},
+		{name: "url", sources: []string{srv.URL}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", nil)
+			s.initialize([]GracefulServerOption{WithClientCRL(time.Hour, tt.sources...)})
+
+			_, _, err := s.initializeTLS("", "")
+			require.NoError(t, err)
+
+			verify := s.TLSConfig.VerifyPeerCertificate
+			assert.NoError(t, verify(nil, [][]*x509.Certificate{{valid, ca.cert}}))
+			assert.ErrorIs(t, verify(nil, [][]*x509.Certificate{{revoked, ca.cert}}), ErrCertificateRevoked)
+		})
+	}
+}
+
+func TestWithClientCRL_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	for _, source := range []string{"missing.crl", srv.URL} {
+		s := Bind("", nil)
+		s.initialize([]GracefulServerOption{WithClientCRL(time.Hour, source)})
+
+		_, _, err := s.initializeTLS("", "")
+		assert.Error(t, err, source)
+	}
+}
+
+func TestWithClientCRL_Expired(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, 10)
+
+	t.Run("rejected when loaded", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "crl.der")
+		require.NoError(t, os.WriteFile(file, ca.revocationListUntil(t, time.Now().Add(-time.Minute)), 0o600))
+
+		s := Bind("", nil)
+		s.initialize([]GracefulServerOption{WithClientCRL(time.Hour, file)})
+
+		_, _, err := s.initializeTLS("", "")
+		assert.ErrorIs(t, err, ErrCRLExpired)
+	})
+
+	t.Run("certificates rejected once expired", func(t *testing.T) {
+		crl, err := x509.ParseRevocationList(ca.revocationListUntil(t, time.Now().Add(-time.Minute)))
+		require.NoError(t, err)
+
+		c := &crlChecker{clock: realClock{}, crls: []*x509.RevocationList{crl}}
+		assert.ErrorIs(t, c.VerifyPeerCertificate(nil, [][]*x509.Certificate{{cert, ca.cert}}), ErrCRLExpired)
+		assert.Equal(t, crlRetryInterval, c.nextRefresh(0))
+	})
+
+	t.Run("reloaded once the next update is due", func(t *testing.T) {
+		crl, err := x509.ParseRevocationList(ca.revocationListUntil(t, time.Now().Add(time.Hour)))
+		require.NoError(t, err)
+
+		c := &crlChecker{clock: realClock{}, crls: []*x509.RevocationList{crl}}
+		assert.InDelta(t, time.Hour, c.nextRefresh(0), float64(time.Minute))
+		assert.Equal(t, time.Minute, c.nextRefresh(time.Minute))
+		assert.Zero(t, (&crlChecker{clock: realClock{}}).nextRefresh(0))
+	})
+}
+
+func Test_fetchCRL_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	_, err := fetchCRL(context.Background(), srv.URL, 50*time.Millisecond, maxCRLSize)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_fetchCRL_MaxSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 1025))
+	}))
+	defer srv.Close()
+
+	_, err := fetchCRL(context.Background(), srv.URL, time.Second, 1024)
+	assert.ErrorContains(t, err, "larger than 1024 bytes")
+
+	data, err := fetchCRL(context.Background(), srv.URL, time.Second, 1025)
+	require.NoError(t, err)
+	assert.Len(t, data, 1025)
+}
+
+func TestWithClientCRL_Refresh(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, 10)
+
+	file := filepath.Join(t.TempDir(), "crl.der")
+	require.NoError(t, os.WriteFile(file, ca.revocationList(t), 0o600))
+
+	clock := newManualClock()
+
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithClientCRL(time.Minute, file), WithClock(clock)})
+
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+	require.Len(t, s.background, 1)
+
+	verify := s.TLSConfig.VerifyPeerCertificate
+	require.NoError(t, verify(nil, [][]*x509.Certificate{{cert, ca.cert}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[0](ctx)
+
+	require.NoError(t, os.WriteFile(file, ca.revocationList(t, 10), 0o600))
+	clock.fire()
+	clock.fire()
+
+	assert.ErrorIs(t, verify(nil, [][]*x509.Certificate{{cert, ca.cert}}), ErrCertificateRevoked)
+}
@@ -0,0 +1,225 @@
+package gracefulhttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithMinDataRate closes the connections transferring less than rate bytes per second, on average,
+// once they have spent the grace period blocked in reads or writes, so that slow clients cannot
+// pin connections with endless uploads or downloads. Only the time spent waiting for the client
+// is measured: the reads of the request bodies of HTTP/1.x requests, and the writes of the responses.
+// A slow client is reported [ErrTransferTooSlow]. A non-positive rate means no limit.
+func WithMinDataRate(rate int64, grace time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if rate < 0 || grace < 0 {
+			s.invalidOption("WithMinDataRate", "negative rate %d or grace period %v", rate, grace)
+		}
+
+		s.minDataRate = rate
+		s.minDataRateGrace = grace
+	}
+}
+
+// dataRateListener wraps the accepted connections to enforce the minimum data rate.
+type dataRateListener struct {
+	net.Listener
+
+	rate  int64
+	grace time.Duration
+}
+
+// Accept accepts the next connection, wrapping it to enforce the minimum data rate.
+func (l *dataRateListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataRateConn{Conn: c, rate: l.rate, grace: l.grace}, nil
+}
+
+// dataMeter measures the bytes transferred in one direction and the time spent blocked transferring them.
+type dataMeter struct {
+	bytes    int64
+	elapsed  time.Duration
+	enforced time.Time
+	deadline time.Time
+}
+
+// budget returns how long the next transfer may block before the rate falls below the minimum.
+func (m *dataMeter) budget(rate int64, grace time.Duration) time.Duration {
+	allowed := time.Duration(float64(m.bytes) / float64(rate) * float64(time.Second))
+
+	return max(allowed, grace) - m.elapsed
+}
+
+// dataRateConn enforces the minimum data rate through deadlines set around the measured transfers,
+// capping the deadlines set by the server meanwhile.
+type dataRateConn struct {
+	net.Conn
+
+	rate  int64
+	grace time.Duration
+
+	mu      sync.Mutex
+	active  bool
+	reading bool
+	read    dataMeter
+	written dataMeter
+}
+
+// Read reads from the connection, measuring the transfer while a request body is being read.
+func (c *dataRateConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	measured := c.reading
+	c.mu.Unlock()
+
+	if !measured {
+		return c.Conn.Read(p)
+	}
+
+	return c.measure(&c.read, c.Conn.SetReadDeadline, c.Conn.Read, p)
+}
+
+// Write writes to the connection, measuring the transfer while a request is being served.
+func (c *dataRateConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	measured := c.active
+	c.mu.Unlock()
+
+	if !measured {
+		return c.Conn.Write(p)
+	}
+
+	return c.measure(&c.written, c.Conn.SetWriteDeadline, c.Conn.Write, p)
+}
+
+// measure performs a transfer within the budget of the meter, closing the connection if it is exhausted.
+func (c *dataRateConn) measure(m *dataMeter, setDeadline func(t time.Time) error,
+	transfer func(p []byte) (int, error), p []byte) (int, error) {
+	c.mu.Lock()
+	budget := m.budget(c.rate, c.grace)
+	if budget <= 0 {
+		c.mu.Unlock()
+		_ = c.Conn.Close()
+		return 0, ErrTransferTooSlow
+	}
+
+	start := time.Now()
+	m.enforced = start.Add(budget)
+	_ = setDeadline(earliest(m.deadline, m.enforced))
+	c.mu.Unlock()
+
+	n, err := transfer(p)
+
+	c.mu.Lock()
+	now := time.Now()
+	m.bytes += int64(n)
+	m.elapsed += now.Sub(start)
+	enforced := m.enforced
+	m.enforced = time.Time{}
+	_ = setDeadline(m.deadline)
+	expired := !now.Before(enforced) && (m.deadline.IsZero() || m.deadline.After(enforced))
+	c.mu.Unlock()
+
+	var netErr net.Error
+	if err != nil && errors.As(err, &netErr) && netErr.Timeout() && expired {
+		_ = c.Conn.Close()
+		return n, ErrTransferTooSlow
+	}
+
+	return n, err
+}
+
+// SetDeadline sets the read and write deadlines, capped by the deadlines of the measured transfers.
+func (c *dataRateConn) SetDeadline(t time.Time) error {
+	return errors.Join(c.SetReadDeadline(t), c.SetWriteDeadline(t))
+}
+
+// SetReadDeadline sets the read deadline, capped by the deadline of the measured read, if any.
+func (c *dataRateConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.read.deadline = t
+
+	return c.Conn.SetReadDeadline(earliest(t, c.read.enforced))
+}
+
+// SetWriteDeadline sets the write deadline, capped by the deadline of the measured write, if any.
+func (c *dataRateConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.written.deadline = t
+
+	return c.Conn.SetWriteDeadline(earliest(t, c.written.enforced))
+}
+
+// setActive starts or stops measuring the writes, resetting the meters when a request begins.
+func (c *dataRateConn) setActive(active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if active && !c.active {
+		c.read.bytes, c.read.elapsed = 0, 0
+		c.written.bytes, c.written.elapsed = 0, 0
+	}
+
+	c.active = active
+}
+
+// setReading starts or stops measuring the reads.
+func (c *dataRateConn) setReading(reading bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reading = reading
+}
+
+// dataRateBody measures the reads of the connection while the request body is being read.
+type dataRateBody struct {
+	io.ReadCloser
+
+	conn *dataRateConn
+}
+
+// Read reads from the request body.
+func (b *dataRateBody) Read(p []byte) (int, error) {
+	b.conn.setReading(true)
+	defer b.conn.setReading(false)
+
+	return b.ReadCloser.Read(p)
+}
+
+// dataRateHandler measures the reads of the request bodies of the HTTP/1.x requests, which
+// are read from the connection as the handler reads them, unlike the HTTP/2 ones.
+func (s *GracefulServer) dataRateHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := r.Context().Value(connContextKey{}).(net.Conn)
+		if !ok || r.ProtoMajor != 1 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if dc, ok := findConn[*dataRateConn](c); ok {
+			r.Body = &dataRateBody{ReadCloser: r.Body, conn: dc}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// earliest returns the earliest of two deadlines, where a zero time means no deadline.
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,125 @@
+package gracefulhttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMinDataRate(t *testing.T) {
+	t.Run("slow upload", func(t *testing.T) {
+		errs := make(chan error, 1)
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.ReadAll(r.Body)
+			errs <- err
+		}))
+		addr, stop := serveDataRate(t, s, 1024)
+		defer stop()
+
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 1000\r\n\r\n")
+		require.NoError(t, err)
+
+		go func() {
+			for range 20 {
+				if _, err := conn.Write([]byte("x")); err != nil {
+					return
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}()
+
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, ErrTransferTooSlow)
+		case <-time.After(5 * time.Second):
+			t.Fatal("slow upload not interrupted")
+		}
+	})
+
+	t.Run("slow download", func(t *testing.T) {
+		errs := make(chan error, 1)
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chunk := bytes.Repeat([]byte("x"), 1<<20)
+			for {
+				if _, err := w.Write(chunk); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}))
+		addr, stop := serveDataRate(t, s, 1<<30)
+		defer stop()
+
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		require.NoError(t, err)
+
+		select {
+		case err := <-errs:
+			assert.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("slow download not interrupted")
+		}
+	})
+
+	t.Run("fast transfers", func(t *testing.T) {
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(body)
+		}))
+		addr, stop := serveDataRate(t, s, 1024)
+		defer stop()
+
+		client := &http.Client{Transport: &http.Transport{}}
+		defer client.CloseIdleConnections()
+
+		for range 2 {
+			body := strings.Repeat("x", 1<<16)
+			resp, err := client.Post("http://"+addr, "text/plain", strings.NewReader(body))
+			require.NoError(t, err)
+			got, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			require.NoError(t, err)
+			assert.Equal(t, body, string(got))
+
+			// Idle keep-alive connections are not measured.
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		assert.Equal(t, int64(1), s.Stats().Accepted)
+	})
+}
+
+// serveDataRate serves s with the minimum data rate and a grace period of 100ms,
+// returning its address and a function stopping it.
+func serveDataRate(t *testing.T, s *GracefulServer, rate int64) (string, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithMinDataRate(rate, 100*time.Millisecond),
+			WithShutdownTimeout(100*time.Millisecond))
+	}()
+	<-s.Ready()
+
+	return s.ListenerAddr().String(), func() {
+		cancel()
+		<-done
+	}
+}
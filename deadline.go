@@ -0,0 +1,41 @@
+package gracefulhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// ExtendDeadlines serves the requests through h with read and write deadlines set from the start of each
+// request, through [http.ResponseController], in place of those of the server, so that the long-running routes,
+// such as large downloads or exports, are not cut by a WriteTimeout set for the others, e.g. by
+// [WithCloudflareTimeouts], without loosening it for the whole server:
+//
+//	mux.Handle("/exports/", gracefulhttp.ExtendDeadlines(0, 10*time.Minute, exports))
+//
+// A zero duration leaves the corresponding deadline of the server unchanged, while a negative one removes it.
+// The deadlines are ignored by the response writers not supporting them.
+func ExtendDeadlines(read, write time.Duration, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		now := time.Now()
+
+		if read != 0 {
+			_ = rc.SetReadDeadline(deadlineAfter(now, read))
+		}
+
+		if write != 0 {
+			_ = rc.SetWriteDeadline(deadlineAfter(now, write))
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// deadlineAfter returns the deadline d after now, or no deadline if d is negative.
+func deadlineAfter(now time.Time, d time.Duration) time.Time {
+	if d < 0 {
+		return time.Time{}
+	}
+
+	return now.Add(d)
+}
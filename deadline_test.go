@@ -0,0 +1,56 @@
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendDeadlines(t *testing.T) {
+	slow := &delayedHandler{delay: 300 * time.Millisecond}
+
+	mux := http.NewServeMux()
+	mux.Handle("/short", slow)
+	mux.Handle("/export", ExtendDeadlines(0, 5*time.Second, slow))
+	mux.Handle("/unbounded", ExtendDeadlines(-1, -1, slow))
+
+	s := Bind("localhost:0", mux)
+	s.WriteTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx)
+	}()
+	<-s.Ready()
+
+	get := func(path string) (string, error) {
+		r, err := http.Get("http://" + s.ListenerAddr().String() + path)
+		if err != nil {
+			return "", err
+		}
+		defer r.Body.Close()
+
+		body, err := io.ReadAll(r.Body)
+		return string(body), err
+	}
+
+	_, err := get("/short")
+	assert.Error(t, err)
+
+	for _, path := range []string{"/export", "/unbounded"} {
+		body, err := get(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, "{}", body, path)
+	}
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,59 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WithDeregistration runs fn at the very start of the shutdown, before the shutdown delay, if any, e.g. to
+// deregister the target from an AWS load balancer or to remove a Consul tag, then waits for the settle time,
+// serving as usual, so that the traffic stops being routed to the server before the drain begins.
+// The context of fn expires after the graceful timeout. A failure of fn does not stop the shutdown:
+// it is reported by the serving method, joined with the error of the shutdown, if any.
+// The deregistration is skipped when the server is stopped without draining.
+func WithDeregistration(fn func(ctx context.Context) error, wait time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if fn == nil {
+			s.invalidOption("WithDeregistration", "nil deregistration function")
+		}
+
+		if wait < 0 {
+			s.invalidOption("WithDeregistration", "negative settle time %v", wait)
+		}
+
+		s.deregister = fn
+		s.deregisterWait = max(wait, 0)
+	}
+}
+
+// deregisterServer runs the deregistration function and deregisters the server through the registrars, if any,
+// then waits for the longest settle time.
+func (s *GracefulServer) deregisterServer() error {
+	if s.deregister == nil && len(s.registrars) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	gracefulTimeout := s.gracefulTimeout
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
+	var errs []error
+	if s.deregister != nil {
+		if err := s.deregister(ctx); err != nil {
+			err = fmt.Errorf("gracefulhttp: deregistration: %w", err)
+			s.reportError(PhaseDrain, err)
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, s.deregisterRegistrars(ctx)...)
+	cancel()
+
+	if wait := max(s.deregisterWait, s.registrarSettle); wait > 0 {
+		<-s.clock.After(wait)
+	}
+
+	return phaseError(PhaseDrain, errors.Join(errs...))
+}
@@ -0,0 +1,51 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeregistration(t *testing.T) {
+	deregisterErr := errors.New("target not found")
+	deregistered := make(chan struct{})
+
+	clock := newManualClock()
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithDeregistration(func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			close(deregistered)
+			return deregisterErr
+		}, 10*time.Second))
+	}()
+	<-s.Ready()
+
+	cancel()
+	<-deregistered
+
+	// The server keeps serving during the settle time.
+	assert.False(t, s.isDraining())
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	clock.fire()
+
+	err = <-done
+	assert.ErrorIs(t, err, deregisterErr)
+	assert.True(t, s.isDraining())
+
+	assert.ErrorIs(t, ValidateOptions(WithDeregistration(nil, 0)), ErrInvalidOption)
+	assert.ErrorIs(t, ValidateOptions(WithDeregistration(func(context.Context) error { return nil }, -time.Second)), ErrInvalidOption)
+}
@@ -0,0 +1,91 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// countDrainInterval is the interval at which the connections are counted by [WithConnectionCountDrain].
+const countDrainInterval = 10 * time.Millisecond
+
+// A DrainStrategy drains the connections of the server when the shutdown begins,
+// e.g. waiting for a job queue to flush or coordinating with a sidecar before shutting the server down.
+// The context expires at the end of the graceful timeout, and the strategy must return once it does,
+// as the active connections are forcibly closed only after it returned. Once the strategy returns,
+// [http.Server.Shutdown] is invoked to make sure the server stops, even if the strategy failed,
+// so a strategy may limit itself to waiting.
+type DrainStrategy interface {
+	Drain(ctx context.Context, srv *http.Server) error
+}
+
+// DrainStrategyFunc is an adapter allowing the use of ordinary functions as a [DrainStrategy].
+type DrainStrategyFunc func(ctx context.Context, srv *http.Server) error
+
+// Drain calls f(ctx, srv).
+func (f DrainStrategyFunc) Drain(ctx context.Context, srv *http.Server) error {
+	return f(ctx, srv)
+}
+
+// DefaultDrainStrategy stops accepting connections and waits for the active ones
+// to become idle through [http.Server.Shutdown].
+var DefaultDrainStrategy DrainStrategy = DrainStrategyFunc(func(ctx context.Context, srv *http.Server) error {
+	return srv.Shutdown(ctx)
+})
+
+// runDrainStrategy runs the drain strategy, if any, then shuts the server down,
+// even if the strategy failed.
+func (s *GracefulServer) runDrainStrategy(ctx context.Context) error {
+	s.mu.Lock()
+	strategy := s.drainStrategy
+	s.mu.Unlock()
+
+	if strategy == nil {
+		return s.shutdownServer(ctx)
+	}
+
+	err := strategy.Drain(ctx, &s.Server)
+
+	return errors.Join(err, s.shutdownServer(ctx))
+}
+
+// WithConnectionCountDrain completes the drain as soon as the connections are no longer active, closing
+// the idle ones right away, rather than when [http.Server.Shutdown] notices it, which polls the connections
+// at intervals growing up to 500ms. The connections opened and not serving a request yet, and the hijacked
+// ones, count as active. The graceful timeout remains the ceiling of the drain.
+func WithConnectionCountDrain() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.countDrain = true
+	}
+}
+
+// shutdownServer shuts the server down through [http.Server.Shutdown], completing as soon as
+// the connections are no longer active if [WithConnectionCountDrain] is set.
+func (s *GracefulServer) shutdownServer(ctx context.Context) error {
+	if !s.countDrain {
+		return s.Shutdown(ctx)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Shutdown(ctx)
+	}()
+
+	ticker := time.NewTicker(countDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case <-ticker.C:
+			stats := s.ConnStats()
+			if stats.New+stats.Active+len(s.trackedHijacked()) == 0 {
+				// The listeners are closed already: closing the server only closes the idle connections.
+				_ = s.Close()
+				return nil
+			}
+		}
+	}
+}
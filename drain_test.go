@@ -0,0 +1,158 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDrainStrategy(t *testing.T) {
+	t.Run("wait for the strategy before shutting down", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{})
+
+		called := make(chan *http.Server, 1)
+		flushed := make(chan struct{})
+		strategy := DrainStrategyFunc(func(ctx context.Context, srv *http.Server) error {
+			called <- srv
+			<-flushed
+
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithDrainStrategy(strategy))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		assert.Same(t, &s.Server, <-called)
+
+		// The server keeps serving until the strategy returns.
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_ = r.Body.Close()
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+
+		close(flushed)
+
+		require.NoError(t, <-done)
+	})
+
+	t.Run("return the strategy error", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{})
+
+		errDrain := errors.New("drain failed")
+		strategy := DrainStrategyFunc(func(ctx context.Context, srv *http.Server) error {
+			return errDrain
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithDrainStrategy(strategy))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		require.ErrorIs(t, <-done, errDrain)
+	})
+
+	t.Run("default strategy", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithDrainStrategy(DefaultDrainStrategy))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		require.NoError(t, <-done)
+	})
+}
+
+func TestWithCloseIdleOnDrain(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	flushed := make(chan struct{})
+	strategy := DrainStrategyFunc(func(ctx context.Context, srv *http.Server) error {
+		<-flushed
+
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithDrainStrategy(strategy), WithCloseIdleOnDrain())
+	}()
+
+	<-s.Ready()
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, r.Body)
+	_ = r.Body.Close()
+
+	for s.ConnStats().Idle == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	// The idle connection is closed while the drain strategy is still running.
+	for s.ConnStats().Idle != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(flushed)
+
+	require.NoError(t, <-done)
+}
+
+func TestWithConnectionCountDrain(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{delay: 600 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithConnectionCountDrain(), WithShutdownTimeout(5*time.Second))
+	}()
+	<-s.Ready()
+
+	go func() {
+		if r, err := http.Get("http://" + s.ListenerAddr().String()); err == nil {
+			_ = r.Body.Close()
+		}
+	}()
+	for s.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Shutdown would only notice the end of the request at its next poll, about 1s after it began.
+	start := time.Now()
+	cancel()
+	require.NoError(t, <-done)
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+
+	report := s.status().LastShutdown
+	require.NotNil(t, report)
+	assert.Equal(t, "connections", report.DrainedBy)
+	assert.Zero(t, s.ConnStats().Idle)
+}
@@ -0,0 +1,92 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FromEnv returns the options configuring the server from the environment variables set
+// among the following ones, named after the prefix (e.g. GRACEFUL_READ_TIMEOUT for the "GRACEFUL" prefix):
+//
+//   - ADDR: the address to listen on
+//   - READ_TIMEOUT, READ_HEADER_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT: the [http.Server] timeouts
+//   - SHUTDOWN_TIMEOUT: the timeout of the graceful shutdown
+//   - TLS_CERT_FILE, TLS_KEY_FILE: the certificate and key files, served as with [WithKeyPairPEM]
+//
+// The durations are parsed by [time.ParseDuration]. Invalid values are reported when serving.
+func FromEnv(prefix string) []GracefulServerOption {
+	name := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + "_" + key
+	}
+
+	var opts []GracefulServerOption
+
+	if addr, ok := os.LookupEnv(name("ADDR")); ok {
+		opts = append(opts, func(s *GracefulServer) {
+			s.Addr = addr
+			s.addrs = nil
+		})
+	}
+
+	durations := []struct {
+		key     string
+		setting string
+		set     func(s *GracefulServer, d time.Duration)
+	}{
+		{"READ_TIMEOUT", "timeouts.read", func(s *GracefulServer, d time.Duration) { s.ReadTimeout = d }},
+		{"READ_HEADER_TIMEOUT", "timeouts.read_header", func(s *GracefulServer, d time.Duration) { s.ReadHeaderTimeout = d }},
+		{"WRITE_TIMEOUT", "timeouts.write", func(s *GracefulServer, d time.Duration) { s.WriteTimeout = d }},
+		{"IDLE_TIMEOUT", "timeouts.idle", func(s *GracefulServer, d time.Duration) { s.IdleTimeout = d }},
+		{"SHUTDOWN_TIMEOUT", "", func(s *GracefulServer, d time.Duration) { WithShutdownTimeout(d)(s) }},
+	}
+
+	for _, duration := range durations {
+		value, ok := os.LookupEnv(name(duration.key))
+		if !ok {
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			opts = append(opts, withOptionError(fmt.Errorf("gracefulhttp: %s: %w", name(duration.key), err)))
+			continue
+		}
+
+		opts = append(opts, func(s *GracefulServer) {
+			if duration.setting != "" {
+				s.claimSetting(duration.setting, "FromEnv")
+			}
+
+			duration.set(s, d)
+		})
+	}
+
+	certFile, hasCert := os.LookupEnv(name("TLS_CERT_FILE"))
+	keyFile, hasKey := os.LookupEnv(name("TLS_KEY_FILE"))
+	switch {
+	case hasCert && hasKey:
+		opts = append(opts, func(s *GracefulServer) {
+			s.certLoaders = append(s.certLoaders, func() (tls.Certificate, error) {
+				return tls.LoadX509KeyPair(certFile, keyFile)
+			})
+		})
+	case hasCert || hasKey:
+		opts = append(opts, withOptionError(fmt.Errorf("gracefulhttp: %s and %s must be set together",
+			name("TLS_CERT_FILE"), name("TLS_KEY_FILE"))))
+	}
+
+	return opts
+}
+
+// withOptionError reports an invalid option when the server is initialized.
+func withOptionError(err error) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.optionErrs = append(s.optionErrs, err)
+	}
+}
@@ -0,0 +1,61 @@
+package gracefulhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Run("apply the variables set", func(t *testing.T) {
+		t.Setenv("APP_ADDR", "localhost:0")
+		t.Setenv("APP_READ_TIMEOUT", "3s")
+		t.Setenv("APP_READ_HEADER_TIMEOUT", "1s")
+		t.Setenv("APP_WRITE_TIMEOUT", "4s")
+		t.Setenv("APP_IDLE_TIMEOUT", "1m")
+		t.Setenv("APP_SHUTDOWN_TIMEOUT", "30s")
+		certFile, keyFile := testKeyPairFiles(t)
+		t.Setenv("APP_TLS_CERT_FILE", certFile)
+		t.Setenv("APP_TLS_KEY_FILE", keyFile)
+
+		s := BindMulti([]string{"localhost:8080", "localhost:8081"}, nil)
+		require.NoError(t, s.initialize(FromEnv("APP")))
+
+		assert.Equal(t, "localhost:0", s.Addr)
+		assert.Empty(t, s.addrs)
+		assert.Equal(t, 3*time.Second, s.ReadTimeout)
+		assert.Equal(t, time.Second, s.ReadHeaderTimeout)
+		assert.Equal(t, 4*time.Second, s.WriteTimeout)
+		assert.Equal(t, time.Minute, s.IdleTimeout)
+		assert.Equal(t, 30*time.Second, s.gracefulTimeout)
+		assert.Len(t, s.certLoaders, 1)
+	})
+
+	t.Run("keep the defaults without variables", func(t *testing.T) {
+		s := Bind("localhost:8080", nil)
+		require.NoError(t, s.initialize(FromEnv("UNSET_PREFIX")))
+
+		assert.Equal(t, "localhost:8080", s.Addr)
+		assert.Equal(t, defaultGracefulTimeout, s.gracefulTimeout)
+		assert.Empty(t, s.certLoaders)
+	})
+
+	t.Run("report an invalid duration", func(t *testing.T) {
+		t.Setenv("APP_WRITE_TIMEOUT", "soon")
+
+		s := Bind("localhost:0", nil)
+		err := s.ListenAndServeWithShutdown(context.Background(), FromEnv("APP")...)
+		require.ErrorContains(t, err, "APP_WRITE_TIMEOUT")
+	})
+
+	t.Run("report a certificate without key", func(t *testing.T) {
+		certFile, _ := testKeyPairFiles(t)
+		t.Setenv("TLS_CERT_FILE", certFile)
+
+		s := Bind("localhost:0", nil)
+		require.Error(t, s.initialize(FromEnv("")))
+	})
+}
@@ -0,0 +1,68 @@
+package gracefulhttp
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// NewErrorLog returns a [log.Logger], meant to be set as the ErrorLog of an [http.Server],
+// forwarding its messages to the logger, e.g. a [*slog.Logger]. The known messages are given structured
+// fields, such as the remote address of the TLS handshake errors or the stack of the panics.
+// It is set on the server by [WithLogger], unless an ErrorLog is set already.
+func NewErrorLog(logger Logger) *log.Logger {
+	return log.New(errorLogWriter{logger: logger}, "", 0)
+}
+
+// errorLogWriter parses the messages logged by [http.Server] into structured records.
+type errorLogWriter struct {
+	logger Logger
+}
+
+// Write logs a message of [http.Server].
+func (w errorLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level, msg, attrs := parseErrorLog(msg)
+	if l, ok := w.logger.(*slog.Logger); ok {
+		l.LogAttrs(context.Background(), level, msg, attrs...)
+		return len(p), nil
+	}
+
+	args := make([]any, 0, 2*len(attrs))
+	for _, attr := range attrs {
+		args = append(args, attr.Key, attr.Value.Any())
+	}
+
+	if level == slog.LevelWarn {
+		w.logger.Warn(msg, args...)
+	} else {
+		w.logger.Error(msg, args...)
+	}
+
+	return len(p), nil
+}
+
+// parseErrorLog returns the level, message and fields of a message logged by [http.Server].
+func parseErrorLog(msg string) (slog.Level, string, []slog.Attr) {
+	if rest, ok := strings.CutPrefix(msg, "http: TLS handshake error from "); ok {
+		addr, err, _ := strings.Cut(rest, ": ")
+		return slog.LevelWarn, "gracefulhttp: TLS handshake error",
+			[]slog.Attr{slog.String("remote_addr", addr), slog.String("error", err)}
+	}
+
+	if rest, ok := strings.CutPrefix(msg, "http: panic serving "); ok {
+		addr, rest, _ := strings.Cut(rest, ": ")
+		panicValue, stack, _ := strings.Cut(rest, "\n")
+		return slog.LevelError, "gracefulhttp: panic serving request",
+			[]slog.Attr{slog.String("remote_addr", addr), slog.String("panic", panicValue), slog.String("stack", stack)}
+	}
+
+	if rest, ok := strings.CutPrefix(msg, "http: Accept error: "); ok {
+		err, retry, _ := strings.Cut(rest, "; retrying in ")
+		return slog.LevelError, "gracefulhttp: accept error",
+			[]slog.Attr{slog.String("error", err), slog.String("retry_in", retry)}
+	}
+
+	return slog.LevelError, "gracefulhttp: " + strings.TrimPrefix(msg, "http: "), nil
+}
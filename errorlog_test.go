@@ -0,0 +1,132 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseErrorLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       string
+		wantLevel slog.Level
+		wantMsg   string
+		wantAttrs []slog.Attr
+	}{
+		{
+			name:      "tls handshake error",
+			msg:       "http: TLS handshake error from 192.0.2.1:4321: EOF",
+			wantLevel: slog.LevelWarn,
+			wantMsg:   "gracefulhttp: TLS handshake error",
+			wantAttrs: []slog.Attr{slog.String("remote_addr", "192.0.2.1:4321"), slog.String("error", "EOF")},
+		},
+		{
+			name:      "panic",
+			msg:       "http: panic serving 192.0.2.1:4321: boom\ngoroutine 1 [running]:",
+			wantLevel: slog.LevelError,
+			wantMsg:   "gracefulhttp: panic serving request",
+			wantAttrs: []slog.Attr{
+				slog.String("remote_addr", "192.0.2.1:4321"),
+				slog.String("panic", "boom"),
+				slog.String("stack", "goroutine 1 [running]:"),
+			},
+		},
+		{
+			name:      "accept error",
+			msg:       "http: Accept error: too many open files; retrying in 5ms",
+			wantLevel: slog.LevelError,
+			wantMsg:   "gracefulhttp: accept error",
+			wantAttrs: []slog.Attr{slog.String("error", "too many open files"), slog.String("retry_in", "5ms")},
+		},
+		{
+			name:      "other message",
+			msg:       "http: superfluous response.WriteHeader call",
+			wantLevel: slog.LevelError,
+			wantMsg:   "gracefulhttp: superfluous response.WriteHeader call",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, msg, attrs := parseErrorLog(tt.msg)
+
+			assert.Equal(t, tt.wantLevel, level)
+			assert.Equal(t, tt.wantMsg, msg)
+			assert.Equal(t, tt.wantAttrs, attrs)
+		})
+	}
+}
+
+func TestWithLogger_ErrorLog(t *testing.T) {
+	var logs lockedBuffer
+	certFile, keyFile := testKeyPairFiles(t)
+	s := Bind("localhost:0", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeTLSWithShutdown(ctx, certFile, keyFile,
+			WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	}()
+	<-s.Ready()
+
+	conn, err := net.Dial("tcp", s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = tls.Client(conn, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS10}).Handshake()
+	_ = conn.Close()
+
+	cancel()
+	require.NoError(t, <-errCh)
+
+	assert.Contains(t, logs.String(), `msg="gracefulhttp: TLS handshake error"`)
+}
+
+// recordingLogger records the messages logged through the [Logger] interface.
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordingLogger) Info(msg string, args ...any)  { l.record("INFO", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.record("WARN", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.record("ERROR", msg, args) }
+
+// record records the message with its level and arguments.
+func (l *recordingLogger) record(level, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.logs = append(l.logs, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func TestNewErrorLog_Logger(t *testing.T) {
+	var logger recordingLogger
+
+	errorLog := NewErrorLog(&logger)
+	errorLog.Print("http: TLS handshake error from 192.0.2.1:4321: EOF")
+	errorLog.Print("http: superfluous response.WriteHeader call")
+
+	assert.Equal(t, []string{
+		"WARN gracefulhttp: TLS handshake error [remote_addr 192.0.2.1:4321 error EOF]",
+		"ERROR gracefulhttp: superfluous response.WriteHeader call []",
+	}, logger.logs)
+}
+
+func TestWithLogger(t *testing.T) {
+	var logger recordingLogger
+
+	s := GracefulServer{}
+	WithLogger(&logger)(&s)
+	assert.Same(t, &logger, s.logger)
+
+	var nilLogger *slog.Logger
+	WithLogger(nilLogger)(&s)
+	assert.Nil(t, s.logger)
+}
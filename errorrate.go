@@ -0,0 +1,106 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultErrorRateWindow is the default window over which the error rate is measured by [WithErrorRateBreaker].
+const defaultErrorRateWindow = time.Minute
+
+// ErrErrorRateShutdown is the cause of the shutdown started by [WithErrorRateBreaker], returned by the serving
+// methods when [WithReturnCause] is set, and wrapped by the error of the [EventErrorRateExceeded] events.
+var ErrErrorRateShutdown = errors.New("gracefulhttp: error rate exceeded")
+
+// WithErrorRateBreaker measures the fraction of the requests failing, answered with a 5xx status or whose
+// handler panicked, over consecutive windows of the given duration, one minute by default. Once the fraction
+// exceeds the threshold, between 0 and 1, over a window with at least minRequests requests, an
+// [EventErrorRateExceeded] event is emitted and, if shutdown is set, the graceful shutdown is started, so that
+// the orchestrator replaces an instance whose state got corrupted. The requests rejected by the server itself,
+// while draining, shedding or beyond the in-flight limit, are not measured.
+func WithErrorRateBreaker(threshold float64, window time.Duration, minRequests int, shutdown bool) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if threshold <= 0 || threshold > 1 {
+			s.invalidOption("WithErrorRateBreaker", "threshold %v not in (0, 1]", threshold)
+		}
+
+		if window < 0 {
+			s.invalidOption("WithErrorRateBreaker", "negative window %v", window)
+		}
+
+		if minRequests < 0 {
+			s.invalidOption("WithErrorRateBreaker", "negative minimum requests %d", minRequests)
+		}
+
+		s.errorRateThreshold = min(max(threshold, 0), 1)
+		s.errorRateWindow = max(window, 0)
+		s.errorRateMinRequests = max(minRequests, 1)
+		s.errorRateShutdown = shutdown
+	}
+}
+
+// initializeErrorRateBreaker measures the error rate in the background, if enabled.
+func (s *GracefulServer) initializeErrorRateBreaker() {
+	if s.errorRateThreshold == 0 {
+		return
+	}
+
+	s.errorRateRequests.Store(0)
+	s.errorRateErrors.Store(0)
+	s.background = append(s.background, s.watchErrorRate)
+}
+
+// errorRateHandler counts the requests and the failed ones measured by [WithErrorRateBreaker].
+func (s *GracefulServer) errorRateHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		completed := false
+		defer func() {
+			s.errorRateRequests.Add(1)
+			if !completed || rw.status >= http.StatusInternalServerError {
+				s.errorRateErrors.Add(1)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+		completed = true
+	})
+}
+
+// watchErrorRate checks the error rate at the end of every window, emitting an event and, if enabled,
+// starting the shutdown once it exceeds the threshold, until the context is done.
+func (s *GracefulServer) watchErrorRate(ctx context.Context) {
+	window := cmp.Or(s.errorRateWindow, defaultErrorRateWindow)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(window):
+		}
+
+		requests, failed := s.errorRateRequests.Swap(0), s.errorRateErrors.Swap(0)
+		if requests < int64(s.errorRateMinRequests) || float64(failed) <= s.errorRateThreshold*float64(requests) {
+			continue
+		}
+
+		if s.logger != nil {
+			s.logger.Warn("gracefulhttp: error rate exceeded",
+				"failed", failed, "requests", requests, "threshold", s.errorRateThreshold)
+		}
+
+		s.emit(Event{
+			Type: EventErrorRateExceeded,
+			Err:  fmt.Errorf("%w: %d of %d requests failed", ErrErrorRateShutdown, failed, requests),
+		})
+
+		if s.errorRateShutdown {
+			s.cancelServing(ErrErrorRateShutdown)
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithErrorRateBreaker(t *testing.T) {
+	clock := newManualClock()
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(context.Background(), WithClock(clock), WithReturnCause(),
+			WithErrorRateBreaker(0.5, time.Second, 2, true))
+	}()
+	<-s.Ready()
+
+	url := "http://" + s.ListenerAddr().String()
+	get := func(path string) {
+		r, err := http.Get(url + path)
+		require.NoError(t, err)
+		_ = r.Body.Close()
+	}
+
+	// Below the minimum number of requests.
+	window := <-clock.timers
+	get("/fail")
+	window <- time.Now()
+
+	// Not above the threshold.
+	window = <-clock.timers
+	get("/")
+	get("/fail")
+	window <- time.Now()
+
+	window = <-clock.timers
+	assert.False(t, s.isDraining())
+	get("/fail")
+	get("/fail")
+	window <- time.Now()
+
+	assert.ErrorIs(t, <-done, ErrErrorRateShutdown)
+
+	events := bufferedEvents(s)
+	assert.Contains(t, eventTypes(events), EventErrorRateExceeded)
+	for _, e := range events {
+		if e.Type == EventErrorRateExceeded {
+			assert.ErrorIs(t, e.Err, ErrErrorRateShutdown)
+		}
+	}
+
+	assert.ErrorIs(t, ValidateOptions(WithErrorRateBreaker(1.5, time.Second, 0, false)), ErrInvalidOption)
+	assert.ErrorIs(t, ValidateOptions(WithErrorRateBreaker(0.5, -time.Second, 0, false)), ErrInvalidOption)
+}
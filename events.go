@@ -0,0 +1,100 @@
+package gracefulhttp
+
+import (
+	"net"
+	"time"
+)
+
+// eventBufferSize is the number of events buffered by the channel returned by [GracefulServer.Events].
+const eventBufferSize = 64
+
+// EventType is the type of a lifecycle [Event].
+type EventType int
+
+const (
+	// EventListenerBound is emitted once a listener is bound, with its address.
+	EventListenerBound EventType = iota
+	// EventReady is emitted once all the listeners are bound and the server is about to accept connections.
+	EventReady
+	// EventDrainStarted is emitted once the shutdown sequence has begun.
+	EventDrainStarted
+	// EventDrainCompleted is emitted once the shutdown completes without forcibly closing any connection.
+	EventDrainCompleted
+	// EventForcedClose is emitted when the active connections are forcibly closed.
+	EventForcedClose
+	// EventStopped is emitted once the server stops serving, with the error returned by the serving method, if any.
+	EventStopped
+	// EventError is emitted when an error occurs, with the phase of the lifecycle in which it occurred,
+	// as reported to the callback set with [WithOnError].
+	EventError
+	// EventErrorRateExceeded is emitted when the error rate measured by [WithErrorRateBreaker] exceeds
+	// the threshold, with an error wrapping [ErrErrorRateShutdown].
+	EventErrorRateExceeded
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventListenerBound:
+		return "listener bound"
+	case EventReady:
+		return "ready"
+	case EventDrainStarted:
+		return "drain started"
+	case EventDrainCompleted:
+		return "drain completed"
+	case EventForcedClose:
+		return "forced close"
+	case EventStopped:
+		return "stopped"
+	case EventError:
+		return "error"
+	case EventErrorRateExceeded:
+		return "error rate exceeded"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a transition of the lifecycle of a [GracefulServer].
+type Event struct {
+	// Type is the type of the event.
+	Type EventType
+	// Time is the time at which the event occurred, as reported by the clock of the server.
+	Time time.Time
+	// Addr is the address of the bound listener, for EventListenerBound.
+	Addr net.Addr
+	// Phase is the phase in which the error occurred, for EventError.
+	Phase Phase
+	// Err is the error, for EventError and EventErrorRateExceeded, or the error returned by the serving method,
+	// if any, for EventStopped.
+	Err error
+}
+
+// Events returns a channel receiving the lifecycle events of the server, so that supervisors and
+// user interfaces can react to the transitions without polling or setting every callback.
+// The channel is buffered and shared by all the callers, and it is never closed, as the server may
+// serve again: the events are dropped while the buffer is full, so that a slow receiver never blocks
+// the server.
+func (s *GracefulServer) Events() <-chan Event {
+	return s.eventChan()
+}
+
+// eventChan lazily creates the channel of the lifecycle events.
+func (s *GracefulServer) eventChan() chan Event {
+	s.eventsOnce.Do(func() {
+		s.events = make(chan Event, eventBufferSize)
+	})
+
+	return s.events
+}
+
+// emit sends the event, dropping it if the buffer is full.
+func (s *GracefulServer) emit(e Event) {
+	e.Time = s.clock.Now()
+
+	select {
+	case s.eventChan() <- e:
+	default:
+	}
+}
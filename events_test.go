@@ -0,0 +1,103 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufferedEvents returns the events buffered by the server.
+func bufferedEvents(s *GracefulServer) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-s.Events():
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+// eventTypes returns the types of the events.
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+
+	return types
+}
+
+func TestGracefulServer_Events(t *testing.T) {
+	t.Run("graceful shutdown", func(t *testing.T) {
+		s := Bind("localhost:0", nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx)
+		}()
+
+		e := <-s.Events()
+		assert.Equal(t, EventListenerBound, e.Type)
+		assert.False(t, e.Time.IsZero())
+		<-s.Ready()
+		assert.Equal(t, s.ListenerAddr(), e.Addr)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []EventType{EventReady, EventDrainStarted, EventDrainCompleted, EventStopped}, eventTypes(bufferedEvents(s)))
+	})
+
+	t.Run("forced close", func(t *testing.T) {
+		started := make(chan struct{})
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(50*time.Millisecond))
+		}()
+		<-s.Ready()
+
+		go func() {
+			if r, err := http.Get("http://" + s.ListenerAddr().String()); err == nil {
+				_ = r.Body.Close()
+			}
+		}()
+		<-started
+
+		cancel()
+		err := <-done
+
+		events := bufferedEvents(s)
+		types := eventTypes(events)
+		assert.Contains(t, types, EventForcedClose)
+		assert.NotContains(t, types, EventDrainCompleted)
+
+		last := events[len(events)-1]
+		assert.Equal(t, EventStopped, last.Type)
+		assert.Equal(t, err, last.Err)
+
+		for _, e := range events {
+			if e.Type == EventError {
+				assert.Equal(t, PhaseForceClose, e.Phase)
+				assert.ErrorIs(t, e.Err, ErrDrainTimeout)
+			}
+		}
+	})
+}
+
+func TestEventType_String(t *testing.T) {
+	assert.Equal(t, "drain started", EventDrainStarted.String())
+	assert.Equal(t, "unknown", EventType(-1).String())
+}
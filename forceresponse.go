@@ -0,0 +1,109 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultForceCloseResponseBudget is the default time given to write the responses of [WithForceCloseResponse].
+const defaultForceCloseResponseBudget = 100 * time.Millisecond
+
+// WithForceCloseResponse answers with 503 Service Unavailable and Connection: close the requests whose handler
+// has not started writing the response when the connections are forcibly closed, instead of resetting them,
+// so that the clients can tell a server restarting from a network failure, and retry. The responses are
+// written within the budget, 100 milliseconds by default, with the delay set by [WithDrainRejection] in the
+// Retry-After header. It is best effort: only the HTTP/1 connections are answered, as the HTTP/2 streams
+// are reset by the GOAWAY frame already.
+func WithForceCloseResponse(budget time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if budget < 0 {
+			s.invalidOption("WithForceCloseResponse", "negative budget %v", budget)
+		}
+
+		s.forceCloseBudget = cmp.Or(max(budget, 0), defaultForceCloseResponseBudget)
+	}
+}
+
+// forceCloseResponseHandler records the HTTP/1 connections serving a request whose response has not started yet.
+func (s *GracefulServer) forceCloseResponseHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := r.Context().Value(connContextKey{}).(net.Conn)
+		if !ok || r.ProtoMajor != 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s.conns.setPending(c, true)
+		defer s.conns.setPending(c, false)
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			beforeWriteHeader: func(http.Header) {
+				s.conns.setPending(c, false)
+			},
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// answerPending writes a 503 Service Unavailable to the connections whose response has not started,
+// then closes them, within the budget.
+func (s *GracefulServer) answerPending() {
+	conns := s.conns.takePending()
+	if len(conns) == 0 {
+		return
+	}
+
+	seconds := int(math.Ceil(cmp.Or(s.retryAfter, defaultRetryAfter).Seconds()))
+	body := http.StatusText(http.StatusServiceUnavailable) + "\n"
+	response := fmt.Sprintf("HTTP/1.1 503 %s\r\nConnection: close\r\nContent-Type: text/plain; charset=utf-8\r\n"+
+		"Content-Length: %d\r\nRetry-After: %d\r\n\r\n%s", http.StatusText(http.StatusServiceUnavailable), len(body), seconds, body)
+	deadline := time.Now().Add(s.forceCloseBudget)
+
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_ = c.SetWriteDeadline(deadline)
+			_, _ = c.Write([]byte(response))
+			_ = c.Close()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// setPending records whether a tracked connection serves a request whose response has not started.
+func (t *connTracker) setPending(c net.Conn, pending bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tc, ok := t.conns[c]; ok {
+		tc.pending = pending
+	}
+}
+
+// takePending returns the tracked connections serving a request whose response has not started,
+// which are no longer recorded as such.
+func (t *connTracker) takePending() []net.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var conns []net.Conn
+	for c, tc := range t.conns {
+		if tc.pending {
+			tc.pending = false
+			conns = append(conns, c)
+		}
+	}
+
+	return conns
+}
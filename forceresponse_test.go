@@ -0,0 +1,72 @@
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithForceCloseResponse(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/started" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, "partial")
+			w.(http.Flusher).Flush()
+		}
+
+		<-release
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(100*time.Millisecond),
+			WithForceCloseResponse(0), WithDrainRejection(3*time.Second))
+	}()
+	<-s.Ready()
+	addr := "http://" + s.ListenerAddr().String()
+
+	type result struct {
+		r   *http.Response
+		err error
+	}
+	pending, started := make(chan result, 1), make(chan result, 1)
+	go func() {
+		r, err := http.Get(addr + "/pending")
+		pending <- result{r, err}
+	}()
+	go func() {
+		r, err := http.Get(addr + "/started")
+		started <- result{r, err}
+	}()
+
+	// The response of the request started is already being written.
+	res := <-started
+	require.NoError(t, res.err)
+	defer res.r.Body.Close()
+
+	require.Eventually(t, func() bool { return s.ConnStats().Active == 2 }, time.Second, 10*time.Millisecond)
+	cancel()
+
+	// The request not answered yet is given a 503, instead of a reset.
+	res = <-pending
+	require.NoError(t, res.err)
+	defer res.r.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.r.StatusCode)
+	assert.Equal(t, "3", res.r.Header.Get("Retry-After"))
+	assert.True(t, res.r.Close)
+
+	_, err := io.ReadAll(res.r.Body)
+	assert.NoError(t, err)
+
+	<-done
+	assert.ErrorIs(t, ValidateOptions(WithForceCloseResponse(-time.Second)), ErrInvalidOption)
+}
@@ -0,0 +1,241 @@
+// Package gracefulconsul registers a [gracefulhttp.GracefulServer] as a service of the local Consul agent
+// once it is listening, with its actual address, and deregisters it as soon as the shutdown begins, so that
+// the traffic stops being routed to it before the drain. It talks to the HTTP API of the agent directly,
+// sparing the dependency on the Consul client to the other users of gracefulhttp.
+package gracefulconsul
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+)
+
+const (
+	// defaultAgentAddr is the address of the local Consul agent used when none is set.
+	defaultAgentAddr = "http://127.0.0.1:8500"
+	// defaultCheckInterval is the default interval at which the agent checks the health of the service.
+	defaultCheckInterval = 10 * time.Second
+)
+
+// Agent is the local Consul agent with which the server is registered.
+// Its zero value talks to the agent set by the CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN environment
+// variables, as the Consul CLI does, or to the agent listening on 127.0.0.1:8500.
+type Agent struct {
+	// Addr is the address of the HTTP API of the agent, e.g. "http://127.0.0.1:8500".
+	Addr string
+	// Token is the ACL token sent to the agent, if any.
+	Token string
+	// Client is the client sending the requests to the agent, [http.DefaultClient] if nil.
+	Client *http.Client
+}
+
+// Service describes the registration of the server.
+type Service struct {
+	// Name is the name of the service.
+	Name string
+	// ID is the ID of the instance, unique on the agent, "<name>-<host>-<port>" if empty.
+	ID string
+	// Tags are the tags of the service, if any.
+	Tags []string
+	// Meta is the metadata of the service, if any.
+	Meta map[string]string
+	// Address is the address advertised for the service. If empty, the address the server is bound to
+	// is advertised, unless it is unspecified, in which case the agent advertises its own.
+	Address string
+	// HealthPath is the path, e.g. "/readyz", polled by the agent to check the health of the service,
+	// on the address the server is bound to. No check is registered if empty.
+	HealthPath string
+	// HealthTLS polls the health path over HTTPS, for the servers serving TLS, whose certificate must be
+	// trusted by the agent.
+	HealthTLS bool
+	// CheckInterval is the interval of the health check, 10 seconds if not positive.
+	CheckInterval time.Duration
+	// DeregisterCriticalAfter is the time after which the agent deregisters the service whose health check
+	// keeps failing, e.g. after the process was killed, if positive.
+	DeregisterCriticalAfter time.Duration
+}
+
+// WithService registers the service with the agent once the server is listening, and deregisters it at the very
+// start of the shutdown, then waits for the settle time, serving as usual, through [gracefulhttp.WithRegistrar].
+// The service is bound to the first address the server is listening on, which must be a TCP one.
+func WithService(agent Agent, svc Service, settle time.Duration) gracefulhttp.GracefulServerOption {
+	return gracefulhttp.WithRegistrar(NewRegistrar(agent, svc), settle)
+}
+
+// NewRegistrar returns a [gracefulhttp.Registrar] registering the service with the agent, e.g. to be composed
+// with the registrars of other discovery systems. See [WithService] for the details.
+func NewRegistrar(agent Agent, svc Service) gracefulhttp.Registrar {
+	return &registration{agent: agent, svc: svc}
+}
+
+// registration registers a server with an agent.
+type registration struct {
+	agent Agent
+	svc   Service
+
+	mu sync.Mutex
+	// id is the ID of the registered instance, once registered.
+	id string
+}
+
+// serviceDefinition is the body of the registration request of the agent API.
+type serviceDefinition struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Address string            `json:"Address,omitempty"`
+	Port    int               `json:"Port"`
+	Check   *checkDefinition  `json:"Check,omitempty"`
+}
+
+// checkDefinition is the HTTP health check of a [serviceDefinition].
+type checkDefinition struct {
+	HTTP                           string `json:"HTTP"`
+	Interval                       string `json:"Interval"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter,omitempty"`
+}
+
+// Register registers the service with the first address the server is bound to.
+func (r *registration) Register(ctx context.Context, info gracefulhttp.RegistrationInfo) error {
+	var addr *net.TCPAddr
+	if len(info.Addrs) > 0 {
+		addr, _ = info.Addrs[0].(*net.TCPAddr)
+	}
+
+	if addr == nil {
+		return fmt.Errorf("gracefulconsul: unsupported listener addresses %v", info.Addrs)
+	}
+
+	def := r.definition(addr)
+	body, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+
+	if err := r.agent.do(ctx, "/v1/agent/service/register", body); err != nil {
+		return fmt.Errorf("gracefulconsul: register %s: %w", def.ID, err)
+	}
+
+	r.mu.Lock()
+	r.id = def.ID
+	r.mu.Unlock()
+
+	return nil
+}
+
+// definition returns the definition of the service bound to the address.
+func (r *registration) definition(addr *net.TCPAddr) serviceDefinition {
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() {
+		host = ""
+	}
+
+	def := serviceDefinition{
+		ID:      r.svc.ID,
+		Name:    r.svc.Name,
+		Tags:    r.svc.Tags,
+		Meta:    r.svc.Meta,
+		Address: cmp.Or(r.svc.Address, host),
+		Port:    addr.Port,
+	}
+
+	if def.ID == "" {
+		def.ID = r.svc.Name + "-" + cmp.Or(def.Address, hostname()) + "-" + strconv.Itoa(addr.Port)
+	}
+
+	if r.svc.HealthPath != "" {
+		scheme := "http"
+		if r.svc.HealthTLS {
+			scheme = "https"
+		}
+
+		checkURL := url.URL{
+			Scheme: scheme,
+			Host:   net.JoinHostPort(cmp.Or(host, "127.0.0.1"), strconv.Itoa(addr.Port)),
+			Path:   "/" + strings.TrimPrefix(r.svc.HealthPath, "/"),
+		}
+
+		def.Check = &checkDefinition{
+			HTTP:     checkURL.String(),
+			Interval: cmp.Or(max(r.svc.CheckInterval, 0), defaultCheckInterval).String(),
+		}
+
+		if r.svc.DeregisterCriticalAfter > 0 {
+			def.Check.DeregisterCriticalServiceAfter = r.svc.DeregisterCriticalAfter.String()
+		}
+	}
+
+	return def
+}
+
+// Deregister deregisters the service, if registered.
+func (r *registration) Deregister(ctx context.Context) error {
+	r.mu.Lock()
+	id := r.id
+	r.id = ""
+	r.mu.Unlock()
+
+	if id == "" {
+		return nil
+	}
+
+	if err := r.agent.do(ctx, "/v1/agent/service/deregister/"+url.PathEscape(id), nil); err != nil {
+		return fmt.Errorf("gracefulconsul: deregister %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// do sends a PUT request to the path of the agent API, failing unless answered with 200 OK.
+func (a Agent) do(ctx context.Context, path string, body []byte) error {
+	addr := cmp.Or(a.Addr, os.Getenv("CONSUL_HTTP_ADDR"), defaultAgentAddr)
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(addr, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if token := cmp.Or(a.Token, os.Getenv("CONSUL_HTTP_TOKEN")); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+
+	res, err := cmp.Or(a.Client, http.DefaultClient).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("agent answered %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// hostname returns the name of the host, identifying the instances of the services not bound to an address.
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+
+	return name
+}
@@ -0,0 +1,144 @@
+package gracefulconsul
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent records the registrations and deregistrations it receives.
+type fakeAgent struct {
+	mu           sync.Mutex
+	registered   []serviceDefinition
+	deregistered []string
+	tokens       []string
+	fail         bool
+}
+
+func (a *fakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tokens = append(a.tokens, r.Header.Get("X-Consul-Token"))
+	if a.fail {
+		http.Error(w, "agent unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/agent/service/register":
+		var def serviceDefinition
+		if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.registered = append(a.registered, def)
+	case r.Method == http.MethodPut && len(r.URL.Path) > len("/v1/agent/service/deregister/"):
+		a.deregistered = append(a.deregistered, r.URL.Path[len("/v1/agent/service/deregister/"):])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *fakeAgent) registrations() ([]serviceDefinition, []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]serviceDefinition(nil), a.registered...), append([]string(nil), a.deregistered...)
+}
+
+func TestWithService(t *testing.T) {
+	fake := &fakeAgent{}
+	agentServer := httptest.NewServer(fake)
+	defer agentServer.Close()
+
+	s := gracefulhttp.Bind("127.0.0.1:0", http.NotFoundHandler())
+
+	var deregisteredBeforeDrain bool
+	s.RegisterShutdownHook("check", func(context.Context) error {
+		_, deregistered := fake.registrations()
+		deregisteredBeforeDrain = len(deregistered) == 1
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithService(
+			Agent{Addr: agentServer.URL, Token: "secret"},
+			Service{Name: "api", Tags: []string{"v1"}, HealthPath: "readyz", CheckInterval: time.Second},
+			0,
+		))
+	}()
+	<-s.Ready()
+
+	require.Eventually(t, func() bool {
+		registered, _ := fake.registrations()
+		return len(registered) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	port := s.ListenerAddr().(*net.TCPAddr).Port
+	registered, _ := fake.registrations()
+	assert.Equal(t, serviceDefinition{
+		ID:      "api-127.0.0.1-" + strconv.Itoa(port),
+		Name:    "api",
+		Tags:    []string{"v1"},
+		Address: "127.0.0.1",
+		Port:    port,
+		Check: &checkDefinition{
+			HTTP:     "http://127.0.0.1:" + strconv.Itoa(port) + "/readyz",
+			Interval: "1s",
+		},
+	}, registered[0])
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, deregistered := fake.registrations()
+	assert.Equal(t, []string{registered[0].ID}, deregistered)
+	assert.True(t, deregisteredBeforeDrain)
+	assert.Equal(t, []string{"secret", "secret"}, fake.tokens)
+}
+
+func TestWithService_agentFailure(t *testing.T) {
+	fake := &fakeAgent{fail: true}
+	agentServer := httptest.NewServer(fake)
+	defer agentServer.Close()
+
+	s := gracefulhttp.Bind("127.0.0.1:0", http.NotFoundHandler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithService(Agent{Addr: agentServer.URL}, Service{Name: "api"}, 0))
+	}()
+	<-s.Ready()
+
+	require.Eventually(t, func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		return len(fake.tokens) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The server is not reported ready while not registered, and nothing is deregistered.
+	rec := httptest.NewRecorder()
+	s.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, deregistered := fake.registrations()
+	assert.Empty(t, deregistered)
+}
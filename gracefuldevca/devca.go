@@ -0,0 +1,250 @@
+// Package gracefuldevca provides a local certificate authority for development, similar to mkcert:
+// the authority is created once and kept on disk, then mints the host certificates served by a
+// [gracefulhttp.GracefulServer], trusted by the clients through its certificate pool.
+// It is not meant for production.
+package gracefuldevca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+)
+
+const (
+	// certFileName is the name of the file holding the PEM encoded certificate of the authority.
+	certFileName = "ca.pem"
+	// keyFileName is the name of the file holding the PEM encoded private key of the authority.
+	keyFileName = "ca-key.pem"
+
+	// caValidity is the validity of the certificate of the authority.
+	caValidity = 10 * 365 * 24 * time.Hour
+	// hostValidity is the validity of the host certificates, within the limit accepted by the browsers.
+	hostValidity = 397 * 24 * time.Hour
+)
+
+// A CA is a local certificate authority minting host certificates.
+type CA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+}
+
+// Load returns the authority stored in the directory, creating it on first use. The directory defaults to
+// gracefulhttp-devca under the user configuration directory, e.g. ~/.config on Linux. The private key is
+// only readable by the user.
+func Load(dir string) (*CA, error) {
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+
+		dir = filepath.Join(config, "gracefulhttp-devca")
+	}
+
+	certPath, keyPath := filepath.Join(dir, certFileName), filepath.Join(dir, keyFileName)
+
+	certPEM, err := os.ReadFile(certPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return create(dir, certPath, keyPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(certPEM, keyPEM)
+}
+
+// New returns an authority held in memory, e.g. for tests.
+func New() (*CA, error) {
+	certPEM, keyPEM, err := generate()
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(certPEM, keyPEM)
+}
+
+// create generates the authority, and stores it in the directory.
+func create(dir, certPath, keyPath string) (*CA, error) {
+	certPEM, keyPEM, err := generate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return nil, err
+	}
+
+	return parse(certPEM, keyPEM)
+}
+
+// generate returns the PEM encoded certificate and private key of a new authority.
+func generate() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	host, _ := os.Hostname()
+	template, err := newTemplate(caValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template.Subject = pkix.Name{Organization: []string{"gracefulhttp development CA"}, CommonName: "gracefulhttp devca " + host}
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	template.BasicConstraintsValid = true
+	template.IsCA = true
+	template.MaxPathLenZero = true
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encode(der, key)
+}
+
+// parse returns the authority of the PEM encoded certificate and private key.
+func parse(certPEM, keyPEM []byte) (*CA, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("gracefuldevca: invalid authority: %w", err)
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok || !pair.Leaf.IsCA {
+		return nil, errors.New("gracefuldevca: invalid authority: not a certificate authority")
+	}
+
+	return &CA{cert: pair.Leaf, certPEM: certPEM, key: key}, nil
+}
+
+// Mint returns a PEM encoded certificate signed by the authority, valid for the hosts, which are DNS names
+// or IP addresses ("localhost", "127.0.0.1" and "::1" by default), and its private key.
+func (ca *CA) Mint(hosts ...string) (certPEM, keyPEM []byte, err error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(hostValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template.Subject = pkix.Name{Organization: []string{"gracefulhttp development certificate"}, CommonName: hosts[0]}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encode(der, key)
+}
+
+// ServerOption mints a certificate for the hosts, and returns the option serving it through
+// [gracefulhttp.WithKeyPairPEM]. Pass empty certFile and keyFile to
+// [gracefulhttp.GracefulServer.ListenAndServeTLSWithShutdown] when using it.
+func (ca *CA) ServerOption(hosts ...string) (gracefulhttp.GracefulServerOption, error) {
+	certPEM, keyPEM, err := ca.Mint(hosts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return gracefulhttp.WithKeyPairPEM(certPEM, keyPEM), nil
+}
+
+// CertPool returns a pool trusting the authority only, e.g. for the RootCAs of the clients.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	return pool
+}
+
+// Transport returns a clone of [http.DefaultTransport] trusting the authority only.
+func (ca *CA) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{RootCAs: ca.CertPool()}
+
+	return t
+}
+
+// CertPEM returns the PEM encoded certificate of the authority, e.g. to be trusted by a browser.
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+// newTemplate returns the template of a certificate valid for the duration, with a random serial number.
+func newTemplate(validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+// encode returns the PEM encoded certificate and private key.
+func encode(der []byte, key *ecdsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
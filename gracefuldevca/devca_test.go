@@ -0,0 +1,65 @@
+package gracefuldevca
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "devca")
+
+	ca, err := Load(dir)
+	require.NoError(t, err)
+
+	fi, err := os.Stat(filepath.Join(dir, keyFileName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	// The authority is kept on disk, and loaded again.
+	again, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ca.CertPEM(), again.CertPEM())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyFileName), []byte("invalid"), 0o600))
+	_, err = Load(dir)
+	assert.Error(t, err)
+}
+
+func TestCA_ServerOption(t *testing.T) {
+	ca, err := New()
+	require.NoError(t, err)
+
+	opt, err := ca.ServerOption("localhost", "127.0.0.1")
+	require.NoError(t, err)
+
+	s := gracefulhttp.Bind("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", opt)
+	}()
+	<-s.Ready()
+
+	// The certificate is verified against the authority.
+	client := &http.Client{Transport: ca.Transport()}
+	r, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	other, err := New()
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: other.Transport()}).Get("https://" + s.ListenerAddr().String())
+	assert.Error(t, err)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,182 @@
+// Package gracefuletcd registers a [gracefulhttp.GracefulServer] in etcd, under a key attached to a lease kept
+// alive while the server runs and revoked as soon as the shutdown begins, so that the clients discovering the
+// instances through the key prefix stop finding the server before it drains. It is kept in a separate module
+// to spare the dependency on the etcd client to the other users of gracefulhttp.
+package gracefuletcd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// defaultTTL is the default time to live of the lease of the key.
+const defaultTTL = 10 * time.Second
+
+// Client is the subset of the etcd client used to register the server, implemented by a *clientv3.Client.
+type Client interface {
+	clientv3.KV
+	clientv3.Lease
+}
+
+// Registration describes the key registering the server.
+type Registration struct {
+	// Key is the key registering the server, e.g. "/services/api/instance-1".
+	Key string
+	// Value is the value of the key. If empty, the address the server is bound to, e.g. "10.0.0.7:8080".
+	Value string
+	// TTL is the time to live of the lease, after which the key is deleted once the server stops keeping
+	// it alive, e.g. when the process is killed, 10 seconds if less than a second.
+	TTL time.Duration
+}
+
+// WithRegistration puts the key once the server is listening, and revokes its lease, deleting the key, at the
+// very start of the shutdown, then waits for the settle time, serving as usual, through [gracefulhttp.WithRegistrar].
+// The lease is kept alive while the server runs, and granted again, putting the key back, if it expires
+// meanwhile, e.g. after a network partition.
+func WithRegistration(client Client, reg Registration, settle time.Duration) gracefulhttp.GracefulServerOption {
+	return func(s *gracefulhttp.GracefulServer) {
+		r := &registration{client: client, reg: reg}
+
+		gracefulhttp.WithRegistrar(r, settle)(s)
+		// The lease is revoked once the server has stopped as well, if it was not drained.
+		s.RegisterCleanup("etcd registration", cmp.Or(reg.TTL, defaultTTL), r.Deregister)
+	}
+}
+
+// registration registers a server in etcd.
+type registration struct {
+	client Client
+	reg    Registration
+
+	mu sync.Mutex
+	// lease is the lease of the key, once put.
+	lease clientv3.LeaseID
+	// cancel stops keeping the lease alive, once put.
+	cancel context.CancelFunc
+	// done is closed once the lease is no longer kept alive.
+	done chan struct{}
+}
+
+// Register grants the lease, puts the key and keeps the lease alive in the background.
+func (r *registration) Register(ctx context.Context, info gracefulhttp.RegistrationInfo) error {
+	value := r.reg.Value
+	if value == "" {
+		if len(info.Addrs) == 0 {
+			return errors.New("gracefuletcd: server not listening")
+		}
+
+		value = info.Addrs[0].String()
+	}
+
+	lease, err := r.put(ctx, value)
+	if err != nil {
+		return err
+	}
+
+	keepCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	r.mu.Lock()
+	r.lease, r.cancel, r.done = lease, cancel, done
+	r.mu.Unlock()
+
+	go r.keepAlive(keepCtx, value, lease, done)
+
+	return nil
+}
+
+// put grants a lease and puts the key with the value attached to it, returning the lease.
+func (r *registration) put(ctx context.Context, value string) (clientv3.LeaseID, error) {
+	if r.reg.Key == "" {
+		return 0, errors.New("gracefuletcd: empty key")
+	}
+
+	ttl := cmp.Or(max(int64(r.reg.TTL/time.Second), 0), int64(defaultTTL/time.Second))
+	grant, err := r.client.Grant(ctx, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("gracefuletcd: grant lease: %w", err)
+	}
+
+	if _, err := r.client.Put(ctx, r.reg.Key, value, clientv3.WithLease(grant.ID)); err != nil {
+		_, _ = r.client.Revoke(context.WithoutCancel(ctx), grant.ID)
+		return 0, fmt.Errorf("gracefuletcd: put %s: %w", r.reg.Key, err)
+	}
+
+	return grant.ID, nil
+}
+
+// keepAlive keeps the lease alive until the context is done, granting a new one and putting the key back
+// whenever the lease is lost, then closes done.
+func (r *registration) keepAlive(ctx context.Context, value string, lease clientv3.LeaseID, done chan struct{}) {
+	defer close(done)
+
+	retry := cmp.Or(r.reg.TTL/3, defaultTTL/3)
+	for {
+		ch, err := r.client.KeepAlive(ctx, lease)
+		if err == nil {
+			// The channel is closed once the context is done or the lease is lost.
+			for range ch {
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+
+			if lease, err = r.put(ctx, value); err == nil {
+				break
+			}
+		}
+
+		r.mu.Lock()
+		r.lease = lease
+		r.mu.Unlock()
+	}
+}
+
+// Deregister stops keeping the lease alive and revokes it, deleting the key, if put.
+func (r *registration) Deregister(ctx context.Context) error {
+	lease := r.stop()
+	if lease == 0 {
+		return nil
+	}
+
+	if _, err := r.client.Revoke(ctx, lease); err != nil {
+		return fmt.Errorf("gracefuletcd: revoke lease of %s: %w", r.reg.Key, err)
+	}
+
+	return nil
+}
+
+// stop stops keeping the lease alive, returning the lease, if any.
+func (r *registration) stop() clientv3.LeaseID {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return 0
+	}
+
+	cancel()
+	<-done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lease := r.lease
+	r.lease = 0
+
+	return lease
+}
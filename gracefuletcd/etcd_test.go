@@ -0,0 +1,178 @@
+package gracefuletcd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeClient stores the keys in memory, deleting them with their lease.
+type fakeClient struct {
+	clientv3.KV
+	clientv3.Lease
+
+	mu        sync.Mutex
+	nextLease clientv3.LeaseID
+	keys      map[string]string
+	leases    map[string]clientv3.LeaseID
+	revoked   []clientv3.LeaseID
+	keepAlive map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse
+	putErr    error
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		keys:      make(map[string]string),
+		leases:    make(map[string]clientv3.LeaseID),
+		keepAlive: make(map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse),
+	}
+}
+
+func (c *fakeClient) Grant(_ context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextLease++
+
+	return &clientv3.LeaseGrantResponse{ID: c.nextLease, TTL: ttl}, nil
+}
+
+func (c *fakeClient) Put(_ context.Context, key, val string, _ ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.putErr != nil {
+		return nil, c.putErr
+	}
+
+	// The lease option is not readable: the key is put under the lease granted last.
+	c.keys[key] = val
+	c.leases[key] = c.nextLease
+
+	return &clientv3.PutResponse{}, nil
+}
+
+func (c *fakeClient) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan *clientv3.LeaseKeepAliveResponse)
+	c.keepAlive[id] = ch
+	go func() {
+		<-ctx.Done()
+		c.expire(id)
+	}()
+
+	return ch, nil
+}
+
+// expire closes the keepalive channel of the lease, as if the lease was lost, deleting its keys.
+func (c *fakeClient) expire(id clientv3.LeaseID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ch, ok := c.keepAlive[id]; ok {
+		close(ch)
+		delete(c.keepAlive, id)
+	}
+
+	for key, lease := range c.leases {
+		if lease == id {
+			delete(c.keys, key)
+			delete(c.leases, key)
+		}
+	}
+}
+
+func (c *fakeClient) Revoke(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	c.mu.Lock()
+	c.revoked = append(c.revoked, id)
+	c.mu.Unlock()
+
+	c.expire(id)
+
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (c *fakeClient) get(key string) (string, clientv3.LeaseID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.keys[key], c.leases[key]
+}
+
+func TestWithRegistration(t *testing.T) {
+	client := newFakeClient()
+	s := gracefulhttp.Bind("127.0.0.1:0", http.NotFoundHandler())
+
+	var registeredOnDrain bool
+	s.RegisterShutdownHook("check", func(context.Context) error {
+		value, _ := client.get("/services/api/1")
+		registeredOnDrain = value != ""
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx,
+			WithRegistration(client, Registration{Key: "/services/api/1", TTL: 3 * time.Second}, 0))
+	}()
+	<-s.Ready()
+
+	require.Eventually(t, func() bool {
+		value, _ := client.get("/services/api/1")
+		return value == s.ListenerAddr().String()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The key is put back under a new lease once the lease is lost.
+	_, lease := client.get("/services/api/1")
+	client.expire(lease)
+	require.Eventually(t, func() bool {
+		_, newLease := client.get("/services/api/1")
+		return newLease != 0 && newLease != lease
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, lease = client.get("/services/api/1")
+
+	cancel()
+	require.NoError(t, <-done)
+
+	value, _ := client.get("/services/api/1")
+	assert.Empty(t, value)
+	assert.False(t, registeredOnDrain)
+	assert.Equal(t, []clientv3.LeaseID{lease}, client.revoked)
+}
+
+func TestWithRegistration_putFailure(t *testing.T) {
+	client := newFakeClient()
+	client.putErr = errors.New("etcd unavailable")
+
+	s := gracefulhttp.Bind("127.0.0.1:0", http.NotFoundHandler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithRegistration(client, Registration{Key: "/services/api/1"}, 0))
+	}()
+	<-s.Ready()
+
+	// The granted lease is revoked, and the server is not reported ready.
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+
+		return len(client.revoked) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,27 @@
+module github.com/aoliveti/gracefulhttp/gracefulgrpc
+
+go 1.24.0
+
+require (
+	github.com/aoliveti/gracefulhttp v0.0.0
+	github.com/soheilhy/cmux v0.1.5
+	github.com/stretchr/testify v1.11.1
+	google.golang.org/grpc v1.78.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.48.0 // indirect
+	golang.org/x/net v0.49.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
+	golang.org/x/time v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251029180050-ab9386a59fda // indirect
+	google.golang.org/protobuf v1.36.10 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/aoliveti/gracefulhttp => ../
@@ -0,0 +1,107 @@
+// Package gracefulgrpc serves a gRPC server and a [gracefulhttp.GracefulServer] on the same port,
+// multiplexing the connections through cmux, and shuts both of them down within the same
+// graceful timeout. It is kept in a separate module to spare the dependencies on gRPC and cmux
+// to the other users of gracefulhttp.
+package gracefulgrpc
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/soheilhy/cmux"
+)
+
+// Server is the subset of the methods of a *grpc.Server used to serve and stop it.
+type Server interface {
+	gracefulhttp.GracefulStopper
+	Serve(lis net.Listener) error
+}
+
+// WithGRPC serves the gRPC server on the port of the server: the connections sending HTTP/2
+// requests with the "application/grpc" content type are handed to the gRPC server, while any
+// other connection is served by the HTTP server.
+//
+// Once the shutdown begins, the gRPC server is gracefully stopped in parallel with the HTTP server,
+// and forcibly stopped when the graceful timeout expires. The connections are multiplexed before
+// any TLS layer, so it is meant to be used with [gracefulhttp.GracefulServer.ListenAndServeWithShutdown]
+// and plaintext gRPC. Only the first listener of the server is multiplexed.
+func WithGRPC(srv Server) gracefulhttp.GracefulServerOption {
+	return func(s *gracefulhttp.GracefulServer) {
+		svc := &service{server: s, grpc: srv}
+
+		gracefulhttp.WithListenerWrapper(svc.wrap)(s)
+		gracefulhttp.WithService(svc)(s)
+	}
+}
+
+// service runs the connection multiplexer and the gRPC server as a [gracefulhttp.Service].
+type service struct {
+	server *gracefulhttp.GracefulServer
+	grpc   Server
+
+	once   sync.Once
+	mux    cmux.CMux
+	grpcLn net.Listener
+}
+
+// wrap multiplexes the first listener, returning the listener of the HTTP connections.
+func (svc *service) wrap(ln net.Listener) net.Listener {
+	wrapped := ln
+
+	svc.once.Do(func() {
+		svc.mux = cmux.New(ln)
+		svc.grpcLn = svc.mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+		wrapped = svc.mux.Match(cmux.Any())
+	})
+
+	return wrapped
+}
+
+// Serve dispatches the connections and serves the gRPC ones until the shutdown.
+// The errors caused by the listener being closed during the shutdown are ignored.
+func (svc *service) Serve() error {
+	grpcErr := make(chan error, 1)
+	go func() {
+		grpcErr <- svc.grpc.Serve(svc.grpcLn)
+	}()
+
+	muxErr := svc.mux.Serve()
+	err := <-grpcErr
+
+	select {
+	case <-svc.server.Draining():
+		return nil
+	default:
+	}
+
+	if muxErr != nil {
+		return muxErr
+	}
+
+	return err
+}
+
+// Shutdown gracefully stops the gRPC server, waiting for the pending RPCs to complete.
+func (svc *service) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.grpc.GracefulStop()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close forcibly stops the gRPC server, closing the pending RPCs.
+func (svc *service) Close() error {
+	svc.grpc.Stop()
+
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package gracefulhttp3 serves HTTP/3 alongside a [gracefulhttp.GracefulServer], using quic-go.
+// The HTTP/3 server listens on the UDP port matching the TCP listener, advertises itself through
+// the Alt-Svc header, and takes part in the graceful shutdown: once it begins, a GOAWAY frame is
+// sent to the clients and the connections are closed within the same graceful timeout.
+// It is kept in a separate module to spare the dependency on quic-go to the other users of gracefulhttp.
+package gracefulhttp3
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// WithHTTP3 serves HTTP/3 alongside the server. It is meant to be used with
+// [gracefulhttp.GracefulServer.ListenAndServeTLSWithShutdown], as QUIC requires TLS.
+//
+// The provided HTTP/3 server, if any, allows tuning the QUIC configuration: when its handler
+// or its TLS configuration are nil, those of the graceful server are used. Its address is
+// ignored, as the UDP port always matches the TCP listener.
+func WithHTTP3(h3 *http3.Server) gracefulhttp.GracefulServerOption {
+	if h3 == nil {
+		h3 = &http3.Server{}
+	}
+
+	return func(s *gracefulhttp.GracefulServer) {
+		svc := &service{server: s, h3: h3}
+		s.Handler = svc.altSvcHandler(s.Handler)
+
+		gracefulhttp.WithService(svc)(s)
+	}
+}
+
+// service runs the HTTP/3 server as a [gracefulhttp.Service].
+type service struct {
+	server *gracefulhttp.GracefulServer
+	h3     *http3.Server
+
+	mu     sync.Mutex
+	conn   net.PacketConn
+	closed bool
+}
+
+// altSvcHandler advertises HTTP/3 on the responses through the Alt-Svc header.
+func (svc *service) altSvcHandler(next http.Handler) http.Handler {
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			_ = svc.h3.SetQUICHeaders(w.Header())
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Serve listens on the UDP address matching the TCP listener of the server, and serves HTTP/3.
+func (svc *service) Serve() error {
+	conn, err := net.ListenPacket("udp", svc.server.ListenerAddr().String())
+	if err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	if svc.closed {
+		svc.mu.Unlock()
+		_ = conn.Close()
+
+		return http.ErrServerClosed
+	}
+	svc.conn = conn
+	svc.mu.Unlock()
+
+	if svc.h3.Handler == nil {
+		svc.h3.Handler = svc.server.Handler
+	}
+
+	if svc.h3.TLSConfig == nil {
+		svc.h3.TLSConfig = svc.server.TLSConfig
+	}
+
+	return svc.h3.Serve(conn)
+}
+
+// Shutdown sends a GOAWAY frame to the clients, and waits for the connections to be closed.
+func (svc *service) Shutdown(ctx context.Context) error {
+	err := svc.h3.Shutdown(ctx)
+	svc.closeConn()
+
+	return err
+}
+
+// Close forcibly closes the connections.
+func (svc *service) Close() error {
+	err := svc.h3.Close()
+	svc.closeConn()
+
+	return err
+}
+
+// closeConn closes the UDP socket, which is left open by the HTTP/3 server
+// as long as its connections are using it.
+func (svc *service) closeConn() {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.closed = true
+	if svc.conn != nil {
+		_ = svc.conn.Close()
+	}
+}
@@ -0,0 +1,93 @@
+package gracefulhttp3
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTTP3(t *testing.T) {
+	certPEM, keyPEM, err := gracefulhttp.GenerateKeyPair("127.0.0.1")
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	started := make(chan struct{}, 1)
+	s := gracefulhttp.Bind("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		_, _ = io.WriteString(w, r.Proto)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", gracefulhttp.WithKeyPairPEM(certPEM, keyPEM), WithHTTP3(nil))
+	}()
+
+	<-s.Ready()
+
+	url := "https://" + s.ListenerAddr().String()
+
+	tcpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	r, err := tcpClient.Get(url)
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+	assert.Contains(t, r.Header.Get("Alt-Svc"), `h3=":`)
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	defer transport.Close()
+	client := &http.Client{Transport: transport}
+
+	get := func(path string) (string, error) {
+		r, err := client.Get(url + path)
+		if err != nil {
+			return "", err
+		}
+		defer r.Body.Close()
+
+		body, err := io.ReadAll(r.Body)
+
+		return string(body), err
+	}
+
+	var body string
+	require.Eventually(t, func() bool {
+		body, err = get("/")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "HTTP/3.0", body)
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		body, err := get("/slow")
+		inFlight <- result{body, err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-inFlight
+	require.NoError(t, res.err)
+	assert.Equal(t, "HTTP/3.0", res.body)
+
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,87 @@
+// Package gracefulhttpfx wires a [gracefulhttp.GracefulServer] into the lifecycle of an fx application:
+// the server starts listening when the application starts, and is shut down gracefully when it stops.
+package gracefulhttpfx
+
+import (
+	"context"
+
+	"github.com/aoliveti/gracefulhttp"
+	"go.uber.org/fx"
+)
+
+// Module serves the *gracefulhttp.GracefulServer provided to the application through
+// [gracefulhttp.GracefulServer.ListenAndServeWithShutdown], with the options, e.g.:
+//
+//	fx.New(
+//		fx.Provide(func(h http.Handler) *gracefulhttp.GracefulServer {
+//			return gracefulhttp.Bind(":8080", h)
+//		}),
+//		gracefulhttpfx.Module(gracefulhttp.WithShutdownTimeout(10*time.Second)),
+//	).Run()
+//
+// The application starts once the server is listening, and fails to start if it cannot listen.
+// Stopping the application starts the graceful shutdown: set a graceful timeout shorter than the stop
+// timeout of the application, as the shutdown goes on in the background once the latter expires.
+// If serving fails while the application is running, the application is shut down.
+func Module(opts ...gracefulhttp.GracefulServerOption) fx.Option {
+	return module(func(ctx context.Context, s *gracefulhttp.GracefulServer) error {
+		return s.ListenAndServeWithShutdown(ctx, opts...)
+	})
+}
+
+// ModuleTLS serves the *gracefulhttp.GracefulServer provided to the application through
+// [gracefulhttp.GracefulServer.ListenAndServeTLSWithShutdown], with the certificate, key and options.
+// See [Module] for the details.
+func ModuleTLS(certFile, keyFile string, opts ...gracefulhttp.GracefulServerOption) fx.Option {
+	return module(func(ctx context.Context, s *gracefulhttp.GracefulServer) error {
+		return s.ListenAndServeTLSWithShutdown(ctx, certFile, keyFile, opts...)
+	})
+}
+
+// module returns the fx module serving the server through serve.
+func module(serve func(ctx context.Context, s *gracefulhttp.GracefulServer) error) fx.Option {
+	return fx.Module("gracefulhttp", fx.Invoke(func(lc fx.Lifecycle, sh fx.Shutdowner, s *gracefulhttp.GracefulServer) {
+		lc.Append(newHook(sh, s, serve))
+	}))
+}
+
+// newHook returns the lifecycle hook starting and stopping the server.
+func newHook(sh fx.Shutdowner, s *gracefulhttp.GracefulServer, serve func(ctx context.Context, s *gracefulhttp.GracefulServer) error) fx.Hook {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan struct{})
+	var serveErr error
+
+	return fx.Hook{
+		OnStart: func(startCtx context.Context) error {
+			go func() {
+				defer close(done)
+
+				serveErr = serve(ctx, s)
+				if serveErr != nil && ctx.Err() == nil {
+					// Serving failed on its own: stop the application.
+					_ = sh.Shutdown(fx.ExitCode(1))
+				}
+			}()
+
+			select {
+			case <-s.Ready():
+				return nil
+			case <-done:
+				return serveErr
+			case <-startCtx.Done():
+				cancel(gracefulhttp.ErrImmediateStop)
+				return startCtx.Err()
+			}
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel(context.Canceled)
+
+			select {
+			case <-done:
+				return serveErr
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	}
+}
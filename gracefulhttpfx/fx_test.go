@@ -0,0 +1,76 @@
+package gracefulhttpfx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestModule(t *testing.T) {
+	s := gracefulhttp.Bind("localhost:0", http.NotFoundHandler())
+	app := fxtest.New(t, fx.Supply(s), Module())
+
+	require.NoError(t, app.Start(context.Background()))
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+
+	require.NoError(t, app.Stop(context.Background()))
+	assert.True(t, isClosed(s.Draining()))
+}
+
+func TestModule_ListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := gracefulhttp.Bind(ln.Addr().String(), nil)
+	app := fx.New(fx.Supply(s), Module(), fx.NopLogger)
+
+	assert.Error(t, app.Start(context.Background()))
+}
+
+func TestModule_ServeError(t *testing.T) {
+	s := gracefulhttp.Bind("localhost:0", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("corrupted")
+	}))
+	app := fxtest.New(t, fx.Supply(s), Module(gracefulhttp.WithPanicShutdown(1, 0)))
+
+	require.NoError(t, app.Start(context.Background()))
+
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err == nil {
+			_ = r.Body.Close()
+		}
+	}()
+
+	select {
+	case sig := <-app.Wait():
+		assert.Equal(t, 1, sig.ExitCode)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the application was not shut down")
+	}
+
+	assert.ErrorIs(t, app.Stop(context.Background()), gracefulhttp.ErrPanicShutdown)
+}
+
+// isClosed reports whether the channel is closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
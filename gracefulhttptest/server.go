@@ -0,0 +1,113 @@
+// Package gracefulhttptest provides utilities for end-to-end tests of handlers
+// served by a [gracefulhttp.GracefulServer], exercising its shutdown machinery.
+package gracefulhttptest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aoliveti/gracefulhttp"
+)
+
+// A Server is a [gracefulhttp.GracefulServer] listening on a random port of the local
+// loopback interface, similar to [httptest.Server].
+type Server struct {
+	*gracefulhttp.GracefulServer
+
+	// URL is the base URL of the form http://ipaddr:port with no trailing slash.
+	URL string
+
+	cancel context.CancelFunc
+	done   chan error
+	err    error
+	waited bool
+}
+
+// Result is the outcome of a request issued in the background by [Server.Go].
+type Result struct {
+	// Response is the response received, with a closed body.
+	Response *http.Response
+	// Body is the content of the response body.
+	Body []byte
+	// Err is the error returned by the client, if any.
+	Err error
+}
+
+// NewServer starts and returns a new [Server] serving the handler with the provided options.
+// It blocks until the server is accepting connections, and panics if it fails to start.
+// The caller should call Shutdown or Close when finished, to shut it down.
+func NewServer(handler http.Handler, opts ...gracefulhttp.GracefulServerOption) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Server{
+		GracefulServer: gracefulhttp.Bind("127.0.0.1:0", handler),
+		cancel:         cancel,
+		done:           make(chan error, 1),
+	}
+
+	go func() {
+		s.done <- s.ListenAndServeWithShutdown(ctx, opts...)
+	}()
+
+	select {
+	case <-s.Ready():
+	case err := <-s.done:
+		cancel()
+		panic(fmt.Sprintf("gracefulhttptest: failed to start server: %v", err))
+	}
+
+	s.URL = "http://" + s.ListenerAddr().String()
+
+	return s
+}
+
+// Go issues a GET request to the path in the background, so that the shutdown
+// can be started while the request is in flight.
+func (s *Server) Go(path string) <-chan Result {
+	results := make(chan Result, 1)
+
+	go func() {
+		r, err := http.Get(s.URL + "/" + strings.TrimPrefix(path, "/"))
+		if err != nil {
+			results <- Result{Err: err}
+			return
+		}
+		defer r.Body.Close()
+
+		body, err := io.ReadAll(r.Body)
+		results <- Result{Response: r, Body: body, Err: err}
+	}()
+
+	return results
+}
+
+// BeginShutdown starts the graceful shutdown without waiting for it to complete.
+func (s *Server) BeginShutdown() {
+	s.cancel()
+}
+
+// Wait blocks until the server has stopped, returning the error returned by
+// [gracefulhttp.GracefulServer.ListenAndServeWithShutdown].
+func (s *Server) Wait() error {
+	if !s.waited {
+		s.err = <-s.done
+		s.waited = true
+	}
+
+	return s.err
+}
+
+// Shutdown gracefully shuts down the server and waits for it to stop.
+func (s *Server) Shutdown() error {
+	s.BeginShutdown()
+
+	return s.Wait()
+}
+
+// Close shuts down the server, ignoring the returned error.
+func (s *Server) Close() {
+	_ = s.Shutdown()
+}
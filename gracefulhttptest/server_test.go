@@ -0,0 +1,59 @@
+package gracefulhttptest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer(t *testing.T) {
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	result := <-s.Go("/")
+	require.NoError(t, result.Err)
+	assert.Equal(t, http.StatusOK, result.Response.StatusCode)
+	assert.Equal(t, "hello", string(result.Body))
+
+	require.NoError(t, s.Shutdown())
+}
+
+func TestServer_BeginShutdown(t *testing.T) {
+	started := make(chan struct{})
+
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	}), gracefulhttp.WithShutdownTimeout(time.Second))
+
+	inFlight := s.Go("/")
+	<-started
+
+	s.BeginShutdown()
+	<-s.Draining()
+
+	result := <-inFlight
+	require.NoError(t, result.Err)
+	assert.Equal(t, "done", string(result.Body))
+
+	require.NoError(t, s.Wait())
+}
+
+func TestNewServer_Panic(t *testing.T) {
+	s := NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	occupied := func(gs *gracefulhttp.GracefulServer) {
+		gs.Addr = s.ListenerAddr().String()
+	}
+
+	assert.Panics(t, func() {
+		NewServer(http.NotFoundHandler(), occupied)
+	})
+}
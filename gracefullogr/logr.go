@@ -0,0 +1,50 @@
+// Package gracefullogr writes the diagnostics of a [gracefulhttp.GracefulServer] through a logr logger.
+// It is kept in a separate module to spare the dependency on logr to the other users of gracefulhttp.
+package gracefullogr
+
+import (
+	"slices"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/go-logr/logr"
+)
+
+// New returns a [gracefulhttp.Logger] writing to the logr logger, to be set through [gracefulhttp.WithLogger]:
+//
+//	srv.ListenAndServeWithShutdown(ctx, gracefulhttp.WithLogger(gracefullogr.New(logger)))
+//
+// As logr has no warning level, the warnings are written as the informational messages, at verbosity zero.
+// The value of the "error" key of the errors, if any, is passed to logr as the error of the message.
+func New(l logr.Logger) gracefulhttp.Logger {
+	return logger{l: l.WithCallDepth(1)}
+}
+
+// logger adapts a logr logger to the [gracefulhttp.Logger] interface.
+type logger struct {
+	l logr.Logger
+}
+
+// Info writes an informational message.
+func (l logger) Info(msg string, args ...any) {
+	l.l.Info(msg, args...)
+}
+
+// Warn writes a warning as an informational message.
+func (l logger) Warn(msg string, args ...any) {
+	l.l.Info(msg, args...)
+}
+
+// Error writes an error message.
+func (l logger) Error(msg string, args ...any) {
+	var err error
+	for i := 0; i+1 < len(args); i += 2 {
+		if key, ok := args[i].(string); ok && key == "error" {
+			if err, ok = args[i+1].(error); ok {
+				args = slices.Delete(slices.Clone(args), i, i+2)
+				break
+			}
+		}
+	}
+
+	l.l.Error(err, msg, args...)
+}
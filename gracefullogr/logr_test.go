@@ -0,0 +1,26 @@
+package gracefullogr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	var logs []string
+	logger := New(funcr.New(func(_, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{}))
+
+	logger.Info("gracefulhttp: draining", "requests", 2)
+	logger.Warn("gracefulhttp: registration failed", "attempt", 1)
+	logger.Error("gracefulhttp: restart failed", "pid", 42, "error", errors.New("boom"))
+
+	assert.Equal(t, []string{
+		`"level"=0 "msg"="gracefulhttp: draining" "requests"=2`,
+		`"level"=0 "msg"="gracefulhttp: registration failed" "attempt"=1`,
+		`"msg"="gracefulhttp: restart failed" "error"="boom" "pid"=42`,
+	}, logs)
+}
@@ -0,0 +1,130 @@
+// Package gracefulspiffe serves mutual TLS with the X.509 SVIDs of a SPIFFE workload, such as those
+// issued by SPIRE, authorizing the clients by their SPIFFE ID. It is kept in a separate module to spare
+// the dependency on go-spiffe to the other users of gracefulhttp.
+package gracefulspiffe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+)
+
+// errSourceNotOpen is returned by the workload source used before the server has started or once it has stopped.
+var errSourceNotOpen = errors.New("gracefulspiffe: workload API source not open")
+
+// Source is a source of X.509 SVIDs and bundles, such as a *workloadapi.X509Source.
+type Source interface {
+	x509svid.Source
+	x509bundle.Source
+}
+
+// WithSource serves mutual TLS with the SVIDs of the source, verifying the client certificates against
+// its bundles and authorizing their SPIFFE ID through the authorizer, e.g. [tlsconfig.AuthorizeMemberOf].
+// The SVIDs rotated by the source are served right away. The source is left open once the server stops.
+// It is meant to be used with [gracefulhttp.GracefulServer.ListenAndServeTLSWithShutdown] and no certificate files.
+func WithSource(source Source, authorizer tlsconfig.Authorizer) gracefulhttp.GracefulServerOption {
+	return func(s *gracefulhttp.GracefulServer) {
+		gracefulhttp.WithTLSConfig(tlsconfig.MTLSServerConfig(source, source, authorizer))(s)
+	}
+}
+
+// WithWorkloadAPI serves mutual TLS as [WithSource] does, with the SVIDs obtained from the SPIFFE Workload API,
+// at the address set by the SPIFFE_ENDPOINT_SOCKET environment variable unless given through the options.
+// The source is opened before listening, waiting for the first SVID, and closed once the server has drained.
+func WithWorkloadAPI(authorizer tlsconfig.Authorizer, opts ...workloadapi.X509SourceOption) gracefulhttp.GracefulServerOption {
+	return func(s *gracefulhttp.GracefulServer) {
+		source := &workloadSource{}
+
+		WithSource(source, authorizer)(s)
+		gracefulhttp.WithWarmup(func(ctx context.Context) error {
+			return source.open(ctx, opts)
+		})(s)
+		s.RegisterCleanup("spiffe workload API", 0, func(context.Context) error {
+			return source.close()
+		})
+	}
+}
+
+// PeerID returns the SPIFFE ID of the client of the request, as authorized during the handshake,
+// letting the handlers apply finer grained authorization rules.
+func PeerID(r *http.Request) (spiffeid.ID, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return spiffeid.ID{}, errors.New("gracefulspiffe: no client certificate")
+	}
+
+	return x509svid.IDFromCert(r.TLS.PeerCertificates[0])
+}
+
+// workloadSource is a [Source] backed by a Workload API source opened once the server starts.
+type workloadSource struct {
+	mu     sync.Mutex
+	source *workloadapi.X509Source
+}
+
+// open creates the Workload API source, waiting for the first SVID until the context is done.
+func (w *workloadSource) open(ctx context.Context, opts []workloadapi.X509SourceOption) error {
+	source, err := workloadapi.NewX509Source(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.source = source
+	w.mu.Unlock()
+
+	return nil
+}
+
+// close closes the Workload API source, if open.
+func (w *workloadSource) close() error {
+	w.mu.Lock()
+	source := w.source
+	w.source = nil
+	w.mu.Unlock()
+
+	if source == nil {
+		return nil
+	}
+
+	return source.Close()
+}
+
+// current returns the Workload API source, if open.
+func (w *workloadSource) current() (*workloadapi.X509Source, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.source == nil {
+		return nil, errSourceNotOpen
+	}
+
+	return w.source, nil
+}
+
+// GetX509SVID returns the current SVID of the Workload API source.
+func (w *workloadSource) GetX509SVID() (*x509svid.SVID, error) {
+	source, err := w.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return source.GetX509SVID()
+}
+
+// GetX509BundleForTrustDomain returns the current bundle of the trust domain from the Workload API source.
+func (w *workloadSource) GetX509BundleForTrustDomain(td spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	source, err := w.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return source.GetX509BundleForTrustDomain(td)
+}
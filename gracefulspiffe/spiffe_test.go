@@ -0,0 +1,179 @@
+package gracefulspiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var trustDomain = spiffeid.RequireTrustDomainFromString("example.org")
+
+// staticSource serves a fixed SVID and the bundle of the trust domain.
+type staticSource struct {
+	*x509svid.SVID
+	*x509bundle.Bundle
+}
+
+// testCA issues the SVIDs of the trust domain.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.org CA"},
+		URIs:                  []*url.URL{trustDomain.ID().URL()},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+// source returns a source serving an SVID of the path issued by the CA.
+func (ca *testCA) source(t *testing.T, path string) *staticSource {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	id := spiffeid.RequireFromPath(trustDomain, path)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		URIs:         []*url.URL{id.URL()},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &staticSource{
+		SVID:   &x509svid.SVID{ID: id, Certificates: []*x509.Certificate{cert}, PrivateKey: key},
+		Bundle: x509bundle.FromX509Authorities(trustDomain, []*x509.Certificate{ca.cert}),
+	}
+}
+
+func TestWithSource(t *testing.T) {
+	ca := newTestCA(t)
+	serverID := spiffeid.RequireFromPath(trustDomain, "/server")
+	allowedID := spiffeid.RequireFromPath(trustDomain, "/allowed")
+
+	s := gracefulhttp.Bind("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := PeerID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = io.WriteString(w, id.String())
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithSource(ca.source(t, "/server"), tlsconfig.AuthorizeID(allowedID)))
+	}()
+	<-s.Ready()
+	url := "https://" + s.ListenerAddr().String()
+
+	t.Run("serve an authorized client", func(t *testing.T) {
+		client := ca.source(t, "/allowed")
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: tlsconfig.MTLSClientConfig(client, client, tlsconfig.AuthorizeID(serverID)),
+		}}
+		defer c.CloseIdleConnections()
+
+		resp, err := c.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, allowedID.String(), string(body))
+	})
+
+	t.Run("reject an unauthorized client", func(t *testing.T) {
+		client := ca.source(t, "/denied")
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: tlsconfig.MTLSClientConfig(client, client, tlsconfig.AuthorizeID(serverID)),
+		}}
+		defer c.CloseIdleConnections()
+
+		resp, err := c.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestWithWorkloadAPI(t *testing.T) {
+	s := gracefulhttp.Bind("127.0.0.1:0", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Without a Workload API to reach, the server never listens.
+	t.Setenv("SPIFFE_ENDPOINT_SOCKET", "unix:///nonexistent/agent.sock")
+	err := s.ListenAndServeTLSWithShutdown(ctx, "", "", WithWorkloadAPI(tlsconfig.AuthorizeAny()))
+
+	assert.Error(t, err)
+	assert.Nil(t, s.ListenerAddr())
+}
+
+func TestWorkloadSource_NotOpen(t *testing.T) {
+	var source workloadSource
+
+	_, err := source.GetX509SVID()
+	assert.ErrorIs(t, err, errSourceNotOpen)
+
+	_, err = source.GetX509BundleForTrustDomain(trustDomain)
+	assert.ErrorIs(t, err, errSourceNotOpen)
+	assert.NoError(t, source.close())
+}
+
+func TestPeerID(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+
+	_, err = PeerID(r)
+	assert.Error(t, err)
+}
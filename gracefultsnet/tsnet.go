@@ -0,0 +1,38 @@
+// Package gracefultsnet serves a [gracefulhttp.GracefulServer] on a tailnet node embedded through
+// tailscale.com/tsnet, shutting the node down once the server has drained.
+package gracefultsnet
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/aoliveti/gracefulhttp"
+)
+
+// Node is the subset of the methods of a *tsnet.Server used to listen on the tailnet and shut the node down.
+type Node interface {
+	Listen(network, addr string) (net.Listener, error)
+	Close() error
+}
+
+// ListenAndServe listens on the address of the server, ":80" if empty, on the tailnet node, and serves
+// the server on it until the context is canceled, as [gracefulhttp.GracefulServer.ServeWithShutdown] does.
+// The node is closed once the server has drained and its cleanup hooks have run, so that the in-flight
+// requests are answered over the tailnet, or right away if the server cannot be started.
+// Its failure to close is joined with the error of the server, if any.
+func ListenAndServe(ctx context.Context, s *gracefulhttp.GracefulServer, node Node, opts ...gracefulhttp.GracefulServerOption) error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":80"
+	}
+
+	ln, err := node.Listen("tcp", addr)
+	if err != nil {
+		return errors.Join(err, node.Close())
+	}
+
+	err = s.ServeWithShutdown(ctx, ln, opts...)
+
+	return errors.Join(err, node.Close())
+}
@@ -0,0 +1,85 @@
+package gracefultsnet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode listens on the loopback interface, recording whether the server was drained when it is closed.
+type fakeNode struct {
+	server       *gracefulhttp.GracefulServer
+	listenErr    error
+	addr         string
+	closed       bool
+	drainedFirst bool
+}
+
+func (n *fakeNode) Listen(_, addr string) (net.Listener, error) {
+	n.addr = addr
+	if n.listenErr != nil {
+		return nil, n.listenErr
+	}
+
+	return net.Listen("tcp", "127.0.0.1:0")
+}
+
+func (n *fakeNode) Close() error {
+	n.closed = true
+
+	select {
+	case <-n.server.Draining():
+		n.drainedFirst = n.server.ConnStats().Active == 0
+	default:
+	}
+
+	return nil
+}
+
+func TestListenAndServe(t *testing.T) {
+	t.Run("close the node after the drain", func(t *testing.T) {
+		s := gracefulhttp.Bind("", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "tailnet")
+		}))
+		node := &fakeNode{server: s}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- ListenAndServe(ctx, s, node)
+		}()
+		<-s.Ready()
+
+		resp, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, "tailnet", string(body))
+
+		cancel()
+		assert.NoError(t, <-errCh)
+		assert.Equal(t, ":80", node.addr)
+		assert.True(t, node.closed)
+		assert.True(t, node.drainedFirst)
+	})
+
+	t.Run("close the node if listening fails", func(t *testing.T) {
+		listenErr := errors.New("not logged in")
+		s := gracefulhttp.Bind(":443", nil)
+		node := &fakeNode{server: s, listenErr: listenErr}
+
+		err := ListenAndServe(context.Background(), s, node)
+
+		assert.ErrorIs(t, err, listenErr)
+		assert.Equal(t, ":443", node.addr)
+		assert.True(t, node.closed)
+	})
+}
@@ -0,0 +1,87 @@
+// Package gracefultunnel serves a [gracefulhttp.GracefulServer] on a listener provided by a tunnel,
+// such as an ngrok-go tunnel, draining the server before the tunnel is torn down.
+package gracefultunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/aoliveti/gracefulhttp"
+)
+
+// ServeWithShutdown serves the server on the tunnel until the context is canceled, as
+// [gracefulhttp.GracefulServer.ServeWithShutdown] does, then closes the tunnel.
+//
+// Closing the listener of a tunnel usually tears the whole tunnel down, along with the connections
+// it carries, while the shutdown of an [http.Server] closes its listeners before draining. The tunnel
+// is hence detached from the server: the shutdown only stops accepting its connections, and the tunnel
+// is closed once the server has drained and its cleanup hooks have run, or right away if the server
+// cannot be started. Its failure to close is joined with the error of the server, if any.
+func ServeWithShutdown(ctx context.Context, s *gracefulhttp.GracefulServer, tunnel net.Listener, opts ...gracefulhttp.GracefulServerOption) error {
+	err := s.ServeWithShutdown(ctx, detach(tunnel), opts...)
+
+	return errors.Join(err, tunnel.Close())
+}
+
+// detachedListener accepts the connections of a listener, but stops accepting them when closed
+// instead of closing the listener.
+type detachedListener struct {
+	net.Listener
+
+	done chan struct{}
+	once sync.Once
+}
+
+// detach returns a listener accepting the connections of ln until closed, without ever closing ln.
+func detach(ln net.Listener) *detachedListener {
+	return &detachedListener{
+		Listener: ln,
+		done:     make(chan struct{}),
+	}
+}
+
+// acceptResult is the outcome of an accept of the underlying listener.
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// Accept waits for the next connection of the underlying listener, until the listener is closed.
+// A connection accepted after the close is closed right away.
+func (l *detachedListener) Accept() (net.Conn, error) {
+	select {
+	case <-l.done:
+		return nil, net.ErrClosed
+	default:
+	}
+
+	accepted := make(chan acceptResult, 1)
+	go func() {
+		conn, err := l.Listener.Accept()
+		accepted <- acceptResult{conn: conn, err: err}
+	}()
+
+	select {
+	case r := <-accepted:
+		return r.conn, r.err
+	case <-l.done:
+		go func() {
+			if r := <-accepted; r.conn != nil {
+				_ = r.conn.Close()
+			}
+		}()
+
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting the connections, leaving the underlying listener open.
+func (l *detachedListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+
+	return nil
+}
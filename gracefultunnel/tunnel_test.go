@@ -0,0 +1,118 @@
+package gracefultunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aoliveti/gracefulhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTunnel is a loopback listener recording whether a request was in flight when it was closed.
+type fakeTunnel struct {
+	net.Listener
+
+	inFlight         *atomic.Int64
+	closed           atomic.Bool
+	closedWhileInUse atomic.Bool
+}
+
+func (t *fakeTunnel) Close() error {
+	t.closed.Store(true)
+	t.closedWhileInUse.Store(t.inFlight.Load() > 0)
+
+	return t.Listener.Close()
+}
+
+func newFakeTunnel(t *testing.T, inFlight *atomic.Int64) *fakeTunnel {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return &fakeTunnel{Listener: ln, inFlight: inFlight}
+}
+
+func TestServeWithShutdown(t *testing.T) {
+	t.Run("close the tunnel after the drain", func(t *testing.T) {
+		var inFlight atomic.Int64
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		s := gracefulhttp.Bind("", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			close(started)
+			<-release
+			_, _ = io.WriteString(w, "tunnel")
+		}))
+		tunnel := newFakeTunnel(t, &inFlight)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- ServeWithShutdown(ctx, s, tunnel, gracefulhttp.WithShutdownTimeout(5*time.Second))
+		}()
+		<-s.Ready()
+
+		respCh := make(chan string, 1)
+		go func() {
+			resp, err := http.Get("http://" + tunnel.Addr().String())
+			if err != nil {
+				respCh <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			respCh <- string(body)
+		}()
+
+		<-started
+		cancel()
+		<-s.Draining()
+		assert.False(t, tunnel.closed.Load())
+
+		close(release)
+		assert.Equal(t, "tunnel", <-respCh)
+		assert.NoError(t, <-errCh)
+		assert.True(t, tunnel.closed.Load())
+		assert.False(t, tunnel.closedWhileInUse.Load())
+	})
+
+	t.Run("close the tunnel if the server cannot be started", func(t *testing.T) {
+		warmupErr := errors.New("cold")
+		tunnel := newFakeTunnel(t, new(atomic.Int64))
+		s := gracefulhttp.Bind("", nil)
+
+		err := ServeWithShutdown(context.Background(), s, tunnel, gracefulhttp.WithWarmup(func(context.Context) error { return warmupErr }))
+
+		assert.ErrorIs(t, err, warmupErr)
+		assert.True(t, tunnel.closed.Load())
+	})
+}
+
+func TestDetachedListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	detached := detach(ln)
+	require.NoError(t, detached.Close())
+
+	_, err = detached.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	// The underlying listener is left open.
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_ = conn.Close()
+}
@@ -0,0 +1,7 @@
+// Package gracefulwinsvc runs a [github.com/aoliveti/gracefulhttp.GracefulServer] as a Windows service:
+// the SERVICE_STOP and SERVICE_SHUTDOWN control codes sent by the Service Control Manager start
+// the graceful shutdown, and the service is reported as stopped once it completes.
+//
+// Outside of the Service Control Manager, e.g. when started from a console or on other platforms,
+// the graceful shutdown starts on an interrupt or termination signal instead.
+package gracefulwinsvc
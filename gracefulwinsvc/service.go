@@ -0,0 +1,16 @@
+package gracefulwinsvc
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runInteractive runs serve, canceling the context passed to it on an interrupt or termination signal.
+func runInteractive(serve func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return serve(ctx)
+}
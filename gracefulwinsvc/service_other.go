@@ -0,0 +1,19 @@
+//go:build !windows
+
+package gracefulwinsvc
+
+import (
+	"context"
+)
+
+// Run runs serve, canceling the context passed to it on an interrupt or termination signal,
+// as there is no Service Control Manager outside of Windows, e.g.:
+//
+//	err := gracefulwinsvc.Run("myservice", func(ctx context.Context) error {
+//		return srv.ListenAndServeWithShutdown(ctx)
+//	})
+//
+// It returns the error of serve.
+func Run(_ string, serve func(ctx context.Context) error) error {
+	return runInteractive(serve)
+}
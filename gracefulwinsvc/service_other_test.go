@@ -0,0 +1,22 @@
+//go:build !windows
+
+package gracefulwinsvc
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runInteractive(t *testing.T) {
+	err := runInteractive(func(ctx context.Context) error {
+		assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+		<-ctx.Done()
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+}
@@ -0,0 +1,78 @@
+//go:build windows
+
+package gracefulwinsvc
+
+import (
+	"context"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// Run runs serve as the Windows service with the given name, canceling the context passed to serve
+// when the Service Control Manager stops the service or the system shuts down, e.g.:
+//
+//	err := gracefulwinsvc.Run("myservice", func(ctx context.Context) error {
+//		return srv.ListenAndServeWithShutdown(ctx)
+//	})
+//
+// It returns the error of serve. When not running as a service, it behaves as on the other platforms.
+func Run(name string, serve func(ctx context.Context) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+
+	if !isService {
+		return runInteractive(serve)
+	}
+
+	h := &handler{serve: serve}
+	if err := svc.Run(name, h); err != nil {
+		return err
+	}
+
+	return h.err
+}
+
+// handler maps the control codes of the Service Control Manager onto the graceful shutdown.
+type handler struct {
+	serve func(ctx context.Context) error
+	err   error
+}
+
+// Execute serves until the service is stopped, reporting its state to the Service Control Manager.
+func (h *handler) Execute(_ []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.serve(ctx)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		case h.err = <-done:
+			changes <- svc.Status{State: svc.StopPending}
+
+			if h.err != nil {
+				// A service-specific exit code reports the failure to the Service Control Manager.
+				return true, 1
+			}
+
+			return false, 0
+		}
+	}
+}
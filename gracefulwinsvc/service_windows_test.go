@@ -0,0 +1,43 @@
+package gracefulwinsvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/windows/svc"
+)
+
+func Test_handler_Execute(t *testing.T) {
+	tests := []struct {
+		name         string
+		cmd          svc.Cmd
+		serveErr     error
+		wantExitCode uint32
+	}{
+		{name: "stop", cmd: svc.Stop},
+		{name: "shutdown", cmd: svc.Shutdown},
+		{name: "serve failure", cmd: svc.Stop, serveErr: errors.New("listen failed"), wantExitCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{serve: func(ctx context.Context) error {
+				<-ctx.Done()
+				return tt.serveErr
+			}}
+
+			r := make(chan svc.ChangeRequest, 1)
+			changes := make(chan svc.Status, 4)
+			r <- svc.ChangeRequest{Cmd: tt.cmd}
+
+			_, exitCode := h.Execute(nil, r, changes)
+
+			assert.Equal(t, tt.wantExitCode, exitCode)
+			assert.ErrorIs(t, h.err, tt.serveErr)
+			assert.Equal(t, svc.StartPending, (<-changes).State)
+			assert.Equal(t, svc.Running, (<-changes).State)
+			assert.Equal(t, svc.StopPending, (<-changes).State)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package gracefulzap writes the diagnostics of a [gracefulhttp.GracefulServer] through a zap logger.
+// It is kept in a separate module to spare the dependency on zap to the other users of gracefulhttp.
+package gracefulzap
+
+import (
+	"github.com/aoliveti/gracefulhttp"
+	"go.uber.org/zap"
+)
+
+// New returns a [gracefulhttp.Logger] writing to the zap logger, to be set through [gracefulhttp.WithLogger]:
+//
+//	srv.ListenAndServeWithShutdown(ctx, gracefulhttp.WithLogger(gracefulzap.New(logger)))
+//
+// The arguments are written as the loosely typed key-value pairs of a [zap.SugaredLogger].
+func New(l *zap.Logger) gracefulhttp.Logger {
+	return logger{s: l.WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+// logger adapts a zap logger to the [gracefulhttp.Logger] interface.
+type logger struct {
+	s *zap.SugaredLogger
+}
+
+// Info writes an informational message.
+func (l logger) Info(msg string, args ...any) {
+	l.s.Infow(msg, args...)
+}
+
+// Warn writes a warning.
+func (l logger) Warn(msg string, args ...any) {
+	l.s.Warnw(msg, args...)
+}
+
+// Error writes an error message.
+func (l logger) Error(msg string, args ...any) {
+	l.s.Errorw(msg, args...)
+}
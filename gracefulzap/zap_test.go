@@ -0,0 +1,29 @@
+package gracefulzap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNew(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := New(zap.New(core))
+
+	logger.Info("gracefulhttp: draining", "requests", 2)
+	logger.Warn("gracefulhttp: registration failed", "attempt", 1)
+	logger.Error("gracefulhttp: restart failed", "pid", 42)
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		assert.Equal(t, "gracefulhttp: draining", entries[0].Message)
+		assert.Equal(t, map[string]any{"requests": int64(2)}, entries[0].ContextMap())
+		assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+		assert.Equal(t, zapcore.ErrorLevel, entries[2].Level)
+		assert.Equal(t, map[string]any{"pid": int64(42)}, entries[2].ContextMap())
+	}
+}
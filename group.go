@@ -0,0 +1,84 @@
+package gracefulhttp
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ServerGroup serves several servers together, e.g. a public API and its admin server, shutting them
+// all down once the context is canceled or one of them fails. Each member keeps its own graceful timeout
+// and drain policy, set by its options, e.g. 1s for the admin server and 30s for the public API, while
+// the group enforces a global ceiling: the members still draining once it expires are closed right away,
+// as by [GracefulServer.CloseNow].
+type ServerGroup struct {
+	ceiling time.Duration
+	clock   Clock
+	members []groupMember
+}
+
+// groupMember is a server of a [ServerGroup], with the function serving it.
+type groupMember struct {
+	server *GracefulServer
+	serve  func(ctx context.Context) error
+}
+
+// NewServerGroup returns an empty [ServerGroup] whose shutdown is bounded by the ceiling, if positive.
+func NewServerGroup(ceiling time.Duration) *ServerGroup {
+	return &ServerGroup{
+		ceiling: ceiling,
+		clock:   realClock{},
+	}
+}
+
+// Add adds a server to the group, served through [GracefulServer.ListenAndServeWithShutdown] with the options.
+func (g *ServerGroup) Add(s *GracefulServer, opts ...GracefulServerOption) {
+	g.AddFunc(s, func(ctx context.Context) error {
+		return s.ListenAndServeWithShutdown(ctx, opts...)
+	})
+}
+
+// AddFunc adds a server to the group, served by serve until the context is canceled,
+// e.g. through [GracefulServer.ListenAndServeTLSWithShutdown].
+func (g *ServerGroup) AddFunc(s *GracefulServer, serve func(ctx context.Context) error) {
+	g.members = append(g.members, groupMember{server: s, serve: serve})
+}
+
+// ServeWithShutdown serves all the members of the group until the context is canceled or one of them fails,
+// then shuts them down together, returning the first error, if any.
+func (g *ServerGroup) ServeWithShutdown(ctx context.Context) error {
+	eg, groupCtx := errgroup.WithContext(ctx)
+	for _, m := range g.members {
+		eg.Go(func() error {
+			return m.serve(groupCtx)
+		})
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	if g.ceiling > 0 {
+		go g.enforceCeiling(groupCtx, done)
+	}
+
+	return eg.Wait()
+}
+
+// enforceCeiling closes the members right away once the ceiling expires after the shutdown began,
+// unless the group is done beforehand.
+func (g *ServerGroup) enforceCeiling(ctx context.Context, done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	select {
+	case <-done:
+	case <-g.clock.After(g.ceiling):
+		for _, m := range g.members {
+			m.server.CloseNow()
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerGroup(t *testing.T) {
+	api := Bind("localhost:0", &delayedHandler{delay: 10 * time.Second})
+	admin := Bind("localhost:0", nil)
+
+	clock := newManualClock()
+	g := NewServerGroup(time.Hour)
+	g.clock = clock
+	g.Add(api, WithShutdownTimeout(time.Minute))
+	g.AddFunc(admin, func(ctx context.Context) error {
+		return admin.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Second))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- g.ServeWithShutdown(ctx)
+	}()
+	<-api.Ready()
+	<-admin.Ready()
+
+	go func() {
+		if r, err := http.Get("http://" + api.ListenerAddr().String()); err == nil {
+			_ = r.Body.Close()
+		}
+	}()
+	for api.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	for admin.State() != StateStopped {
+		time.Sleep(10 * time.Millisecond)
+	}
+	clock.fire()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ceiling not enforced")
+	}
+
+	assert.Equal(t, int64(1), api.Stats().ForcedShutdowns)
+	assert.Equal(t, int64(1), admin.Stats().GracefulShutdowns)
+}
+
+func TestServerGroup_MemberFailure(t *testing.T) {
+	taken := Bind("localhost:0", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = taken.ListenAndServeWithShutdown(ctx) }()
+	<-taken.Ready()
+
+	g := NewServerGroup(0)
+	g.Add(Bind("localhost:0", nil))
+	g.Add(Bind(taken.ListenerAddr().String(), nil))
+
+	err := g.ServeWithShutdown(context.Background())
+
+	var gerr *GracefulError
+	require.ErrorAs(t, err, &gerr)
+	assert.Equal(t, PhaseListen, gerr.Phase)
+}
@@ -0,0 +1,131 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hijackedConn is a connection taken over from the [http.Server] and registered
+// through [GracefulServer.TrackHijacked].
+type hijackedConn struct {
+	conn        net.Conn
+	closeFn     func(ctx context.Context) error
+	established time.Time
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// TrackHijacked registers a hijacked connection (e.g. a WebSocket) so that it takes part
+// in the graceful shutdown, since [http.Server.Shutdown] neither waits for nor closes
+// hijacked connections.
+//
+// When the shutdown begins, closeFn is invoked with a context bounded by the graceful
+// timeout, and should notify the peer (e.g. sending a WebSocket 1001 Going Away close frame);
+// once it returns the connection is closed. If closeFn is nil, the shutdown waits until
+// the returned untrack function is called. Connections still tracked when the graceful
+// timeout expires are forcibly closed.
+//
+// The returned untrack function must be called once the handler is done with the connection.
+func (s *GracefulServer) TrackHijacked(conn net.Conn, closeFn func(ctx context.Context) error) (untrack func()) {
+	hc := &hijackedConn{
+		conn:        conn,
+		closeFn:     closeFn,
+		established: s.now(),
+		done:        make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	if s.hijacked == nil {
+		s.hijacked = make(map[*hijackedConn]struct{})
+	}
+	s.hijacked[hc] = struct{}{}
+	s.mu.Unlock()
+
+	return func() {
+		s.untrackHijacked(hc)
+	}
+}
+
+// untrackHijacked removes the connection from the registry.
+func (s *GracefulServer) untrackHijacked(hc *hijackedConn) {
+	s.mu.Lock()
+	delete(s.hijacked, hc)
+	s.mu.Unlock()
+
+	hc.doneOnce.Do(func() {
+		close(hc.done)
+	})
+}
+
+// closeHijackedConn closes the connection and removes it from the registry.
+func (s *GracefulServer) closeHijackedConn(hc *hijackedConn) {
+	_ = hc.conn.Close()
+	s.untrackHijacked(hc)
+}
+
+// trackedHijacked returns a snapshot of the registered hijacked connections.
+func (s *GracefulServer) trackedHijacked() []*hijackedConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*hijackedConn, 0, len(s.hijacked))
+	for hc := range s.hijacked {
+		conns = append(conns, hc)
+	}
+
+	return conns
+}
+
+// shutdownHijacked notifies the registered hijacked connections and waits until
+// all of them are closed or untracked, or until the context is done.
+func (s *GracefulServer) shutdownHijacked(ctx context.Context) {
+	conns := s.trackedHijacked()
+
+	for _, hc := range conns {
+		if hc.closeFn == nil {
+			continue
+		}
+
+		go func(hc *hijackedConn) {
+			_ = hc.closeFn(ctx)
+			s.closeHijackedConn(hc)
+		}(hc)
+	}
+
+	for _, hc := range conns {
+		select {
+		case <-hc.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// hijackedInfos returns the details of the registered hijacked connections.
+func (s *GracefulServer) hijackedInfos() []ConnInfo {
+	now := s.now()
+	conns := s.trackedHijacked()
+
+	infos := make([]ConnInfo, 0, len(conns))
+	for _, hc := range conns {
+		infos = append(infos, ConnInfo{
+			RemoteAddr: hc.conn.RemoteAddr(),
+			State:      http.StateHijacked,
+			Age:        now.Sub(hc.established),
+			Conn:       hc.conn,
+		})
+	}
+
+	return infos
+}
+
+// closeHijacked forcibly closes all the registered hijacked connections.
+func (s *GracefulServer) closeHijacked() {
+	for _, hc := range s.trackedHijacked() {
+		s.closeHijackedConn(hc)
+	}
+}
@@ -0,0 +1,91 @@
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_TrackHijacked(t *testing.T) {
+	t.Run("notify and close on shutdown", func(t *testing.T) {
+		s := Bind("", nil)
+		s.initialize([]GracefulServerOption{WithShutdownTimeout(time.Second)})
+
+		server, client := net.Pipe()
+		defer client.Close()
+
+		notified := make(chan struct{})
+		s.TrackHijacked(server, func(ctx context.Context) error {
+			close(notified)
+			return nil
+		})
+
+		require.NoError(t, s.shutdown())
+
+		<-notified
+		_, err := server.Write([]byte("x"))
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	})
+
+	t.Run("wait for untrack without close function", func(t *testing.T) {
+		s := Bind("", nil)
+		s.initialize([]GracefulServerOption{WithShutdownTimeout(time.Second)})
+
+		server, client := net.Pipe()
+		defer client.Close()
+
+		untrack := s.TrackHijacked(server, nil)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.shutdown()
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("shutdown completed before untrack")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		untrack()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("forcefully close after a timeout", func(t *testing.T) {
+		s := Bind("", nil)
+		s.initialize([]GracefulServerOption{WithShutdownTimeout(100 * time.Millisecond)})
+
+		server, client := net.Pipe()
+		defer client.Close()
+
+		s.TrackHijacked(server, nil)
+
+		require.NoError(t, s.shutdown())
+
+		_, err := server.Write([]byte("x"))
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+		assert.Empty(t, s.trackedHijacked())
+	})
+}
+
+func TestGracefulServer_hijackedInfos(t *testing.T) {
+	clock := &steppedClock{now: time.Unix(0, 0)}
+	s := Bind("", nil)
+	s.clock = clock
+
+	c, peer := net.Pipe()
+	defer c.Close()
+	defer peer.Close()
+
+	defer s.TrackHijacked(c, nil)()
+	clock.advance(time.Minute)
+
+	infos := s.hijackedInfos()
+	require.Len(t, infos, 1)
+	assert.Equal(t, time.Minute, infos[0].Age)
+}
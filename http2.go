@@ -0,0 +1,153 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// initializeHTTP2 configures the HTTP/2 server tuned by the options, if any, and enables
+// cleartext HTTP/2 connections, if enabled by the options.
+func (s *GracefulServer) initializeHTTP2() error {
+	if s.h2Server != nil {
+		if err := s.configureHTTP2(s.h2Server); err != nil {
+			return err
+		}
+	}
+
+	if !s.h2c {
+		return nil
+	}
+
+	h2s := s.h2Server
+	if h2s == nil {
+		h2s = &http2.Server{}
+		if err := s.hookHTTP2Shutdown(h2s); err != nil {
+			return err
+		}
+	}
+
+	s.Handler = s.h2cHandler(s.Handler, h2s)
+
+	return nil
+}
+
+// http2Server lazily creates the HTTP/2 server tuned by the options.
+func (s *GracefulServer) http2Server() *http2.Server {
+	if s.h2Server == nil {
+		s.h2Server = &http2.Server{}
+	}
+
+	return s.h2Server
+}
+
+// configureHTTP2 serves the HTTP/2 connections, over TLS or unencrypted, with the HTTP/2 server,
+// which receives a GOAWAY frame as soon as the shutdown begins. [http2.ConfigureServer] patches
+// a copy of the TLS configuration, and HTTP/2 over TLS is kept disabled if it was.
+func (s *GracefulServer) configureHTTP2(h2s *http2.Server) error {
+	_, hasH2 := s.TLSNextProto[http2.NextProtoTLS]
+	disabled := s.TLSNextProto != nil && !hasH2
+
+	cfg := s.ownTLSConfig()
+	nextProtos := cfg.NextProtos
+
+	if err := http2.ConfigureServer(&s.Server, h2s); err != nil {
+		return err
+	}
+
+	if disabled {
+		delete(s.TLSNextProto, http2.NextProtoTLS)
+		cfg.NextProtos = nextProtos
+	}
+
+	if s.h2GoAwayLead > 0 {
+		return s.hookGoAway(h2s)
+	}
+
+	return nil
+}
+
+// hookGoAway moves the HTTP/2 connections to a server that is never started, so that shutting it down
+// sends them a GOAWAY frame ahead of the shutdown of the embedded [http.Server], which in turn sends it
+// to the connections established in the meantime.
+func (s *GracefulServer) hookGoAway(h2s *http2.Server) error {
+	var goAway http.Server
+	if err := http2.ConfigureServer(&goAway, h2s); err != nil {
+		return err
+	}
+
+	s.h2GoAway = func() {
+		_ = goAway.Shutdown(context.Background())
+	}
+	s.RegisterOnShutdown(s.h2GoAway)
+
+	return nil
+}
+
+// goAwayFirst sends a GOAWAY frame to the HTTP/2 connections, then waits for the lead time
+// before the drain begins, unless the context is done.
+func (s *GracefulServer) goAwayFirst(ctx context.Context) {
+	s.h2GoAway()
+
+	select {
+	case <-s.clock.After(s.h2GoAwayLead):
+	case <-ctx.Done():
+	}
+}
+
+// hookHTTP2Shutdown hooks the HTTP/2 server into the shutdown of the embedded [http.Server],
+// so that its connections receive a GOAWAY frame as soon as the shutdown begins.
+// The TLS settings patched by [http2.ConfigureServer] are restored, leaving HTTP/2 over TLS
+// to the embedded server.
+func (s *GracefulServer) hookHTTP2Shutdown(h2s *http2.Server) error {
+	tlsConfig, tlsNextProto := s.TLSConfig, s.TLSNextProto
+	defer func() {
+		s.TLSConfig, s.TLSNextProto = tlsConfig, tlsNextProto
+	}()
+
+	s.TLSConfig, s.TLSNextProto = nil, nil
+
+	return http2.ConfigureServer(&s.Server, h2s)
+}
+
+// h2cHandler serves cleartext HTTP/2 connections, upgraded or with prior knowledge.
+// The connections are hijacked from the embedded [http.Server], so they are tracked
+// to let the graceful shutdown wait for them to be closed.
+func (s *GracefulServer) h2cHandler(next http.Handler, h2s *http2.Server) http.Handler {
+	h := h2c.NewHandler(next, h2s)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(&responseWriter{
+			ResponseWriter: w,
+			afterHijack:    s.trackH2CConn,
+		}, r)
+	})
+}
+
+// trackH2CConn registers the hijacked connection, and untracks it once closed.
+func (s *GracefulServer) trackH2CConn(conn net.Conn) net.Conn {
+	c := &untrackingConn{Conn: conn}
+	c.untrack = s.TrackHijacked(conn, nil)
+
+	return c
+}
+
+// untrackingConn is a tracked hijacked connection that is untracked once closed.
+type untrackingConn struct {
+	net.Conn
+
+	untrack func()
+	once    sync.Once
+}
+
+// Close closes the connection and removes it from the tracked ones.
+func (c *untrackingConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.untrack)
+
+	return err
+}
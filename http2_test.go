@@ -0,0 +1,273 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+// newH2CClient returns a client speaking cleartext HTTP/2 with prior knowledge.
+func newH2CClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+func TestWithH2C(t *testing.T) {
+	t.Run("serve cleartext http2", func(t *testing.T) {
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.Proto)
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithH2C())
+		}()
+
+		<-s.Ready()
+
+		r, err := newH2CClient().Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+		assert.Equal(t, "HTTP/2.0", string(body))
+
+		cancel()
+
+		require.NoError(t, <-done)
+		assert.Empty(t, s.trackedHijacked())
+	})
+
+	t.Run("wait for in-flight requests", func(t *testing.T) {
+		started := make(chan struct{})
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			_, _ = io.WriteString(w, "done")
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithH2C())
+		}()
+
+		<-s.Ready()
+
+		result := make(chan error, 1)
+		go func() {
+			r, err := newH2CClient().Get("http://" + s.ListenerAddr().String())
+			if err == nil {
+				_, err = io.ReadAll(r.Body)
+				_ = r.Body.Close()
+			}
+			result <- err
+		}()
+
+		<-started
+		cancel()
+
+		require.NoError(t, <-result)
+		require.NoError(t, <-done)
+		assert.Empty(t, s.trackedHijacked())
+	})
+
+	t.Run("forcefully close after a timeout", func(t *testing.T) {
+		started := make(chan struct{})
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+		}))
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithH2C(), WithClock(clock))
+		}()
+
+		<-s.Ready()
+
+		result := make(chan error, 1)
+		go func() {
+			r, err := newH2CClient().Get("http://" + s.ListenerAddr().String())
+			if err == nil {
+				_, err = io.ReadAll(r.Body)
+				_ = r.Body.Close()
+			}
+			result <- err
+		}()
+
+		<-started
+		cancel()
+		clock.fire()
+
+		require.NoError(t, <-done)
+		assert.Error(t, <-result)
+	})
+}
+
+func TestWithProtocols_UnencryptedHTTP2(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithProtocols(true, true, true))
+	}()
+
+	<-s.Ready()
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	r, err := client.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestWithHTTP2Options(t *testing.T) {
+	s := GracefulServer{}
+	WithHTTP2MaxConcurrentStreams(10)(&s)
+	WithHTTP2IdleTimeout(time.Minute)(&s)
+	WithHTTP2ReadIdleTimeout(30 * time.Second)(&s)
+	WithHTTP2PingTimeout(5 * time.Second)(&s)
+
+	require.NotNil(t, s.h2Server)
+	assert.Equal(t, uint32(10), s.h2Server.MaxConcurrentStreams)
+	assert.Equal(t, time.Minute, s.h2Server.IdleTimeout)
+	assert.Equal(t, 30*time.Second, s.h2Server.ReadIdleTimeout)
+	assert.Equal(t, 5*time.Second, s.h2Server.PingTimeout)
+}
+
+func TestGracefulServer_configureHTTP2(t *testing.T) {
+	t.Run("serve http2 over tls", func(t *testing.T) {
+		shared := &tls.Config{}
+
+		s := Bind("localhost:0", nil)
+		require.NoError(t, s.initialize([]GracefulServerOption{WithTLSConfig(shared), WithHTTP2MaxConcurrentStreams(1)}))
+
+		assert.Contains(t, s.TLSNextProto, http2.NextProtoTLS)
+		assert.Contains(t, s.TLSConfig.NextProtos, http2.NextProtoTLS)
+		assert.Empty(t, shared.NextProtos)
+	})
+
+	t.Run("keep http2 over tls disabled", func(t *testing.T) {
+		s := Bind("localhost:0", nil)
+		require.NoError(t, s.initialize([]GracefulServerOption{WithALPN("http/1.1"), WithHTTP2MaxConcurrentStreams(1)}))
+
+		assert.NotContains(t, s.TLSNextProto, http2.NextProtoTLS)
+		assert.Equal(t, []string{"http/1.1"}, s.TLSConfig.NextProtos)
+	})
+}
+
+func TestWithHTTP2MaxConcurrentStreams(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithKeyPairPEM(certPEM, keyPEM), WithHTTP2MaxConcurrentStreams(1))
+	}()
+
+	<-s.Ready()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	conn, err := tls.Dial("tcp", s.ListenerAddr().String(), &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		NextProtos: []string{http2.NextProtoTLS},
+	})
+	require.NoError(t, err)
+
+	cc, err := (&http2.Transport{}).NewClientConn(conn)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "https://localhost/", nil)
+	require.NoError(t, err)
+
+	r, err := cc.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+	assert.Equal(t, 2, r.ProtoMajor)
+	assert.Equal(t, uint32(1), cc.State().MaxConcurrentStreams)
+	require.NoError(t, cc.Close())
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestWithHTTP2GoAwayLead(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithH2C(), WithHTTP2GoAwayLead(time.Second))
+	}()
+
+	<-s.Ready()
+
+	client := newH2CClient()
+	get := func() {
+		r, err := client.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, r.Body)
+		require.NoError(t, r.Body.Close())
+	}
+
+	get()
+	assert.Equal(t, 1, s.ConnStats().Hijacked)
+
+	cancel()
+	<-s.Draining()
+
+	// Once the GOAWAY frame is received, the client opens a new connection,
+	// which is still accepted during the lead time.
+	for s.ConnStats().Hijacked < 2 {
+		get()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.NoError(t, <-done)
+}
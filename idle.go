@@ -0,0 +1,74 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrIdleShutdown is the cause of the shutdown started by [WithIdleShutdown], returned by the serving
+// methods when [WithReturnCause] is set.
+var ErrIdleShutdown = errors.New("gracefulhttp: shutdown after idle period")
+
+// WithIdleShutdown starts the graceful shutdown once no request has been served for the duration,
+// e.g. for scale-to-zero and spot deployments, where the process should exit when unused.
+// The requests in flight keep the server busy until they complete, while the readiness probes
+// served through [WithReadinessPath] and the profiles served through [WithPprof] do not count.
+func WithIdleShutdown(d time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if d < 0 {
+			s.invalidOption("WithIdleShutdown", "negative duration %v", d)
+		}
+
+		s.idleShutdown = max(d, 0)
+	}
+}
+
+// initializeIdleShutdown starts the shutdown once the server has been idle for the duration, if any.
+func (s *GracefulServer) initializeIdleShutdown() {
+	if s.idleShutdown <= 0 {
+		return
+	}
+
+	s.background = append(s.background, s.watchIdle)
+}
+
+// idleHandler records the activity of the requests.
+func (s *GracefulServer) idleHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.busyRequests.Add(1)
+		defer func() {
+			s.lastRequest.Store(s.clock.Now().UnixNano())
+			s.busyRequests.Add(-1)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// watchIdle starts the shutdown once no request has been served for the idle duration,
+// until the context is done.
+func (s *GracefulServer) watchIdle(ctx context.Context) {
+	s.lastRequest.Store(s.clock.Now().UnixNano())
+	wait := s.idleShutdown
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(wait):
+		}
+
+		idle := s.clock.Now().Sub(time.Unix(0, s.lastRequest.Load()))
+		if s.busyRequests.Load() == 0 && idle >= s.idleShutdown {
+			s.cancelServing(ErrIdleShutdown)
+			return
+		}
+
+		wait = max(s.idleShutdown-idle, time.Millisecond)
+		if s.busyRequests.Load() > 0 {
+			wait = s.idleShutdown
+		}
+	}
+}
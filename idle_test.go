@@ -0,0 +1,53 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIdleShutdown(t *testing.T) {
+	// The request outlasts the idle duration, but keeps the server busy.
+	s := Bind("localhost:0", &delayedHandler{delay: 300 * time.Millisecond})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(context.Background(), WithIdleShutdown(200*time.Millisecond),
+			WithReadinessPath("/readyz"), WithReturnCause())
+	}()
+	<-s.Ready()
+	addr := "http://" + s.ListenerAddr().String()
+
+	for range 3 {
+		r, err := http.Get(addr)
+		require.NoError(t, err)
+		_ = r.Body.Close()
+	}
+
+	// The readiness probes do not keep the server busy.
+	start := time.Now()
+	go func() {
+		for {
+			r, err := http.Get(addr + "/readyz")
+			if err != nil {
+				return
+			}
+			_ = r.Body.Close()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrIdleShutdown)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle server not shut down")
+	}
+
+	assert.ErrorIs(t, ValidateOptions(WithIdleShutdown(-time.Second)), ErrInvalidOption)
+}
@@ -0,0 +1,109 @@
+package gracefulhttp
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// WithMaxConnsPerIP caps at n the simultaneous connections from a single remote IP, across the listeners,
+// closing the connections beyond the limit as soon as they are accepted, a mitigation for the clients
+// exhausting the connections. The connections without an IP remote address, e.g. over Unix sockets,
+// are not limited. A non-positive n means no limit.
+func WithMaxConnsPerIP(n int) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if n < 0 {
+			s.invalidOption("WithMaxConnsPerIP", "negative limit %d", n)
+		}
+
+		if n <= 0 {
+			s.ipConns = nil
+			return
+		}
+
+		s.ipConns = &ipConnCounter{limit: n, conns: make(map[netip.Addr]int)}
+	}
+}
+
+// ipConnCounter counts the open connections of each remote IP.
+type ipConnCounter struct {
+	limit int
+
+	mu    sync.Mutex
+	conns map[netip.Addr]int
+}
+
+// acquire counts a connection from the IP, reporting false if the limit is reached.
+func (c *ipConnCounter) acquire(ip netip.Addr) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns[ip] >= c.limit {
+		return false
+	}
+
+	c.conns[ip]++
+
+	return true
+}
+
+// release uncounts a connection from the IP.
+func (c *ipConnCounter) release(ip netip.Addr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns[ip]--; c.conns[ip] <= 0 {
+		delete(c.conns, ip)
+	}
+}
+
+// ipConnsListener closes the accepted connections from the remote IPs which reached the limit.
+type ipConnsListener struct {
+	net.Listener
+
+	counter *ipConnCounter
+}
+
+// Accept accepts the next connection whose remote IP is below the limit, closing the others.
+func (l *ipConnsListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ip, ok := remoteIP(c.RemoteAddr())
+		if !ok {
+			return c, nil
+		}
+
+		if l.counter.acquire(ip) {
+			return &ipConn{Conn: c, ip: ip, counter: l.counter}, nil
+		}
+
+		_ = c.Close()
+	}
+}
+
+// ipConn uncounts itself once closed.
+type ipConn struct {
+	net.Conn
+
+	ip        netip.Addr
+	counter   *ipConnCounter
+	closeOnce sync.Once
+}
+
+// Close closes the connection, uncounting it.
+func (c *ipConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.counter.release(c.ip)
+	})
+
+	return c.Conn.Close()
+}
+
+// NetConn returns the wrapped connection.
+func (c *ipConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,70 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxConnsPerIP(t *testing.T) {
+	s := Bind("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithMaxConnsPerIP(1))
+	}()
+	<-s.Ready()
+
+	url := "http://" + s.ListenerAddr().String()
+
+	// The first connection is kept open by the transport.
+	first := &http.Transport{}
+	r, err := (&http.Client{Transport: first}).Get(url)
+	require.NoError(t, err)
+	_ = r.Body.Close()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	_, err = client.Get(url)
+	assert.Error(t, err)
+
+	// Once the first connection is closed, the IP is below the limit again.
+	first.CloseIdleConnections()
+	assert.Eventually(t, func() bool {
+		r, err := client.Get(url)
+		if err != nil {
+			return false
+		}
+		_ = r.Body.Close()
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.ErrorIs(t, ValidateOptions(WithMaxConnsPerIP(-1)), ErrInvalidOption)
+}
+
+func Test_ipConn_Close(t *testing.T) {
+	counter := &ipConnCounter{limit: 1, conns: make(map[netip.Addr]int)}
+	ip := netip.MustParseAddr("192.0.2.1")
+	require.True(t, counter.acquire(ip))
+	require.False(t, counter.acquire(ip))
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	c := &ipConn{Conn: server, ip: ip, counter: counter}
+	require.NoError(t, c.Close())
+	_ = c.Close()
+
+	assert.Empty(t, counter.conns)
+	assert.True(t, counter.acquire(ip))
+}
@@ -0,0 +1,111 @@
+package gracefulhttp
+
+import (
+	"net"
+	"net/netip"
+)
+
+// WithIPFilter drops the connections whose remote address is in one of the deny prefixes or, when allow
+// is not empty, in none of the allow prefixes, right after they are accepted: before the TLS handshake,
+// the HTTP parsing and the other accept policies, so that unwanted sources cost as little as possible.
+// Deny takes precedence over allow; IPv4-mapped IPv6 addresses are matched as IPv4. The connections
+// without an IP remote address, e.g. over Unix sockets, are not filtered.
+func WithIPFilter(allow, deny []netip.Prefix) GracefulServerOption {
+	return func(s *GracefulServer) {
+		for _, p := range append(append([]netip.Prefix(nil), allow...), deny...) {
+			if !p.IsValid() {
+				s.invalidOption("WithIPFilter", "invalid prefix %v", p)
+			}
+		}
+
+		s.ipAllow, s.ipDeny = maskPrefixes(allow), maskPrefixes(deny)
+	}
+}
+
+// maskPrefixes returns the valid prefixes in canonical form, IPv4-mapped IPv6 ones as IPv4.
+func maskPrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	masked := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if !p.IsValid() {
+			continue
+		}
+
+		if addr := p.Addr(); addr.Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(addr.Unmap(), p.Bits()-96)
+		}
+
+		masked = append(masked, p.Masked())
+	}
+
+	return masked
+}
+
+// ipFilterListener drops the accepted connections whose remote address is not allowed.
+type ipFilterListener struct {
+	net.Listener
+
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// Accept accepts the next connection whose remote address is allowed, closing the others.
+func (l *ipFilterListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if l.allowed(c.RemoteAddr()) {
+			return c, nil
+		}
+
+		_ = c.Close()
+	}
+}
+
+// allowed reports whether the address passes the filter.
+func (l *ipFilterListener) allowed(addr net.Addr) bool {
+	ip, ok := remoteIP(addr)
+	if !ok {
+		return true
+	}
+
+	if containsAddr(l.deny, ip) {
+		return false
+	}
+
+	return len(l.allow) == 0 || containsAddr(l.allow, ip)
+}
+
+// remoteIP returns the IP of the address, unmapped, if it has one.
+func remoteIP(addr net.Addr) (netip.Addr, bool) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.AddrPort().Addr().Unmap(), true
+	case *net.UDPAddr:
+		return a.AddrPort().Addr().Unmap(), true
+	}
+
+	if addr == nil {
+		return netip.Addr{}, false
+	}
+
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return ap.Addr().Unmap(), true
+}
+
+// containsAddr reports whether one of the prefixes contains the address.
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
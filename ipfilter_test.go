@@ -0,0 +1,70 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []netip.Prefix
+		deny    []netip.Prefix
+		allowed bool
+	}{
+		{name: "no filter", allowed: true},
+		{name: "allowed", allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}, allowed: true},
+		{name: "not allowed", allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, allowed: false},
+		{name: "denied", deny: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}, allowed: false},
+		{
+			name:    "deny precedence",
+			allow:   []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+			deny:    []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+			allowed: false,
+		},
+		{name: "mapped prefix", deny: []netip.Prefix{netip.MustParsePrefix("::ffff:127.0.0.1/128")}, allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- s.ListenAndServeWithShutdown(ctx, WithIPFilter(tt.allow, tt.deny))
+			}()
+			<-s.Ready()
+
+			client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+			r, err := client.Get("http://" + s.ListenerAddr().String())
+			if tt.allowed {
+				require.NoError(t, err)
+				_ = r.Body.Close()
+				assert.Equal(t, http.StatusOK, r.StatusCode)
+			} else {
+				assert.Error(t, err)
+			}
+
+			cancel()
+			require.NoError(t, <-done)
+		})
+	}
+
+	assert.ErrorIs(t, ValidateOptions(WithIPFilter([]netip.Prefix{{}}, nil)), ErrInvalidOption)
+}
+
+func Test_ipFilterListener_allowed(t *testing.T) {
+	l := &ipFilterListener{deny: maskPrefixes([]netip.Prefix{netip.MustParsePrefix("192.0.2.1/24")})}
+
+	assert.False(t, l.allowed(&net.TCPAddr{IP: net.ParseIP("192.0.2.200"), Port: 1}))
+	assert.False(t, l.allowed(&net.TCPAddr{IP: net.ParseIP("::ffff:192.0.2.7"), Port: 1}))
+	assert.True(t, l.allowed(&net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 1}))
+	assert.True(t, l.allowed(&net.UnixAddr{Name: "/tmp/socket", Net: "unix"}))
+}
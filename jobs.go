@@ -0,0 +1,100 @@
+package gracefulhttp
+
+import (
+	"context"
+	"sync"
+)
+
+// jobTracker counts the background jobs registered through [GracefulServer.Go] and [GracefulServer.TrackJob].
+type jobTracker struct {
+	mu      sync.Mutex
+	running int
+	// idle is closed once no job is running, if waited for.
+	idle chan struct{}
+	// ctx is the context of the jobs, canceled once the connections are forcibly closed.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Go runs fn in a goroutine that takes part in the graceful shutdown, e.g. the asynchronous processing of
+// an accepted request, so that the drain waits for it to return, within the graceful timeout, instead of
+// leaving it orphaned at the exit of the process. The context passed to fn is canceled once the graceful
+// timeout expires, when the connections are forcibly closed, and must not outlive the drain.
+func (s *GracefulServer) Go(fn func(ctx context.Context)) {
+	done := s.TrackJob()
+	ctx := s.jobs.context()
+
+	go func() {
+		defer done()
+		fn(ctx)
+	}()
+}
+
+// TrackJob registers a background job, e.g. running in a goroutine spawned by a handler, so that the drain
+// waits for it as for the requests in flight, within the graceful timeout, until the returned done function
+// is called. Calling done more than once has no further effect.
+func (s *GracefulServer) TrackJob() (done func()) {
+	s.jobs.mu.Lock()
+	s.jobs.running++
+	s.jobs.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(s.jobs.done)
+	}
+}
+
+// context returns the context of the jobs.
+func (t *jobTracker) context() context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+	}
+
+	return t.ctx
+}
+
+// done marks a job as completed.
+func (t *jobTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.running--
+	if t.running == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// wait waits until no job is running or the context is done.
+func (t *jobTracker) wait(ctx context.Context) {
+	t.mu.Lock()
+	if t.running == 0 {
+		t.mu.Unlock()
+		return
+	}
+
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+	}
+}
+
+// stop cancels the context of the jobs, if any.
+func (t *jobTracker) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
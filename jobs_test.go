@@ -0,0 +1,104 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_Go(t *testing.T) {
+	t.Run("the drain waits for the jobs", func(t *testing.T) {
+		release := make(chan struct{})
+		finished := make(chan struct{})
+
+		var s *GracefulServer
+		s = Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.Go(func(ctx context.Context) {
+				defer close(finished)
+				<-release
+			})
+			w.WriteHeader(http.StatusAccepted)
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx)
+		}()
+		<-s.Ready()
+
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+
+		cancel()
+
+		select {
+		case <-done:
+			t.Fatal("drain not waiting for the job")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(release)
+		require.NoError(t, <-done)
+		<-finished
+		assert.Zero(t, s.Stats().ForcedShutdowns)
+	})
+
+	t.Run("the jobs are canceled once the graceful timeout expires", func(t *testing.T) {
+		s := Bind("localhost:0", nil)
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock))
+		}()
+		<-s.Ready()
+
+		canceled := make(chan struct{})
+		s.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			close(canceled)
+		})
+		stuck := s.TrackJob()
+		defer stuck()
+
+		cancel()
+		clock.fire()
+
+		require.NoError(t, <-done)
+		<-canceled
+		assert.Equal(t, int64(1), s.Stats().ForcedShutdowns)
+	})
+}
+
+func TestGracefulServer_TrackJob(t *testing.T) {
+	s := Bind("localhost:0", nil)
+
+	done := s.TrackJob()
+
+	waited := make(chan struct{})
+	go func() {
+		defer close(waited)
+		s.jobs.wait(context.Background())
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("job not waited for")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	done()
+	done()
+	<-waited
+
+	s.jobs.wait(context.Background())
+}
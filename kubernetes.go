@@ -0,0 +1,83 @@
+package gracefulhttp
+
+import (
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// kubernetesReadinessPath is the path of the readiness endpoint served by [WithKubernetesDefaults].
+	kubernetesReadinessPath = "/readyz"
+	// kubernetesShutdownDelay leaves time to the endpoint controllers and the kube-proxies to remove
+	// the pod from the Service endpoints, before the server stops accepting connections.
+	kubernetesShutdownDelay = 5 * time.Second
+	// kubernetesGracefulTimeout keeps the shutdown delay and the drain within the 30 seconds of the
+	// default terminationGracePeriodSeconds, before the pod is killed.
+	kubernetesGracefulTimeout = 20 * time.Second
+)
+
+// WithKubernetesDefaults bundles the behavior expected from a server running in a Kubernetes pod:
+// the readiness is served on /readyz, failing once the drain begins, a SIGTERM starts the shutdown,
+// which is delayed by 5 seconds to let the pod be removed from the Service endpoints, and the graceful
+// timeout is set to 20 seconds, so that the shutdown completes within the default terminationGracePeriodSeconds.
+// Apply the options after it to tune any of these settings.
+func WithKubernetesDefaults() GracefulServerOption {
+	return func(s *GracefulServer) {
+		WithReadinessPath(kubernetesReadinessPath)(s)
+		WithShutdownDelay(kubernetesShutdownDelay)(s)
+		WithShutdownSignals(syscall.SIGTERM)(s)
+		s.gracefulTimeout = kubernetesGracefulTimeout
+	}
+}
+
+// WithReadinessPath serves [GracefulServer.ReadinessHandler] on the path, ahead of the handler and of the
+// drain rejection, so that the readiness probes can tell the server is draining.
+func WithReadinessPath(path string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.readinessPath = path
+	}
+}
+
+// WithShutdownDelay delays the shutdown once the serving context is canceled: the server is marked as draining
+// right away, failing the readiness, but keeps accepting connections and serving the requests, unless
+// [WithDrainRejection] is set, until the delay expires. It lets the load balancers stop routing traffic to
+// the server before it stops listening. The delay is not part of the graceful timeout.
+func WithShutdownDelay(delay time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if delay < 0 {
+			s.invalidOption("WithShutdownDelay", "negative duration %v", delay)
+		}
+
+		s.shutdownDelay = max(delay, 0)
+	}
+}
+
+// WithShutdownSignals starts the graceful shutdown when one of the signals (SIGTERM and SIGINT by default)
+// is received, as if the serving context were canceled. Once the first signal is received, the default
+// behavior of the signals is restored, so that a second one terminates the process.
+func WithShutdownSignals(sig ...os.Signal) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if len(sig) == 0 {
+			s.shutdownSignals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+			return
+		}
+
+		s.shutdownSignals = sig
+	}
+}
+
+// readinessPathHandler serves the readiness on its path, and any other request through next.
+func (s *GracefulServer) readinessPathHandler(next http.Handler) http.Handler {
+	readiness := s.ReadinessHandler()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != s.readinessPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		readiness.ServeHTTP(w, r)
+	})
+}
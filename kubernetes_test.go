@@ -0,0 +1,76 @@
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithKubernetesDefaults(t *testing.T) {
+	s := GracefulServer{}
+	WithKubernetesDefaults()(&s)
+
+	assert.Equal(t, "/readyz", s.readinessPath)
+	assert.Equal(t, []os.Signal{syscall.SIGTERM}, s.shutdownSignals)
+	assert.Less(t, s.shutdownDelay+s.gracefulTimeout, 30*time.Second)
+}
+
+func TestWithReadinessPath(t *testing.T) {
+	s := Bind("", &delayedHandler{})
+	require.NoError(t, s.initialize([]GracefulServerOption{WithDrainRejection(time.Second), WithReadinessPath("/ready")}))
+	s.markReady()
+
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	s.beginDrain()
+
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "draining")
+}
+
+func TestWithShutdownDelay(t *testing.T) {
+	clock := newManualClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithShutdownDelay(5*time.Second), WithReadinessPath("/readyz"))
+	}()
+	<-s.Ready()
+	url := "http://" + s.ListenerAddr().String()
+
+	cancel()
+	<-s.Draining()
+
+	resp, err := http.Get(url + "/readyz")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = http.Get(url + "/")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	clock.fire()
+	assert.NoError(t, <-errCh)
+}
+
+func TestWithShutdownDelay_Negative(t *testing.T) {
+	assert.ErrorIs(t, ValidateOptions(WithShutdownDelay(-time.Second)), ErrInvalidOption)
+}
@@ -0,0 +1,197 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/netutil"
+	"golang.org/x/time/rate"
+)
+
+// wrapListener decorates the listener with the accept policies enabled by the options,
+// then with the wrappers provided through [WithListenerWrapper].
+func (s *GracefulServer) wrapListener(ln net.Listener) net.Listener {
+	if len(s.ipAllow) > 0 || len(s.ipDeny) > 0 {
+		// Applied first, so that the dropped connections take no accept token nor connection slot.
+		ln = &ipFilterListener{Listener: ln, allow: s.ipAllow, deny: s.ipDeny}
+	}
+
+	if s.ipConns != nil {
+		ln = &ipConnsListener{Listener: ln, counter: s.ipConns}
+	}
+
+	if s.acceptLimiter != nil {
+		ln = newRateLimitListener(ln, s.acceptLimiter)
+	}
+
+	if s.maxConns > 0 {
+		ln = netutil.LimitListener(ln, s.maxConns)
+	}
+
+	for _, wrap := range s.listenerWrappers {
+		ln = wrap(ln)
+	}
+
+	if s.trackBytes {
+		// Applied after the user wrappers, so that the connections can be found under the TLS layer.
+		ln = meteredListener{Listener: ln}
+	}
+
+	if s.minDataRate > 0 {
+		ln = &dataRateListener{Listener: ln, rate: s.minDataRate, grace: s.minDataRateGrace}
+	}
+
+	if s.bandwidthLimiter != nil {
+		// Applied after the minimum data rate, so that the time spent waiting for the tokens is not measured.
+		ln = &bandwidthListener{Listener: ln, limiter: s.bandwidthLimiter}
+	}
+
+	if s.handshakeTimeout > 0 {
+		// Applied after the user wrappers, so that the connections can be found under the TLS layer.
+		ln = &handshakeTimeoutListener{Listener: ln, timeout: s.handshakeTimeout}
+	}
+
+	if len(s.passthroughRoutes) > 0 {
+		// Applied last, so that it reads the TLS ClientHello right under the TLS layer.
+		ln = s.newPassthroughListener(ln)
+	}
+
+	return ln
+}
+
+// rateLimitListener throttles the accepted connections through a token bucket.
+type rateLimitListener struct {
+	net.Listener
+
+	limiter *rate.Limiter
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// newRateLimitListener returns a listener throttled by the limiter, which may be shared.
+func newRateLimitListener(ln net.Listener, limiter *rate.Limiter) *rateLimitListener {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &rateLimitListener{
+		Listener: ln,
+		limiter:  limiter,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Accept waits for a token, then accepts the next connection.
+// Once the listener is closed, the wait is interrupted and the error of the listener is returned.
+func (l *rateLimitListener) Accept() (net.Conn, error) {
+	_ = l.limiter.Wait(l.ctx)
+
+	return l.Listener.Accept()
+}
+
+// Close interrupts the pending wait, if any, and closes the listener.
+func (l *rateLimitListener) Close() error {
+	l.cancel()
+
+	return l.Listener.Close()
+}
+
+// handshakeTimeoutListener bounds the time the accepted connections have to complete the TLS handshake
+// and send the headers of their first request.
+type handshakeTimeoutListener struct {
+	net.Listener
+
+	timeout time.Duration
+}
+
+// Accept accepts the next connection, setting its handshake deadline.
+func (l *handshakeTimeoutListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	hc := &handshakeConn{Conn: c, handshakeDeadline: time.Now().Add(l.timeout)}
+	_ = c.SetDeadline(hc.handshakeDeadline)
+
+	return hc, nil
+}
+
+// handshakeConn enforces a deadline until the connection serves its first request: the deadlines
+// set meanwhile are capped by it, then restored once the connection is established.
+type handshakeConn struct {
+	net.Conn
+
+	mu                sync.Mutex
+	handshakeDeadline time.Time
+	readDeadline      time.Time
+	writeDeadline     time.Time
+}
+
+// SetDeadline sets the read and write deadlines, capped by the handshake deadline until established.
+func (c *handshakeConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline, c.writeDeadline = t, t
+
+	return c.Conn.SetDeadline(earliest(t, c.handshakeDeadline))
+}
+
+// SetReadDeadline sets the read deadline, capped by the handshake deadline until established.
+func (c *handshakeConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+
+	return c.Conn.SetReadDeadline(earliest(t, c.handshakeDeadline))
+}
+
+// SetWriteDeadline sets the write deadline, capped by the handshake deadline until established.
+func (c *handshakeConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeDeadline = t
+
+	return c.Conn.SetWriteDeadline(earliest(t, c.handshakeDeadline))
+}
+
+// NetConn returns the wrapped connection.
+func (c *handshakeConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// established lifts the handshake deadline, restoring the deadlines last set.
+func (c *handshakeConn) established() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.handshakeDeadline.IsZero() {
+		return
+	}
+
+	c.handshakeDeadline = time.Time{}
+	_ = c.Conn.SetReadDeadline(c.readDeadline)
+	_ = c.Conn.SetWriteDeadline(c.writeDeadline)
+}
+
+// findConn returns the connection of type T wrapped by c, unwrapping the connections
+// through their NetConn method, as [tls.Conn] allows.
+func findConn[T net.Conn](c net.Conn) (T, bool) {
+	for {
+		if t, ok := c.(T); ok {
+			return t, true
+		}
+
+		wrapper, ok := c.(interface{ NetConn() net.Conn })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+
+		c = wrapper.NetConn()
+	}
+}
@@ -0,0 +1,180 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestWithMaxConnections(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithMaxConnections(1))
+	}()
+
+	<-s.Ready()
+
+	url := "http://" + s.ListenerAddr().String()
+	results := make(chan error, 2)
+	for range 2 {
+		go func() {
+			client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+			r, err := client.Get(url)
+			if err == nil {
+				err = r.Body.Close()
+			}
+			results <- err
+		}()
+	}
+
+	<-started
+
+	select {
+	case <-started:
+		t.Fatal("second connection accepted beyond the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	stats := s.ConnStats()
+	assert.Equal(t, 1, stats.Active)
+
+	close(release)
+	<-started
+
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_wrapListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := &GracefulServer{}
+	assert.Equal(t, ln, s.wrapListener(ln))
+
+	WithMaxConnections(10)(s)
+	assert.NotEqual(t, ln, s.wrapListener(ln))
+}
+
+func TestRateLimitListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	rl := newRateLimitListener(ln, rate.NewLimiter(rate.Every(time.Hour), 1))
+
+	for range 2 {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer c.Close()
+	}
+
+	c, err := rl.Accept()
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	accepted := make(chan error, 1)
+	go func() {
+		c, err := rl.Accept()
+		if err == nil {
+			_ = c.Close()
+		}
+		accepted <- err
+	}()
+
+	select {
+	case <-accepted:
+		t.Fatal("connection accepted beyond the rate")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, rl.Close())
+	assert.ErrorIs(t, <-accepted, net.ErrClosed)
+}
+
+func TestWithAcceptRateLimit(t *testing.T) {
+	s := &GracefulServer{}
+	WithAcceptRateLimit(10, 0)(s)
+	require.NotNil(t, s.acceptLimiter)
+	assert.Equal(t, rate.Limit(10), s.acceptLimiter.Limit())
+	assert.Equal(t, 1, s.acceptLimiter.Burst())
+
+	WithAcceptRateLimit(0, 5)(s)
+	assert.Nil(t, s.acceptLimiter)
+}
+
+func TestWithTLSHandshakeTimeout(t *testing.T) {
+	certFile, keyFile := testKeyPairFiles(t)
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, certFile, keyFile,
+			WithTLSHandshakeTimeout(100*time.Millisecond))
+	}()
+
+	<-s.Ready()
+	addr := s.ListenerAddr().String()
+
+	t.Run("stalled handshake", func(t *testing.T) {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		// The server would wait for the read header timeout without the handshake timeout.
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, err = conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("established connection", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+		defer client.CloseIdleConnections()
+
+		for range 2 {
+			resp, err := client.Get("https://" + addr)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, "ok", string(body))
+			assert.False(t, resp.Close)
+
+			// The kept-alive connection outlives the handshake timeout.
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		assert.Equal(t, int64(2), s.Stats().Accepted)
+	})
+
+	cancel()
+	require.NoError(t, <-done)
+}
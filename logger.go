@@ -0,0 +1,11 @@
+package gracefulhttp
+
+// Logger is the minimal interface through which the server reports its diagnostics, such as the requests still
+// in flight during the drain, the signals received and the errors of the embedded server. The arguments are
+// alternating keys and values. A [*log/slog.Logger] implements it, while the gracefullogr and gracefulzap
+// modules adapt the loggers of logr and zap, so that the diagnostics fit the logging stack of the application.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
@@ -0,0 +1,93 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"runtime/metrics"
+	"time"
+)
+
+// defaultMemoryCheckInterval is the default interval at which the memory is checked by [WithMemoryShutdown].
+const defaultMemoryCheckInterval = time.Second
+
+// ErrMemoryShutdown is the cause of the shutdown started by [WithMemoryShutdown], returned by the serving
+// methods when [WithReturnCause] is set.
+var ErrMemoryShutdown = errors.New("gracefulhttp: shutdown on memory pressure")
+
+// WithMemoryShutdown checks the memory used by the process at every interval, every second by default,
+// and starts the graceful shutdown once it exceeds the threshold, in bytes, so that a leaking instance
+// drains and restarts cleanly instead of being killed by the OOM killer in the middle of the requests.
+// The memory is read from the Go runtime, as accounted for by the memory limit of the garbage collector,
+// unless a probe is set with [WithMemoryProbe].
+func WithMemoryShutdown(threshold uint64, interval time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if interval < 0 {
+			s.invalidOption("WithMemoryShutdown", "negative interval %v", interval)
+		}
+
+		s.memoryThreshold = threshold
+		s.memoryInterval = max(interval, 0)
+	}
+}
+
+// WithMemoryProbe sets the probe returning the memory used, in bytes, checked by [WithMemoryShutdown],
+// e.g. reading the memory.current file of the cgroup of the container, which accounts for the page cache
+// and the memory allocated outside of the Go runtime as well.
+func WithMemoryProbe(probe func() uint64) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.memoryProbe = probe
+	}
+}
+
+// initializeMemoryShutdown starts the shutdown once the memory used exceeds the threshold, if any.
+func (s *GracefulServer) initializeMemoryShutdown() {
+	if s.memoryThreshold == 0 {
+		return
+	}
+
+	probe := s.memoryProbe
+	if probe == nil {
+		probe = runtimeMemory
+	}
+
+	s.background = append(s.background, func(ctx context.Context) {
+		s.watchMemory(ctx, probe)
+	})
+}
+
+// watchMemory starts the shutdown once the memory returned by the probe exceeds the threshold,
+// until the context is done.
+func (s *GracefulServer) watchMemory(ctx context.Context, probe func() uint64) {
+	interval := cmp.Or(s.memoryInterval, defaultMemoryCheckInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(interval):
+		}
+
+		if used := probe(); used > s.memoryThreshold {
+			if s.logger != nil {
+				s.logger.Warn("gracefulhttp: memory threshold exceeded, shutting down",
+					"used", used, "threshold", s.memoryThreshold)
+			}
+
+			s.cancelServing(ErrMemoryShutdown)
+			return
+		}
+	}
+}
+
+// runtimeMemory returns the memory mapped by the Go runtime and not released to the system,
+// as accounted for by the memory limit of the garbage collector.
+func runtimeMemory() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
@@ -0,0 +1,36 @@
+package gracefulhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMemoryShutdown(t *testing.T) {
+	used := make(chan uint64)
+
+	clock := newManualClock()
+	s := Bind("localhost:0", nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(context.Background(), WithClock(clock), WithReturnCause(),
+			WithMemoryShutdown(1000, time.Second), WithMemoryProbe(func() uint64 { return <-used }))
+	}()
+	<-s.Ready()
+
+	clock.fire()
+	used <- 1000
+	clock.fire()
+	assert.False(t, s.isDraining())
+	used <- 1001
+
+	assert.ErrorIs(t, <-done, ErrMemoryShutdown)
+	assert.ErrorIs(t, ValidateOptions(WithMemoryShutdown(1000, -time.Second)), ErrInvalidOption)
+}
+
+func Test_runtimeMemory(t *testing.T) {
+	assert.Positive(t, runtimeMemory())
+}
@@ -0,0 +1,144 @@
+package gracefulhttp
+
+import (
+	"expvar"
+	"net/http"
+	"time"
+)
+
+// Names of the metrics reported to a [MetricsRecorder].
+const (
+	// MetricRequests is the counter of the requests received, including the rejected ones.
+	MetricRequests = "requests"
+	// MetricRequestDuration is the histogram of the time spent serving the requests, in seconds.
+	MetricRequestDuration = "request_duration_seconds"
+	// MetricConnectionsAccepted is the counter of the connections accepted.
+	MetricConnectionsAccepted = "connections_accepted"
+	// MetricConnectionsOpen is the gauge of the connections currently open, hijacked ones excluded.
+	MetricConnectionsOpen = "connections_open"
+	// MetricDraining is the gauge set to 1 once the shutdown has begun.
+	MetricDraining = "draining"
+	// MetricDrainDuration is the histogram of the time spent shutting down the server, in seconds.
+	MetricDrainDuration = "drain_duration_seconds"
+	// MetricForcedCloses is the counter of the shutdowns which had to close the connections forcibly.
+	MetricForcedCloses = "forced_closes"
+	// MetricHandlerPanics is the counter of the panics recovered from the handler.
+	MetricHandlerPanics = "handler_panics"
+	// MetricShedRequests is the counter of the requests rejected in degraded mode.
+	MetricShedRequests = "shed_requests"
+)
+
+// MetricsRecorder receives the metrics of a [GracefulServer], so that they can be forwarded
+// to any monitoring system, such as StatsD, OpenCensus or Prometheus, through a small adapter.
+// The names of the metrics are the Metric constants. The methods may be invoked concurrently
+// and from the serving path of the requests, so they should not block.
+type MetricsRecorder interface {
+	// AddCounter increments the counter with the given name by delta.
+	AddCounter(name string, delta float64)
+	// SetGauge sets the gauge with the given name to value.
+	SetGauge(name string, value float64)
+	// ObserveHistogram records value in the histogram with the given name.
+	ObserveHistogram(name string, value float64)
+}
+
+// MetricsRecorderFuncs adapts plain functions to a [MetricsRecorder].
+// The metrics of the kinds whose function is nil are discarded.
+type MetricsRecorderFuncs struct {
+	Counter   func(name string, delta float64)
+	Gauge     func(name string, value float64)
+	Histogram func(name string, value float64)
+}
+
+// AddCounter invokes the Counter function, if any.
+func (f MetricsRecorderFuncs) AddCounter(name string, delta float64) {
+	if f.Counter != nil {
+		f.Counter(name, delta)
+	}
+}
+
+// SetGauge invokes the Gauge function, if any.
+func (f MetricsRecorderFuncs) SetGauge(name string, value float64) {
+	if f.Gauge != nil {
+		f.Gauge(name, value)
+	}
+}
+
+// ObserveHistogram invokes the Histogram function, if any.
+func (f MetricsRecorderFuncs) ObserveHistogram(name string, value float64) {
+	if f.Histogram != nil {
+		f.Histogram(name, value)
+	}
+}
+
+// expvarRecorder is a [MetricsRecorder] publishing the metrics in an [expvar.Map].
+type expvarRecorder struct {
+	m *expvar.Map
+}
+
+// NewExpvarRecorder returns a [MetricsRecorder] publishing the metrics in m, e.g. created through [expvar.NewMap].
+// The histograms are summarized by the count and the sum of the observations,
+// under the name of the metric with the "_count" and "_sum" suffixes.
+func NewExpvarRecorder(m *expvar.Map) MetricsRecorder {
+	return expvarRecorder{m: m}
+}
+
+// AddCounter increments the counter with the given name by delta.
+func (r expvarRecorder) AddCounter(name string, delta float64) {
+	r.m.AddFloat(name, delta)
+}
+
+// SetGauge sets the gauge with the given name to value.
+func (r expvarRecorder) SetGauge(name string, value float64) {
+	v := new(expvar.Float)
+	v.Set(value)
+	r.m.Set(name, v)
+}
+
+// ObserveHistogram adds value to the sum of the histogram with the given name, and increments its count.
+func (r expvarRecorder) ObserveHistogram(name string, value float64) {
+	r.m.AddFloat(name+"_count", 1)
+	r.m.AddFloat(name+"_sum", value)
+}
+
+// WithMetricsRecorder reports the metrics of the requests, of the connections and of the drain
+// to the recorder, as they occur.
+func WithMetricsRecorder(r MetricsRecorder) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.metrics = r
+	}
+}
+
+// addCounter increments a counter of the metrics recorder, if any.
+func (s *GracefulServer) addCounter(name string, delta float64) {
+	if s.metrics != nil {
+		s.metrics.AddCounter(name, delta)
+	}
+}
+
+// setGauge sets a gauge of the metrics recorder, if any.
+func (s *GracefulServer) setGauge(name string, value float64) {
+	if s.metrics != nil {
+		s.metrics.SetGauge(name, value)
+	}
+}
+
+// observeDuration records a duration, in seconds, in a histogram of the metrics recorder, if any.
+func (s *GracefulServer) observeDuration(name string, d time.Duration) {
+	if s.metrics != nil {
+		s.metrics.ObserveHistogram(name, d.Seconds())
+	}
+}
+
+// metricsHandler reports the requests and the time spent serving them to the metrics recorder.
+func (s *GracefulServer) metricsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.addCounter(MetricRequests, 1)
+
+		start := s.clock.Now()
+		defer func() {
+			s.observeDuration(MetricRequestDuration, s.clock.Now().Sub(start))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
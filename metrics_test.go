@@ -0,0 +1,116 @@
+package gracefulhttp
+
+import (
+	"context"
+	"expvar"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRecorder is a [MetricsRecorder] keeping the last value of each metric.
+type testRecorder struct {
+	mu         sync.Mutex
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string][]float64
+}
+
+func newTestRecorder() *testRecorder {
+	return &testRecorder{
+		counters:   make(map[string]float64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string][]float64),
+	}
+}
+
+func (r *testRecorder) AddCounter(name string, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name] += delta
+}
+
+func (r *testRecorder) SetGauge(name string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[name] = value
+}
+
+func (r *testRecorder) ObserveHistogram(name string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.histograms[name] = append(r.histograms[name], value)
+}
+
+func TestWithMetricsRecorder(t *testing.T) {
+	recorder := newTestRecorder()
+
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithMetricsRecorder(recorder))
+	}()
+	<-s.Ready()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	for range 2 {
+		resp, err := client.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	client.CloseIdleConnections()
+	cancel()
+	require.NoError(t, <-done)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	assert.Equal(t, 2.0, recorder.counters[MetricRequests])
+	assert.Equal(t, 1.0, recorder.counters[MetricConnectionsAccepted])
+	assert.Zero(t, recorder.counters[MetricForcedCloses])
+	assert.Equal(t, 1.0, recorder.gauges[MetricDraining])
+	assert.Len(t, recorder.histograms[MetricRequestDuration], 2)
+	assert.Len(t, recorder.histograms[MetricDrainDuration], 1)
+}
+
+func TestMetricsRecorderFuncs(t *testing.T) {
+	var counted float64
+	r := MetricsRecorderFuncs{
+		Counter: func(name string, delta float64) {
+			counted += delta
+		},
+	}
+
+	r.AddCounter(MetricRequests, 2)
+	r.SetGauge(MetricDraining, 1)
+	r.ObserveHistogram(MetricDrainDuration, 1)
+
+	assert.Equal(t, 2.0, counted)
+}
+
+func TestNewExpvarRecorder(t *testing.T) {
+	m := new(expvar.Map).Init()
+	r := NewExpvarRecorder(m)
+
+	r.AddCounter(MetricRequests, 1)
+	r.AddCounter(MetricRequests, 2)
+	r.SetGauge(MetricConnectionsOpen, 4)
+	r.SetGauge(MetricConnectionsOpen, 2)
+	r.ObserveHistogram(MetricRequestDuration, 0.5)
+	r.ObserveHistogram(MetricRequestDuration, 1.5)
+
+	assert.Equal(t, "3", m.Get(MetricRequests).String())
+	assert.Equal(t, "2", m.Get(MetricConnectionsOpen).String())
+	assert.Equal(t, "2", m.Get(MetricRequestDuration+"_count").String())
+	assert.Equal(t, "2", m.Get(MetricRequestDuration+"_sum").String())
+}
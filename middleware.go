@@ -0,0 +1,328 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wrapHandler decorates the handler with the middlewares enabled by the options.
+// A nil handler is replaced by [http.DefaultServeMux], as [http.Server] would do.
+func (s *GracefulServer) wrapHandler(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+
+	if len(s.hostHandlers) > 0 {
+		h = hostHandler(s.hostHandlers, h)
+	}
+
+	if s.handlerTimeout > 0 {
+		h = http.TimeoutHandler(h, s.handlerTimeout, s.handlerTimeoutMsg)
+	}
+
+	if s.panicRecovery {
+		h = s.panicRecoveryHandler(h)
+	}
+
+	if s.errorRateThreshold > 0 {
+		// Applied inside the rejections by the server, so that they are not measured.
+		h = s.errorRateHandler(h)
+	}
+
+	if s.requestsCtx != nil {
+		h = s.requestCancelHandler(h)
+	}
+
+	if s.logger != nil {
+		h = s.requestTrackingHandler(h)
+	}
+
+	if s.maxBodySize > 0 {
+		h = maxBodySizeHandler(h, s.maxBodySize)
+	}
+
+	if s.minDataRate > 0 {
+		h = s.dataRateHandler(h)
+	}
+
+	if s.closeWhileDraining {
+		h = s.drainCloseHandler(h)
+	}
+
+	if s.maxInFlight > 0 {
+		s.limitingInFlight = true
+		s.inFlightReleased = make(chan struct{}, max(s.queueSize, 1))
+		h = s.inFlightLimitHandler(h)
+	}
+
+	if s.brownoutEnabled {
+		h = s.brownoutHandler(h)
+	}
+
+	if s.rejectWhileDraining {
+		h = s.drainRejectionHandler(h)
+	}
+
+	h = s.expectContinueHandler(h)
+
+	if s.idleShutdown > 0 {
+		h = s.idleHandler(h)
+	}
+
+	if s.readinessPath != "" {
+		h = s.readinessPathHandler(h)
+	}
+
+	if s.pprofPrefix != "" && s.admin == nil {
+		h = pprofHandler(h, s.pprofPrefix)
+	}
+
+	if s.accessLogger != nil {
+		h = s.accessLogHandler(h)
+	}
+
+	if s.metrics != nil {
+		h = s.metricsHandler(h)
+	}
+
+	if s.forceCloseBudget > 0 {
+		h = s.forceCloseResponseHandler(h)
+	}
+
+	if s.requestHooks != nil {
+		h = s.requestHooksHandler(h)
+	}
+
+	if s.trackTunnels {
+		h = s.tunnelTrackingHandler(h)
+	}
+
+	return s.statsHandler(h)
+}
+
+// drainRejectionHandler answers requests arriving after the shutdown has begun
+// with 503 Service Unavailable, letting already-started requests finish
+// and serving the requests for the exempt paths normally.
+func (s *GracefulServer) drainRejectionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, exempt := s.drainExemptPaths[r.URL.Path]
+		if !exempt && s.isDraining() {
+			serviceUnavailable(w, s.retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// expectContinueHandler answers the requests expecting a 100 Continue, arriving after the shutdown has begun,
+// with 503 Service Unavailable, so that the clients do not upload their body to a server about to close
+// the connection. The requests for the exempt paths are served normally.
+func (s *GracefulServer) expectContinueHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, exempt := s.drainExemptPaths[r.URL.Path]
+		if exempt || !s.isDraining() || !strings.EqualFold(r.Header.Get("Expect"), "100-continue") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ProtoMajor == 1 {
+			w.Header().Set("Connection", "close")
+		}
+
+		serviceUnavailable(w, cmp.Or(s.retryAfter, defaultRetryAfter))
+	})
+}
+
+// panicRecoveryHandler recovers the panics of the handler, reporting them through the logger and the callback,
+// if any, and answering with 500 Internal Server Error unless a response was written already.
+// The [http.ErrAbortHandler] panics are left to [http.Server], which aborts the response silently.
+func (s *GracefulServer) panicRecoveryHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			s.recordPanic()
+
+			if s.logger != nil {
+				s.logger.Error("gracefulhttp: panic serving request",
+					"method", r.Method, "path", r.URL.Path, "panic", v, "stack", string(debug.Stack()))
+			}
+
+			if s.onPanic != nil {
+				s.onPanic(rw, r, v)
+			}
+
+			if !rw.wroteHeader {
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// requestTrackingHandler records the requests being served, so that the ones
+// still in flight during the drain can be logged.
+func (s *GracefulServer) requestTrackingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer s.requests.add(r.Method, r.URL.Path, s.clock.Now())()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// inFlightLimitHandler answers with 503 Service Unavailable the requests exceeding
+// the maximum number of requests processed concurrently, once they could not be queued
+// or have waited too long in the queue.
+func (s *GracefulServer) inFlightLimitHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.admitInFlight(r.Context()) {
+			s.mu.Lock()
+			retryAfter := s.inFlightRetryAfter
+			s.mu.Unlock()
+
+			serviceUnavailable(w, retryAfter)
+			return
+		}
+		defer s.releaseInFlight()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// admitInFlight reserves a slot for a request, waiting in the queue set by [WithRequestQueue], if any,
+// until a slot is released, the queue timeout expires or the request is canceled.
+func (s *GracefulServer) admitInFlight(ctx context.Context) bool {
+	if s.tryAcquireInFlight() {
+		return true
+	}
+
+	if s.queueSize <= 0 {
+		return false
+	}
+
+	if s.queued.Add(1) > int64(s.queueSize) {
+		s.queued.Add(-1)
+		return false
+	}
+	defer s.queued.Add(-1)
+
+	timeout := s.clock.After(s.queueTimeout)
+	for {
+		// Being counted as queued before trying again ensures the release of a slot is never missed.
+		if s.tryAcquireInFlight() {
+			return true
+		}
+
+		select {
+		case <-s.inFlightReleased:
+		case <-timeout:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// tryAcquireInFlight reserves a slot for a request, if any is available.
+func (s *GracefulServer) tryAcquireInFlight() bool {
+	n := s.inFlight.Add(1)
+	if limit := s.inFlightLimit(); limit > 0 && n > limit {
+		s.inFlight.Add(-1)
+		return false
+	}
+
+	return true
+}
+
+// releaseInFlight releases the slot of a request, waking up a queued request, if any.
+func (s *GracefulServer) releaseInFlight() {
+	s.inFlight.Add(-1)
+
+	if s.queued.Load() > 0 {
+		select {
+		case s.inFlightReleased <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// inFlightLimit returns the maximum number of requests processed concurrently,
+// ramped up linearly from 1 during the slow start window after the listener is bound.
+// A non-positive limit, which may be set through [GracefulServer.Reload], means no limit.
+func (s *GracefulServer) inFlightLimit() int64 {
+	s.mu.Lock()
+	limit, readyAt := int64(s.maxInFlight), s.readyAt
+	s.mu.Unlock()
+
+	if s.slowStart <= 0 || limit <= 0 {
+		return limit
+	}
+
+	elapsed := s.clock.Now().Sub(readyAt)
+	if elapsed >= s.slowStart {
+		return limit
+	}
+
+	return max(1, int64(float64(limit)*float64(elapsed)/float64(s.slowStart)))
+}
+
+// maxBodySizeHandler limits the size of the request bodies through [http.MaxBytesHandler],
+// answering with 413 Request Entity Too Large when the declared length exceeds the limit.
+func maxBodySizeHandler(next http.Handler, n int64) http.Handler {
+	limited := http.MaxBytesHandler(next, n)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > n {
+			w.Header().Set("Connection", "close")
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		limited.ServeHTTP(w, r)
+	})
+}
+
+// drainCloseHandler adds the "Connection: close" header to HTTP/1.x responses written
+// while the server is draining, so that keep-alive clients disconnect right away.
+func (s *GracefulServer) drainCloseHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&responseWriter{
+			ResponseWriter: w,
+			beforeWriteHeader: func(header http.Header) {
+				if s.isDraining() {
+					header.Set("Connection", "close")
+				}
+			},
+		}, r)
+	})
+}
+
+// serviceUnavailable replies with 503 Service Unavailable and a Retry-After header
+// expressed in whole seconds.
+func serviceUnavailable(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
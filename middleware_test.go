@@ -0,0 +1,587 @@
+package gracefulhttp
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDrainRejection(t *testing.T) {
+	tests := []struct {
+		name           string
+		retryAfter     time.Duration
+		draining       bool
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "serve requests before shutdown",
+			retryAfter: 3 * time.Second,
+			draining:   false,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "reject requests while draining",
+			retryAfter:     3 * time.Second,
+			draining:       true,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "3",
+		},
+		{
+			name:           "round up retry after to seconds",
+			retryAfter:     1500 * time.Millisecond,
+			draining:       true,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "default retry after",
+			retryAfter:     0,
+			draining:       true,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{WithDrainRejection(tt.retryAfter)})
+
+			if tt.draining {
+				s.beginDrain()
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestWithDrainExemptPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		draining   bool
+		wantStatus int
+	}{
+		{
+			name:       "serve requests before shutdown",
+			path:       "/",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "serve exempt path while draining",
+			path:       "/healthz",
+			draining:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject other paths while draining",
+			path:       "/api",
+			draining:   true,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{WithDrainExemptPaths("/healthz", "/metrics")})
+
+			if tt.draining {
+				s.beginDrain()
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestWithDrainConnectionClose(t *testing.T) {
+	tests := []struct {
+		name       string
+		protoMajor int
+		draining   bool
+		want       string
+	}{
+		{
+			name:       "keep connection before shutdown",
+			protoMajor: 1,
+			draining:   false,
+			want:       "",
+		},
+		{
+			name:       "close connection while draining",
+			protoMajor: 1,
+			draining:   true,
+			want:       "close",
+		},
+		{
+			name:       "ignore http2 requests",
+			protoMajor: 2,
+			draining:   true,
+			want:       "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{WithDrainConnectionClose()})
+
+			if tt.draining {
+				s.beginDrain()
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.ProtoMajor = tt.protoMajor
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Connection"))
+		})
+	}
+}
+
+func TestGracefulServer_expectContinueHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		expect     string
+		draining   bool
+		wantStatus int
+		wantClose  bool
+	}{
+		{
+			name:       "serve requests expecting 100 Continue before shutdown",
+			path:       "/upload",
+			expect:     "100-continue",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject requests expecting 100 Continue while draining",
+			path:       "/upload",
+			expect:     "100-Continue",
+			draining:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantClose:  true,
+		},
+		{
+			name:       "serve other requests while draining",
+			path:       "/upload",
+			draining:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "serve exempt path while draining",
+			path:       "/healthz",
+			expect:     "100-continue",
+			draining:   true,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{WithDrainExemptPaths("/healthz")})
+			s.Handler = s.expectContinueHandler(&delayedHandler{})
+
+			if tt.draining {
+				s.beginDrain()
+			}
+
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("body"))
+			if tt.expect != "" {
+				r.Header.Set("Expect", tt.expect)
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantClose, w.Header().Get("Connection") == "close")
+		})
+	}
+}
+
+func TestWithMaxInFlightRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		inFlight       int64
+		retryAfter     time.Duration
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "serve requests below the limit",
+			inFlight:   1,
+			retryAfter: 2 * time.Second,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "reject requests above the limit",
+			inFlight:       2,
+			retryAfter:     2 * time.Second,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "default retry after",
+			inFlight:       2,
+			retryAfter:     0,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{WithMaxInFlightRequests(2, tt.retryAfter)})
+			s.inFlight.Store(tt.inFlight)
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+			assert.Equal(t, tt.inFlight, s.inFlight.Load())
+		})
+	}
+}
+
+// blockingHandler serves the requests once released, reporting when each one starts.
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.started <- struct{}{}
+	<-h.release
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveAsync serves a request in the background, returning the channel receiving its status.
+func serveAsync(s *GracefulServer) <-chan int {
+	status := make(chan int, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		status <- w.Code
+	}()
+
+	return status
+}
+
+func TestWithRequestQueue(t *testing.T) {
+	newServer := func(t *testing.T, h http.Handler, clock Clock) *GracefulServer {
+		t.Helper()
+
+		s := Bind("", h)
+		require.NoError(t, s.initialize([]GracefulServerOption{
+			WithMaxInFlightRequests(1, 0),
+			WithRequestQueue(1, time.Second),
+			WithClock(clock),
+		}))
+
+		return s
+	}
+
+	t.Run("serve a queued request once a slot is released", func(t *testing.T) {
+		h := newBlockingHandler()
+		s := newServer(t, h, newManualClock())
+
+		first := serveAsync(s)
+		<-h.started
+		second := serveAsync(s)
+		require.Eventually(t, func() bool { return s.queued.Load() == 1 }, time.Second, time.Millisecond)
+
+		h.release <- struct{}{}
+		assert.Equal(t, http.StatusOK, <-first)
+		<-h.started
+		h.release <- struct{}{}
+		assert.Equal(t, http.StatusOK, <-second)
+	})
+
+	t.Run("reject the requests once the queue is full", func(t *testing.T) {
+		h := newBlockingHandler()
+		s := newServer(t, h, newManualClock())
+
+		first := serveAsync(s)
+		<-h.started
+		second := serveAsync(s)
+		require.Eventually(t, func() bool { return s.queued.Load() == 1 }, time.Second, time.Millisecond)
+
+		assert.Equal(t, http.StatusServiceUnavailable, <-serveAsync(s))
+
+		close(h.release)
+		assert.Equal(t, http.StatusOK, <-first)
+		assert.Equal(t, http.StatusOK, <-second)
+	})
+
+	t.Run("reject a request timing out in the queue", func(t *testing.T) {
+		h := newBlockingHandler()
+		clock := newManualClock()
+		s := newServer(t, h, clock)
+
+		first := serveAsync(s)
+		<-h.started
+		second := serveAsync(s)
+
+		clock.fire()
+		assert.Equal(t, http.StatusServiceUnavailable, <-second)
+
+		close(h.release)
+		assert.Equal(t, http.StatusOK, <-first)
+	})
+}
+
+func TestGracefulServer_inFlightLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		slowStart time.Duration
+		elapsed   time.Duration
+		want      int64
+	}{
+		{
+			name:    "no slow start",
+			elapsed: 0,
+			want:    100,
+		},
+		{
+			name:      "start from one",
+			slowStart: 10 * time.Second,
+			elapsed:   0,
+			want:      1,
+		},
+		{
+			name:      "ramp up linearly",
+			slowStart: 10 * time.Second,
+			elapsed:   3 * time.Second,
+			want:      30,
+		},
+		{
+			name:      "full capacity after the window",
+			slowStart: 10 * time.Second,
+			elapsed:   time.Minute,
+			want:      100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fixedClock{now: time.Now()}
+
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{
+				WithMaxInFlightRequests(100, 0),
+				WithSlowStart(tt.slowStart),
+				WithClock(clock),
+			})
+			s.readyAt = clock.now.Add(-tt.elapsed)
+
+			assert.Equal(t, tt.want, s.inFlightLimit())
+		})
+	}
+}
+
+// fixedClock is a Clock whose time never advances.
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fixedClock) After(time.Duration) <-chan time.Time {
+	return nil
+}
+
+func TestWithMaxRequestBodySize(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		unknownLength bool
+		wantStatus    int
+		wantRead      string
+	}{
+		{
+			name:       "serve bodies within the limit",
+			body:       "1234",
+			wantStatus: http.StatusOK,
+			wantRead:   "1234",
+		},
+		{
+			name:       "reject declared lengths beyond the limit",
+			body:       "123456",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "stop reading unknown lengths at the limit",
+			body:          "123456",
+			unknownLength: true,
+			wantStatus:    http.StatusBadRequest,
+			wantRead:      "1234",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var read []byte
+
+			s := Bind("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var err error
+				read, err = io.ReadAll(r.Body)
+
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+			s.initialize([]GracefulServerOption{WithMaxRequestBodySize(4)})
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.unknownLength {
+				r.ContentLength = -1
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantRead, string(read))
+		})
+	}
+}
+
+func TestWithPanicRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		onPanic    func(w http.ResponseWriter, r *http.Request, v any)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "answer 500",
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Internal Server Error\n",
+		},
+		{
+			name: "let the callback answer",
+			onPanic: func(w http.ResponseWriter, r *http.Request, v any) {
+				w.WriteHeader(http.StatusBadGateway)
+				_, _ = io.WriteString(w, v.(string))
+			},
+			wantStatus: http.StatusBadGateway,
+			wantBody:   "boom",
+		},
+		{
+			name:       "report through the callback",
+			onPanic:    func(w http.ResponseWriter, r *http.Request, v any) {},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Internal Server Error\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs lockedBuffer
+			s := Bind("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			}))
+			s.initialize([]GracefulServerOption{
+				WithPanicRecovery(tt.onPanic),
+				WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			})
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Contains(t, logs.String(), "panic=boom")
+			assert.Equal(t, int64(1), s.panics.Load())
+		})
+	}
+
+	t.Run("answer 500 after the early hints", func(t *testing.T) {
+		s := Bind("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			panic("boom")
+		}))
+		s.initialize([]GracefulServerOption{WithPanicRecovery(nil)})
+
+		srv := httptest.NewServer(s.Handler)
+		defer srv.Close()
+
+		r, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+		assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	})
+
+	t.Run("abort the handler", func(t *testing.T) {
+		s := Bind("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		s.initialize([]GracefulServerOption{WithPanicRecovery(nil)})
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			s.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
+
+func TestWithHandlerTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		msg        string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "answer within the timeout",
+			wantStatus: http.StatusOK,
+			wantBody:   "{}",
+		},
+		{
+			name:       "answer 503 once the timeout expires",
+			delay:      time.Second,
+			msg:        "too slow",
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "too slow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{delay: tt.delay})
+			s.initialize([]GracefulServerOption{WithHandlerTimeout(50*time.Millisecond, tt.msg)})
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
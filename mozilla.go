@@ -0,0 +1,98 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// MozillaTLSProfile is one of the server side TLS configurations recommended by Mozilla:
+// https://wiki.mozilla.org/Security/Server_Side_TLS
+type MozillaTLSProfile int
+
+const (
+	// MozillaModern supports only TLS 1.3, for services with modern clients that do not need backwards compatibility.
+	MozillaModern MozillaTLSProfile = iota
+	// MozillaIntermediate supports TLS 1.2 and 1.3, and is the recommended configuration for general-purpose servers.
+	MozillaIntermediate
+	// MozillaOld supports TLS 1.0 and later with legacy cipher suites,
+	// for services accessed by very old clients or libraries.
+	MozillaOld
+)
+
+// String returns the name of the profile.
+func (p MozillaTLSProfile) String() string {
+	switch p {
+	case MozillaModern:
+		return "modern"
+	case MozillaIntermediate:
+		return "intermediate"
+	case MozillaOld:
+		return "old"
+	default:
+		return "unknown"
+	}
+}
+
+// parseMozillaTLSProfile returns the profile with the given name.
+func parseMozillaTLSProfile(name string) (MozillaTLSProfile, error) {
+	for _, p := range []MozillaTLSProfile{MozillaModern, MozillaIntermediate, MozillaOld} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("gracefulhttp: unknown TLS profile %q", name)
+}
+
+var (
+	// mozillaCurvePreferences are the curves recommended by all the Mozilla profiles.
+	mozillaCurvePreferences = []tls.CurveID{
+		tls.X25519,
+		tls.CurveP256,
+		tls.CurveP384,
+	}
+
+	// mozillaIntermediateCipherSuites are the TLS 1.2 cipher suites of the intermediate profile
+	// supported by the standard library. TLS 1.3 cipher suites are not configurable.
+	mozillaIntermediateCipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	}
+
+	// mozillaOldCipherSuites are the cipher suites of the old profile supported by the standard library.
+	mozillaOldCipherSuites = append(append([]uint16{}, mozillaIntermediateCipherSuites...),
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+		tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+		tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	)
+)
+
+// apply patches the TLS configuration with the settings of the profile.
+func (p MozillaTLSProfile) apply(cfg *tls.Config) {
+	cfg.CurvePreferences = mozillaCurvePreferences
+
+	switch p {
+	case MozillaModern:
+		cfg.MinVersion = tls.VersionTLS13
+		cfg.CipherSuites = nil
+	case MozillaOld:
+		cfg.MinVersion = tls.VersionTLS10
+		cfg.CipherSuites = mozillaOldCipherSuites
+	default:
+		cfg.MinVersion = tls.VersionTLS12
+		cfg.CipherSuites = mozillaIntermediateCipherSuites
+	}
+}
@@ -0,0 +1,59 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMozillaTLSProfile(t *testing.T) {
+	tests := []struct {
+		name             string
+		profile          MozillaTLSProfile
+		wantMinVersion   uint16
+		wantCipherSuites []uint16
+	}{
+		{
+			name:           "modern",
+			profile:        MozillaModern,
+			wantMinVersion: tls.VersionTLS13,
+		},
+		{
+			name:             "intermediate",
+			profile:          MozillaIntermediate,
+			wantMinVersion:   tls.VersionTLS12,
+			wantCipherSuites: mozillaIntermediateCipherSuites,
+		},
+		{
+			name:             "old",
+			profile:          MozillaOld,
+			wantMinVersion:   tls.VersionTLS10,
+			wantCipherSuites: mozillaOldCipherSuites,
+		},
+		{
+			name:             "unknown",
+			profile:          MozillaTLSProfile(42),
+			wantMinVersion:   tls.VersionTLS12,
+			wantCipherSuites: mozillaIntermediateCipherSuites,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := GracefulServer{}
+			WithCloudflareTLSConfig()(&s)
+			WithMozillaTLSProfile(tt.profile)(&s)
+
+			assert.Equal(t, tt.wantMinVersion, s.TLSConfig.MinVersion)
+			assert.Equal(t, tt.wantCipherSuites, s.TLSConfig.CipherSuites)
+			assert.Equal(t, mozillaCurvePreferences, s.TLSConfig.CurvePreferences)
+		})
+	}
+}
+
+func TestMozillaTLSProfile_String(t *testing.T) {
+	assert.Equal(t, "modern", MozillaModern.String())
+	assert.Equal(t, "intermediate", MozillaIntermediate.String())
+	assert.Equal(t, "old", MozillaOld.String())
+	assert.Equal(t, "unknown", MozillaTLSProfile(42).String())
+}
@@ -1,17 +1,34 @@
 package gracefulhttp
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"syscall"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // GracefulServerOption is an option used to configure a [GracefulServer] instance.
 type GracefulServerOption func(s *GracefulServer)
 
 // WithShutdownTimeout sets the timeout for a graceful shutdown, after which all active connections
-// will be forcibly closed.
+// will be forcibly closed. A zero duration restores the default timeout, as does a negative one,
+// which is reported as invalid through [WithOnConfigWarning] or [WithStrictOptions].
 func WithShutdownTimeout(duration time.Duration) GracefulServerOption {
 	return func(s *GracefulServer) {
+		if duration < 0 {
+			s.invalidOption("WithShutdownTimeout", "negative duration %v", duration)
+		}
+
 		if duration <= 0 {
 			s.gracefulTimeout = defaultGracefulTimeout
 			return
@@ -21,10 +38,80 @@ func WithShutdownTimeout(duration time.Duration) GracefulServerOption {
 	}
 }
 
+// WithNoForceClose waits for the in-flight requests to complete, however long they take, instead of
+// forcibly closing the connections once the graceful timeout expires, for the services which would rather
+// block their shutdown than truncate a long response. The connections are forcibly closed only once stop
+// is done, as if the graceful timeout had expired: pass [context.Background] to wait indefinitely.
+// [WithRequestCancelMargin] has no effect, as there is no deadline to cancel the requests ahead of.
+func WithNoForceClose(stop context.Context) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.forceCloseCtx = stop
+	}
+}
+
+// WithShutdownContext sets the factory of the context bounding the drain, called once the drain begins, after
+// the shutdown delay, if any, instead of applying the graceful timeout: the connections are forcibly closed once
+// the context is done, e.g. at a deadline derived from the termination deadline of the pod, passed through the
+// downward API. Its deadline, if any, is reported by [GracefulServer.ShutdownDeadline], and cannot be extended
+// through [GracefulServer.ExtendShutdown]. It takes precedence over [WithNoForceClose]; a nil factory unsets it.
+func WithShutdownContext(fn func() (context.Context, context.CancelFunc)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.shutdownContext = fn
+	}
+}
+
+// WithImmediateClose closes the active connections as soon as the serving context is canceled,
+// skipping the drain, as [ErrImmediateStop] does, for crash-only services, test teardowns and
+// emergency stops where waiting for the graceful timeout is not acceptable.
+func WithImmediateClose() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.immediateClose = true
+	}
+}
+
+// WithReturnCause makes the serving methods return the cause of the cancellation which stopped the server,
+// instead of nil, once the shutdown completes without error, so that a supervisor can tell a server stopped
+// on request from one stopped on its own: [context.Canceled], [context.DeadlineExceeded] or the cause given
+// to [context.WithCancelCause] when the serving context is canceled, [ErrImmediateStop] when stopped through
+// [GracefulServer.CloseNow], or the signal received when stopped by [WithShutdownSignals].
+func WithReturnCause() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.returnCause = true
+	}
+}
+
+// WithReportServerClosed makes the serving methods return [http.ErrServerClosed], instead of nil, once
+// the shutdown completes without error, as expected by the frameworks built around [http.Server].
+// Combined with [WithReturnCause], the cause of the cancellation is joined to it.
+func WithReportServerClosed() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.reportClosed = true
+	}
+}
+
+// WithForceCloseTimeout bounds the time given to forcibly close the connections, including the
+// hijacked ones, once the graceful timeout has expired. When set, the shutdown reports
+// [ErrDrainTimeout] if the drain window expired, and [ErrForceCloseTimeout] if the force window expired as well.
+// A non-positive duration leaves the force close unbounded.
+func WithForceCloseTimeout(duration time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if duration < 0 {
+			s.invalidOption("WithForceCloseTimeout", "negative duration %v", duration)
+		}
+
+		s.forceTimeout = max(duration, 0)
+	}
+}
+
 // WithCloudflareTimeouts applies timeout patches to a [http.Server], implementing best practice
 // configurations inspired by Cloudflare: https://blog.cloudflare.com/exposing-go-on-the-internet/
 func WithCloudflareTimeouts() GracefulServerOption {
 	return func(s *GracefulServer) {
+		s.claimSetting("timeouts.read", "WithCloudflareTimeouts")
+		s.claimSetting("timeouts.read_header", "WithCloudflareTimeouts")
+		s.claimSetting("timeouts.write", "WithCloudflareTimeouts")
+		s.claimSetting("timeouts.idle", "WithCloudflareTimeouts")
+
 		s.ReadTimeout = defaultReadTimeout
 		s.ReadHeaderTimeout = defaultReadHeaderTimeout
 		s.WriteTimeout = defaultWriteTimeout
@@ -32,29 +119,798 @@ func WithCloudflareTimeouts() GracefulServerOption {
 	}
 }
 
+// WithGCPLoadBalancerTimeouts applies the timeouts expected by the Google Cloud HTTP(S) load balancers:
+// the idle timeout outlasts their 600 seconds keepalive timeout, as recommended in
+// https://cloud.google.com/load-balancing/docs/https#timeouts_and_retries, while the read and write timeouts
+// are disabled, leaving the requests to be bounded by the timeout of the backend service (30 seconds by default).
+func WithGCPLoadBalancerTimeouts() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.claimSetting("timeouts.read", "WithGCPLoadBalancerTimeouts")
+		s.claimSetting("timeouts.read_header", "WithGCPLoadBalancerTimeouts")
+		s.claimSetting("timeouts.write", "WithGCPLoadBalancerTimeouts")
+		s.claimSetting("timeouts.idle", "WithGCPLoadBalancerTimeouts")
+
+		s.ReadTimeout = 0
+		s.ReadHeaderTimeout = gcpReadHeaderTimeout
+		s.WriteTimeout = 0
+		s.IdleTimeout = gcpIdleTimeout
+	}
+}
+
+// WithNginxUpstreamTimeouts applies timeouts coordinated with the defaults of an nginx reverse proxy:
+// the idle timeout outlasts the 60 seconds keepalive_timeout of the upstream connections, while the read
+// and write timeouts are disabled, leaving the requests to be bounded by proxy_read_timeout and
+// proxy_send_timeout (60 seconds between two operations by default).
+func WithNginxUpstreamTimeouts() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.claimSetting("timeouts.read", "WithNginxUpstreamTimeouts")
+		s.claimSetting("timeouts.read_header", "WithNginxUpstreamTimeouts")
+		s.claimSetting("timeouts.write", "WithNginxUpstreamTimeouts")
+		s.claimSetting("timeouts.idle", "WithNginxUpstreamTimeouts")
+
+		s.ReadTimeout = 0
+		s.ReadHeaderTimeout = nginxReadHeaderTimeout
+		s.WriteTimeout = 0
+		s.IdleTimeout = nginxIdleTimeout
+	}
+}
+
+// WithHerokuTimeouts applies timeouts aligned with the Heroku router, which gives up on the requests
+// not answered within 30 seconds and keeps the idle connections open for 55 seconds, and a graceful timeout
+// expiring before the dyno is killed, 30 seconds after the SIGTERM:
+// https://devcenter.heroku.com/articles/http-routing#timeouts
+func WithHerokuTimeouts() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.claimSetting("timeouts.read", "WithHerokuTimeouts")
+		s.claimSetting("timeouts.read_header", "WithHerokuTimeouts")
+		s.claimSetting("timeouts.write", "WithHerokuTimeouts")
+		s.claimSetting("timeouts.idle", "WithHerokuTimeouts")
+
+		s.ReadTimeout = herokuRequestTimeout
+		s.ReadHeaderTimeout = defaultReadHeaderTimeout
+		s.WriteTimeout = herokuRequestTimeout
+		s.IdleTimeout = herokuIdleTimeout
+		s.gracefulTimeout = herokuGracefulTimeout
+	}
+}
+
 // WithCloudflareTLSConfig applies TLS configuration patches to a [http.Server], implementing best practice
 // configurations inspired by Cloudflare: https://blog.cloudflare.com/exposing-go-on-the-internet/
+//
+// The patches are applied to a copy of the current TLS configuration, so a configuration shared
+// with other servers is never altered. Options are applied in order: use it after [WithTLSConfig]
+// to harden the provided configuration, as a later WithTLSConfig replaces the whole configuration.
 func WithCloudflareTLSConfig() GracefulServerOption {
 	return func(s *GracefulServer) {
-		if s.TLSConfig == nil {
-			s.TLSConfig = &tls.Config{
-				MinVersion:       defaultTLSMinVersion,
-				CurvePreferences: defaultTLSCurvePreferences,
-				CipherSuites:     defaultTLSCipherSuites,
-			}
+		s.claimSetting("tls.params", "WithCloudflareTLSConfig")
+
+		cfg := s.ownTLSConfig()
+		cfg.MinVersion = defaultTLSMinVersion
+		cfg.CurvePreferences = defaultTLSCurvePreferences
+		cfg.CipherSuites = defaultTLSCipherSuites
+	}
+}
 
+// WithCloudflareOriginPull requires the clients to present a certificate signed by the CA of Cloudflare
+// Authenticated Origin Pulls, so that the requests only arrive through Cloudflare: the connections from
+// the clients reaching the server directly fail the TLS handshake. caPEM is the PEM encoded CA, either the
+// one shared by the zones, published at
+// https://developers.cloudflare.com/ssl/static/authenticated_origin_pull_ca.pem, or the one of the custom
+// certificate uploaded for the zone or the hostname. It is not embedded, so that it follows its rotations.
+func WithCloudflareOriginPull(caPEM []byte) GracefulServerOption {
+	return func(s *GracefulServer) {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			s.invalidOption("WithCloudflareOriginPull", "no certificate found in the CA")
 			return
 		}
 
-		s.TLSConfig.MinVersion = defaultTLSMinVersion
-		s.TLSConfig.CurvePreferences = defaultTLSCurvePreferences
-		s.TLSConfig.CipherSuites = defaultTLSCipherSuites
+		cfg := s.ownTLSConfig()
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = pool
+	}
+}
+
+// WithMozillaTLSProfile applies TLS configuration patches to a [http.Server], implementing one of
+// the profiles recommended by Mozilla: https://wiki.mozilla.org/Security/Server_Side_TLS
+// It follows the same rules of [WithCloudflareTLSConfig]. An unknown profile falls back to [MozillaIntermediate].
+func WithMozillaTLSProfile(profile MozillaTLSProfile) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if profile.String() == "unknown" {
+			s.invalidOption("WithMozillaTLSProfile", "unknown profile %d", profile)
+		}
+
+		s.claimSetting("tls.params", "WithMozillaTLSProfile")
+		profile.apply(s.ownTLSConfig())
 	}
 }
 
-// WithTLSConfig sets the provided TLS configuration for a [http.Server].
+// WithTLSConfig sets a copy of the provided TLS configuration for a [http.Server],
+// replacing the current one, so the caller's configuration is never altered by the other options.
+// The certificates provided through the other options are added to the copy when serving,
+// regardless of the order of the options.
 func WithTLSConfig(config *tls.Config) GracefulServerOption {
 	return func(s *GracefulServer) {
-		s.TLSConfig = config
+		if config == nil {
+			s.invalidOption("WithTLSConfig", "nil configuration")
+		}
+
+		s.replaceSettings("tls", "WithTLSConfig")
+		s.TLSConfig = config.Clone()
+		s.ownedTLSConfig = s.TLSConfig
+	}
+}
+
+// WithALPN sets the application protocols advertised through ALPN, in order of preference,
+// patching a copy of the current TLS configuration like [WithCloudflareTLSConfig].
+// HTTP/2 is disabled when "h2" is not listed, while "http/1.1" is always advertised by [http.Server].
+// The ACME TLS-ALPN protocol is still added when a certificate manager is configured.
+func WithALPN(protos ...string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.claimSetting("tls.alpn", "WithALPN")
+		s.ownTLSConfig().NextProtos = slices.Clone(protos)
+
+		if !slices.Contains(protos, "h2") && s.TLSNextProto == nil {
+			s.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+	}
+}
+
+// WithH2C enables cleartext HTTP/2 (h2c), both with prior knowledge and through the HTTP/1.1
+// Upgrade mechanism, as needed by gRPC or mesh traffic behind a TLS-terminating proxy.
+// The h2c connections take part in the graceful shutdown: they receive a GOAWAY frame as soon
+// as the shutdown begins, and are forcibly closed when the timeout expires.
+func WithH2C() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.h2c = true
+	}
+}
+
+// WithProtocols sets the HTTP versions accepted by the server through [http.Server.Protocols]:
+// HTTP/1.x, HTTP/2 over TLS and unencrypted HTTP/2 with prior knowledge. Disabling all of them
+// is the same as enabling HTTP/1.x only, as [http.Server] does. It takes precedence over
+// the HTTP/2 setting implied by [WithALPN].
+func WithProtocols(http1, http2, unencryptedHTTP2 bool) GracefulServerOption {
+	return func(s *GracefulServer) {
+		var p http.Protocols
+		p.SetHTTP1(http1)
+		p.SetHTTP2(http2)
+		p.SetUnencryptedHTTP2(unencryptedHTTP2)
+
+		s.Protocols = &p
+	}
+}
+
+// WithTLSNextProto sets [http.Server.TLSNextProto], taking over the TLS connections on which the client
+// negotiated one of the protocols through ALPN, e.g. to serve a custom protocol next to HTTP. The protocols
+// must be advertised through [WithALPN] as well. As with [http.Server], HTTP/2 over TLS is disabled unless
+// "h2" is a key of the map: an empty map disables it explicitly, as [WithALPN] does when "h2" is not listed.
+// [WithProtocols] takes precedence: enabling HTTP/2 through it adds "h2" to a copy of the map.
+// A nil map restores the default. The connections taken over are drained as active until their function
+// returns, which should happen once [GracefulServer.Draining] is closed.
+func WithTLSNextProto(nextProto map[string]func(*http.Server, *tls.Conn, http.Handler)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.TLSNextProto = maps.Clone(nextProto)
+	}
+}
+
+// WithHTTP2MaxConcurrentStreams sets the number of concurrent streams each HTTP/2 client may open.
+// The HTTP/2 options serve the HTTP/2 connections with the [http2.Server] configured
+// through [http2.ConfigureServer], over TLS and unencrypted.
+func WithHTTP2MaxConcurrentStreams(n uint32) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.http2Server().MaxConcurrentStreams = n
+	}
+}
+
+// WithHTTP2IdleTimeout sets how long an idle HTTP/2 connection is kept open before being closed.
+// It defaults to the IdleTimeout of the [http.Server].
+func WithHTTP2IdleTimeout(timeout time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if timeout < 0 {
+			s.invalidOption("WithHTTP2IdleTimeout", "negative timeout %v", timeout)
+		}
+
+		s.http2Server().IdleTimeout = timeout
+	}
+}
+
+// WithHTTP2ReadIdleTimeout enables the health check of the HTTP/2 connections, sending a PING frame
+// when no frame is received for the provided timeout, so that dead connections are not kept open.
+func WithHTTP2ReadIdleTimeout(timeout time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if timeout < 0 {
+			s.invalidOption("WithHTTP2ReadIdleTimeout", "negative timeout %v", timeout)
+		}
+
+		s.http2Server().ReadIdleTimeout = timeout
+	}
+}
+
+// WithHTTP2PingTimeout sets how long the health check waits for the response to a PING frame
+// before closing the HTTP/2 connection. It defaults to 15 seconds.
+func WithHTTP2PingTimeout(timeout time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if timeout < 0 {
+			s.invalidOption("WithHTTP2PingTimeout", "negative timeout %v", timeout)
+		}
+
+		s.http2Server().PingTimeout = timeout
+	}
+}
+
+// WithHTTP2GoAwayLead sends a GOAWAY frame to the HTTP/2 connections as soon as the shutdown begins,
+// then keeps serving as usual for the lead time before the drain, letting well-behaved clients
+// migrate their new requests to another backend without resetting the streams in flight.
+func WithHTTP2GoAwayLead(lead time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if lead < 0 {
+			s.invalidOption("WithHTTP2GoAwayLead", "negative lead %v", lead)
+		}
+
+		s.http2Server()
+		s.h2GoAwayLead = lead
+	}
+}
+
+// WithHTTPRedirect runs a companion server on the provided address (e.g. ":http") answering every
+// request with a 301 Moved Permanently redirect to the same URL over HTTPS. It is started and shut
+// down gracefully together with the server, when serving TLS. With a certificate manager answering
+// the ACME HTTP-01 challenges on the same address, its companion server already redirects to HTTPS.
+func WithHTTPRedirect(addr string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.httpRedirectAddr = addr
+	}
+}
+
+// WithListenConfig sets the [net.ListenConfig] used to create the listeners, giving access to the
+// socket options through its Control function (e.g. SO_REUSEPORT, TCP_FASTOPEN or SO_BINDTODEVICE).
+func WithListenConfig(lc net.ListenConfig) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.replaceSettings("listen", "WithListenConfig")
+		s.listenConfig = lc
+	}
+}
+
+// WithNetwork sets the network of the TCP listeners: "tcp4" binds IPv4 only and "tcp6" IPv6 only, so that e.g.
+// ":8080" is not bound on both families of a dual-stack host, while "tcp", the default, binds both.
+func WithNetwork(network string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		switch network {
+		case "tcp", "tcp4", "tcp6":
+			s.network = network
+		default:
+			s.invalidOption("WithNetwork", "unsupported network %q", network)
+		}
+	}
+}
+
+// WithTCPKeepAlive sets the TCP keep-alive of the accepted connections, e.g. to align it with the
+// idle timeout of a load balancer. A non-positive period falls back to the default of 15 seconds,
+// while enabled false disables the keep-alive. Use it after [WithListenConfig], which replaces
+// the whole listen configuration.
+func WithTCPKeepAlive(period time.Duration, enabled bool) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if enabled && period < 0 {
+			s.invalidOption("WithTCPKeepAlive", "negative period %v", period)
+		}
+
+		s.claimSetting("listen.keepalive", "WithTCPKeepAlive")
+
+		switch {
+		case !enabled:
+			s.listenConfig.KeepAlive = -1
+		case period <= 0:
+			s.listenConfig.KeepAlive = 0
+		default:
+			s.listenConfig.KeepAlive = period
+		}
+	}
+}
+
+// WithMaxConnections limits the number of connections simultaneously open on each listener:
+// beyond the limit, the server stops accepting until a connection is closed, instead of
+// exhausting the file descriptors under load spikes. A non-positive n means no limit.
+func WithMaxConnections(n int) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if n < 0 {
+			s.invalidOption("WithMaxConnections", "negative limit %d", n)
+		}
+
+		s.maxConns = n
+	}
+}
+
+// WithAcceptRateLimit throttles the accepted connections with a token bucket refilled at rps tokens
+// per second and holding up to burst tokens, shared by all the listeners, as a first line of defense
+// against connection floods. A non-positive rps means no limit, and burst is at least 1.
+func WithAcceptRateLimit(rps float64, burst int) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if rps < 0 || burst < 0 {
+			s.invalidOption("WithAcceptRateLimit", "negative rate %v or burst %d", rps, burst)
+		}
+
+		if rps <= 0 {
+			s.acceptLimiter = nil
+			return
+		}
+
+		s.acceptLimiter = rate.NewLimiter(rate.Limit(rps), max(burst, 1))
+	}
+}
+
+// WithTLSHandshakeTimeout closes the connections which have not completed the TLS handshake and sent
+// the headers of their first request within d after being accepted, so that clients slow to handshake
+// cannot pin connections: the deadlines set by [http.Server] restart once the handshake is complete.
+// The deadlines set by the server afterwards are left untouched. A non-positive d means no limit.
+func WithTLSHandshakeTimeout(d time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if d < 0 {
+			s.invalidOption("WithTLSHandshakeTimeout", "negative timeout %v", d)
+		}
+
+		s.handshakeTimeout = d
+	}
+}
+
+// WithListenerWrapper wraps the listeners created by the server once they are bound, e.g. to
+// multiplex a port between several protocols or to apply custom accept policies. The wrappers
+// are applied in order, and the TLS layer, if any, is added on top of them.
+func WithListenerWrapper(wrap func(ln net.Listener) net.Listener) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.listenerWrappers = append(s.listenerWrappers, wrap)
+	}
+}
+
+// WithBaseContext sets the function returning the base context of the requests accepted on a listener.
+// By default, the base context carries the values of the context passed to the ListenAndServe methods,
+// without being canceled when the shutdown begins, so that the in-flight requests can complete.
+func WithBaseContext(fn func(ln net.Listener) context.Context) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.BaseContext = fn
+	}
+}
+
+// WithConnContext sets the function modifying the context of the requests accepted on a connection,
+// derived from the base context.
+func WithConnContext(fn func(ctx context.Context, c net.Conn) context.Context) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.ConnContext = fn
+	}
+}
+
+// WithDrainRejection makes the server answer requests arriving after the shutdown has begun
+// with 503 Service Unavailable and a Retry-After header, while already-started requests
+// are allowed to finish. A non-positive retryAfter falls back to 1 second.
+func WithDrainRejection(retryAfter time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if retryAfter < 0 {
+			s.invalidOption("WithDrainRejection", "negative retry after %v", retryAfter)
+		}
+
+		s.rejectWhileDraining = true
+
+		if retryAfter <= 0 {
+			s.retryAfter = defaultRetryAfter
+			return
+		}
+
+		s.retryAfter = retryAfter
+	}
+}
+
+// WithDrainExemptPaths keeps serving the requests for the given paths (e.g. /healthz or /metrics)
+// normally after the shutdown has begun, while the other requests are answered with 503 Service Unavailable
+// as with [WithDrainRejection], so that monitoring systems do not see the instance as dead while it drains.
+func WithDrainExemptPaths(paths ...string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.rejectWhileDraining = true
+
+		if s.retryAfter <= 0 {
+			s.retryAfter = defaultRetryAfter
+		}
+
+		for _, path := range paths {
+			if s.drainExemptPaths == nil {
+				s.drainExemptPaths = make(map[string]struct{})
+			}
+
+			s.drainExemptPaths[path] = struct{}{}
+		}
+	}
+}
+
+// WithMaxInFlightRequests limits the number of requests processed concurrently, answering the excess
+// ones with 503 Service Unavailable and a Retry-After header, while the listener keeps accepting.
+// A non-positive n means no limit, and a non-positive retryAfter falls back to 1 second.
+func WithMaxInFlightRequests(n int, retryAfter time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if n < 0 || retryAfter < 0 {
+			s.invalidOption("WithMaxInFlightRequests", "negative limit %d or retry after %v", n, retryAfter)
+		}
+
+		s.maxInFlight = n
+
+		if retryAfter <= 0 {
+			s.inFlightRetryAfter = defaultRetryAfter
+			return
+		}
+
+		s.inFlightRetryAfter = retryAfter
+	}
+}
+
+// WithRequestQueue queues up to n requests exceeding the limit set through [WithMaxInFlightRequests],
+// each one waiting up to timeout for another request to complete, to smooth out the bursts.
+// The requests that cannot be queued, or that time out in the queue, are answered with 503 Service
+// Unavailable and a Retry-After header. A non-positive n or timeout disables the queue.
+// It has no effect without a limit on the in-flight requests.
+func WithRequestQueue(n int, timeout time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if n < 0 || timeout < 0 {
+			s.invalidOption("WithRequestQueue", "negative size %d or timeout %v", n, timeout)
+		}
+
+		if n <= 0 || timeout <= 0 {
+			s.queueSize, s.queueTimeout = 0, 0
+			return
+		}
+
+		s.queueSize = n
+		s.queueTimeout = timeout
+	}
+}
+
+// WithSlowStart ramps up linearly, from 1 to the limit set through [WithMaxInFlightRequests],
+// the number of requests processed concurrently during the window following the listener bind,
+// so that cold caches and connection pools are not overwhelmed as soon as the server is ready.
+// It has no effect without a limit on the in-flight requests.
+func WithSlowStart(window time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if window < 0 {
+			s.invalidOption("WithSlowStart", "negative window %v", window)
+		}
+
+		s.slowStart = window
+	}
+}
+
+// WithMaxRequestBodySize limits the size of the request bodies to n bytes through [http.MaxBytesHandler].
+// Requests declaring a longer body are answered with 413 Request Entity Too Large, while the handlers
+// reading beyond the limit of a body of unknown length receive an [http.MaxBytesError].
+// A non-positive n means no limit.
+func WithMaxRequestBodySize(n int64) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if n < 0 {
+			s.invalidOption("WithMaxRequestBodySize", "negative size %d", n)
+		}
+
+		s.maxBodySize = n
+	}
+}
+
+// WithDrainConnectionClose adds the "Connection: close" header to HTTP/1.x responses written
+// while the server is draining, so that keep-alive clients disconnect as soon as their
+// current request completes instead of holding the connection until the timeout.
+func WithDrainConnectionClose() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.closeWhileDraining = true
+	}
+}
+
+// WithDrainStrategy sets the strategy draining the connections when the shutdown begins,
+// in place of [DefaultDrainStrategy].
+func WithDrainStrategy(strategy DrainStrategy) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.drainStrategy = strategy
+	}
+}
+
+// WithForceCloseOrder closes the connections tagged through [GracefulServer.TagConn] group by group
+// once the graceful timeout expires, in the order of the tags and waiting for the interval between the groups,
+// then closes the remaining connections together with the last group.
+// The groups listed last, e.g. "interactive", get the longest runway.
+func WithForceCloseOrder(interval time.Duration, tags ...string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if interval < 0 {
+			s.invalidOption("WithForceCloseOrder", "negative interval %v", interval)
+		}
+
+		s.forceCloseInterval = max(interval, 0)
+		s.forceCloseOrder = slices.Clone(tags)
+	}
+}
+
+// WithCloseIdleOnDrain disables the keep-alives as soon as the shutdown begins, closing the idle
+// connections right away and the active ones once their response is written, rather than
+// leaving them to the polling of [http.Server.Shutdown] or to a custom [DrainStrategy].
+func WithCloseIdleOnDrain() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.closeIdleOnDrain = true
+	}
+}
+
+// WithDisableKeepAlives disables the keep-alives from the start, closing every connection once its response
+// is written, e.g. for short-lived deployments behind a load balancer which spreads the requests evenly
+// only across new connections. The drain then only waits for the requests in flight.
+func WithDisableKeepAlives() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.disableKeepAlives = true
+	}
+}
+
+// WithDisableGeneralOptionsHandler passes the "OPTIONS *" requests to the handler, instead of answering them
+// with 200 OK and an empty body, as set by [http.Server.DisableGeneralOptionsHandler], e.g. for the proxies
+// and the test suites expecting the server-wide options of RFC 9110 to be described by the application.
+func WithDisableGeneralOptionsHandler() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.DisableGeneralOptionsHandler = true
+	}
+}
+
+// WithOnForceClose sets a callback invoked when the graceful timeout expires, reporting the
+// connections (including the tracked hijacked ones) that are going to be forcibly closed.
+func WithOnForceClose(fn func(conns []ConnInfo)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.onForceClose = fn
+	}
+}
+
+// WithHandlerTimeout bounds the time given to the handler to answer through [http.TimeoutHandler],
+// replying with 503 Service Unavailable and msg, or a default message if empty, once the timeout expires.
+// It keeps slow handlers from using up the write timeout and the graceful timeout, but the responses are
+// buffered, and cannot be flushed or hijacked. A non-positive duration means no timeout.
+func WithHandlerTimeout(d time.Duration, msg string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if d < 0 {
+			s.invalidOption("WithHandlerTimeout", "negative duration %v", d)
+		}
+
+		s.handlerTimeout = max(d, 0)
+		s.handlerTimeoutMsg = msg
+	}
+}
+
+// WithPanicRecovery recovers the panics of the handler, which would otherwise abort the response and close
+// the connection, answering with 500 Internal Server Error instead. The panics are logged through the logger
+// set by [WithLogger], if any, and reported to fn, if not nil, which may write a response of its own.
+func WithPanicRecovery(fn func(w http.ResponseWriter, r *http.Request, v any)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.panicRecovery = true
+		s.onPanic = fn
+	}
+}
+
+// WithPanicShutdown starts the graceful shutdown once the handler panicked n times within the window,
+// or n times overall if the window is not positive, so that the orchestrator replaces an instance which
+// may be left in a corrupted state. The panics are recovered as with [WithPanicRecovery], and the serving
+// methods return [ErrPanicShutdown]. An n lower than 1 shuts the server down on the first panic.
+func WithPanicShutdown(n int, window time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if window < 0 {
+			s.invalidOption("WithPanicShutdown", "negative window %v", window)
+		}
+
+		s.panicRecovery = true
+		s.panicShutdown = &panicTracker{
+			limit:  max(n, 1),
+			window: max(window, 0),
+		}
+	}
+}
+
+// WithDrainProgress sets a callback invoked at every interval during the shutdown, reporting the
+// connections (including the tracked hijacked ones) still open and the time elapsed since the drain began.
+// A non-positive interval defaults to one second.
+func WithDrainProgress(interval time.Duration, fn func(remaining int, elapsed time.Duration)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if interval < 0 {
+			s.invalidOption("WithDrainProgress", "negative interval %v", interval)
+		}
+
+		if interval <= 0 {
+			interval = defaultDrainProgressInterval
+		}
+
+		s.drainProgressInterval = interval
+		s.onDrainProgress = fn
+	}
+}
+
+// WithLogger sets the logger reporting the shutdown sequence, such as the requests
+// still in flight during the drain. Unless set, the ErrorLog of the server forwards
+// its messages to the logger as well, through [NewErrorLog]. A nil logger unsets it.
+func WithLogger(logger Logger) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if l, ok := logger.(*slog.Logger); ok && l == nil {
+			logger = nil
+		}
+
+		s.logger = logger
+	}
+}
+
+// WithDrainLogInterval sets the interval between the logs of the requests still in flight during the drain,
+// when a logger is set. A non-positive interval defaults to five seconds.
+func WithDrainLogInterval(interval time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if interval < 0 {
+			s.invalidOption("WithDrainLogInterval", "negative interval %v", interval)
+		}
+
+		if interval <= 0 {
+			interval = defaultDrainLogInterval
+		}
+
+		s.drainLogInterval = interval
+	}
+}
+
+// WithOnReady sets a callback invoked once the listener is bound
+// and the server is about to accept connections.
+func WithOnReady(fn func()) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.onReady = fn
+	}
+}
+
+// WithClock sets the [Clock] used to measure the graceful timeout.
+// It is mainly intended for tests, to simulate the timeout expiring without real sleeps.
+func WithClock(clock Clock) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if clock == nil {
+			s.invalidOption("WithClock", "nil clock")
+			s.clock = realClock{}
+			return
+		}
+
+		s.clock = clock
+	}
+}
+
+// WithACMEChallengeAddr sets the address of the companion server answering the ACME HTTP-01
+// challenges when the certificates are obtained by a [CertManager] implementing [HTTPChallengeHandler].
+// It defaults to port 80, which the certificate authority connects to; override it
+// only when the port is mapped, e.g. by a container runtime. An empty address disables the companion server.
+func WithACMEChallengeAddr(addr string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.acmeChallengeAddr = addr
+	}
+}
+
+// WithCertificateManager delegates the issuance and renewal of the certificates to the provided [CertManager],
+// such as an [autocert.Manager] or a CertMagic configuration adapted with [CertMagic],
+// while the server keeps owning the lifecycle and the graceful shutdown.
+// If the manager implements [HTTPChallengeHandler], a companion server answers the ACME HTTP-01 challenges.
+func WithCertificateManager(m CertManager) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.certManager = m
+	}
+}
+
+// WithCertReload serves the certificate loaded from the provided files, checking them for changes
+// every minute and swapping the certificate without restarting the server, e.g. when it is rotated
+// by cert-manager. The files of a Kubernetes secret volume are followed through the symlink swap of its
+// ..data directory, so that a rotation is picked up even if the files keep their size and time, and the
+// certificate and the key are always read from the same version of the secret.
+// A certificate that fails to load is ignored and the previous one keeps being served.
+// Pass empty certFile and keyFile to [GracefulServer.ListenAndServeTLSWithShutdown] when using this option.
+func WithCertReload(certFile, keyFile string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.reloadCertFile = certFile
+		s.reloadKeyFile = keyFile
+	}
+}
+
+// WithCertReloadOnSignal reloads the certificate every time one of the signals (SIGHUP by default)
+// is received, as nginx does, for operators who rotate certificates with external tooling.
+// It reloads the files provided through [WithCertReload], if any, otherwise the ones passed to
+// [GracefulServer.ListenAndServeTLSWithShutdown]. A certificate that fails to load is ignored and
+// the previous one keeps being served.
+func WithCertReloadOnSignal(sig ...os.Signal) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if len(sig) == 0 {
+			s.reloadSignals = []os.Signal{syscall.SIGHUP}
+			return
+		}
+
+		s.reloadSignals = sig
+	}
+}
+
+// WithKeyPairPEM serves the provided PEM encoded certificate and private key, e.g. fetched from
+// a secrets manager, without writing them to files. It can be used multiple times to serve several
+// certificates, selected through SNI. Pass empty certFile and keyFile to
+// [GracefulServer.ListenAndServeTLSWithShutdown] when using this option.
+func WithKeyPairPEM(certPEM, keyPEM []byte) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.certLoaders = append(s.certLoaders, func() (tls.Certificate, error) {
+			return tls.X509KeyPair(certPEM, keyPEM)
+		})
+	}
+}
+
+// WithCertificateFromFS serves the PEM encoded certificate and private key read from the file system,
+// such as an [embed.FS] bundling development certificates. It can be used multiple times to serve
+// several certificates, selected through SNI. Pass empty certFile and keyFile to
+// [GracefulServer.ListenAndServeTLSWithShutdown] when using this option.
+func WithCertificateFromFS(fsys fs.FS, certPath, keyPath string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.certLoaders = append(s.certLoaders, func() (tls.Certificate, error) {
+			return loadKeyPairFS(fsys, certPath, keyPath)
+		})
+	}
+}
+
+// WithClientCRL rejects, during the TLS handshake, the client certificates revoked by the certificate
+// revocation lists loaded from the sources, which are file paths or http(s) URLs of DER or PEM encoded CRLs.
+// Only the lists signed by the issuer of a certificate are taken into account. The lists are reloaded
+// at every refresh interval, which must be positive, keeping the previous ones on failure. They are reloaded once
+// their next update is due as well, and rejected, with [ErrCRLExpired], once past it, as are the certificates
+// they cover if no newer list is published. The lists are downloaded within a timeout of ten seconds,
+// and rejected if larger than 32 MiB.
+// It requires a TLS configuration verifying the client certificates, e.g. through [WithTLSConfig]
+// with ClientAuth set to [tls.RequireAndVerifyClientCert].
+func WithClientCRL(refresh time.Duration, sources ...string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if refresh <= 0 {
+			s.invalidOption("WithClientCRL", "non-positive refresh %v", refresh)
+		}
+
+		if len(sources) == 0 {
+			s.invalidOption("WithClientCRL", "no sources")
+		}
+
+		s.crlRefresh = refresh
+		s.crlSources = sources
+	}
+}
+
+// WithCertificates terminates TLS for several hosts, selecting the certificate by the server name
+// indicated by the client (SNI). Hosts can be wildcards, e.g. "*.example.com". Server names matching
+// no host are served by the other certificates configured, if any. Pass empty certFile and keyFile to
+// [GracefulServer.ListenAndServeTLSWithShutdown] when using this option, unless a default certificate is needed.
+func WithCertificates(pairs map[string]CertPair) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if len(pairs) == 0 {
+			s.invalidOption("WithCertificates", "no certificates")
+		}
+
+		for _, host := range slices.Sorted(maps.Keys(pairs)) {
+			if pair := pairs[host]; pair.CertFile == "" || pair.KeyFile == "" {
+				s.invalidOption("WithCertificates", "empty certificate or key file for host %q", host)
+			}
+		}
+
+		s.sniCertPairs = pairs
+	}
+}
+
+// WithGetCertificate selects the certificate of each TLS handshake dynamically through fn, set as the
+// [tls.Config.GetCertificate] callback, without building a whole TLS configuration. The certificates set
+// through [WithCertificates] take precedence for the hosts they match. Pass empty certFile and keyFile to
+// [GracefulServer.ListenAndServeTLSWithShutdown] when using this option.
+func WithGetCertificate(fn func(hello *tls.ClientHelloInfo) (*tls.Certificate, error)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if fn == nil {
+			s.invalidOption("WithGetCertificate", "nil callback")
+			return
+		}
+
+		s.claimSetting("tls.certificate", "WithGetCertificate")
+		s.ownTLSConfig().GetCertificate = fn
+	}
+}
+
+// WithSessionTicketRotation encrypts the TLS session tickets with a key rotated at every interval,
+// keeping the previous key valid for resumption, instead of a single key for the lifetime of the process.
+// Sessions older than twice the interval can no longer be resumed. The interval must be positive.
+func WithSessionTicketRotation(interval time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if interval <= 0 {
+			s.invalidOption("WithSessionTicketRotation", "non-positive interval %v", interval)
+		}
+
+		s.ticketRotation = interval
 	}
 }
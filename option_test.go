@@ -1,7 +1,11 @@
 package gracefulhttp
 
 import (
+	"context"
 	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
 	"reflect"
 	"testing"
 	"time"
@@ -48,6 +52,55 @@ func TestWithCloudflareTimeouts(t *testing.T) {
 	}
 }
 
+func TestWithGCPLoadBalancerTimeouts(t *testing.T) {
+	s := GracefulServer{}
+	s.ReadTimeout = time.Second
+	s.WriteTimeout = time.Second
+	WithGCPLoadBalancerTimeouts()(&s)
+
+	if s.IdleTimeout <= 600*time.Second {
+		t.Errorf("WithGCPLoadBalancerTimeouts() IdleTimeout = %v, want more than the load balancer keepalive", s.IdleTimeout)
+	}
+	if s.ReadHeaderTimeout != gcpReadHeaderTimeout {
+		t.Errorf("WithGCPLoadBalancerTimeouts() ReadHeaderTimeout = %v, want %v", s.ReadHeaderTimeout, gcpReadHeaderTimeout)
+	}
+	if s.ReadTimeout != 0 || s.WriteTimeout != 0 {
+		t.Errorf("WithGCPLoadBalancerTimeouts() ReadTimeout = %v, WriteTimeout = %v, want 0", s.ReadTimeout, s.WriteTimeout)
+	}
+}
+
+func TestWithNginxUpstreamTimeouts(t *testing.T) {
+	s := GracefulServer{}
+	s.ReadTimeout = time.Second
+	s.WriteTimeout = time.Second
+	WithNginxUpstreamTimeouts()(&s)
+
+	if s.IdleTimeout <= 60*time.Second {
+		t.Errorf("WithNginxUpstreamTimeouts() IdleTimeout = %v, want more than the nginx keepalive_timeout", s.IdleTimeout)
+	}
+	if s.ReadHeaderTimeout != nginxReadHeaderTimeout {
+		t.Errorf("WithNginxUpstreamTimeouts() ReadHeaderTimeout = %v, want %v", s.ReadHeaderTimeout, nginxReadHeaderTimeout)
+	}
+	if s.ReadTimeout != 0 || s.WriteTimeout != 0 {
+		t.Errorf("WithNginxUpstreamTimeouts() ReadTimeout = %v, WriteTimeout = %v, want 0", s.ReadTimeout, s.WriteTimeout)
+	}
+}
+
+func TestWithHerokuTimeouts(t *testing.T) {
+	s := GracefulServer{}
+	WithHerokuTimeouts()(&s)
+
+	if s.WriteTimeout != 30*time.Second {
+		t.Errorf("WithHerokuTimeouts() WriteTimeout = %v, want the router request timeout", s.WriteTimeout)
+	}
+	if s.IdleTimeout <= 55*time.Second {
+		t.Errorf("WithHerokuTimeouts() IdleTimeout = %v, want more than the router idle timeout", s.IdleTimeout)
+	}
+	if s.gracefulTimeout >= 30*time.Second {
+		t.Errorf("WithHerokuTimeouts() gracefulTimeout = %v, want less than the dyno shutdown allowance", s.gracefulTimeout)
+	}
+}
+
 func TestWithShutdownTimeout(t *testing.T) {
 	type args struct {
 		duration time.Duration
@@ -92,6 +145,35 @@ func TestWithShutdownTimeout(t *testing.T) {
 	}
 }
 
+func TestWithForceCloseTimeout_Duration(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     time.Duration
+	}{
+		{
+			name:     "positive timeout",
+			duration: 2 * time.Second,
+			want:     2 * time.Second,
+		},
+		{
+			name:     "negative timeout",
+			duration: -time.Second,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := GracefulServer{}
+			WithForceCloseTimeout(tt.duration)(&s)
+
+			if got := s.forceTimeout; got != tt.want {
+				t.Errorf("WithForceCloseTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWithCloudflareTLSConfig(t *testing.T) {
 	type config struct {
 		MinVersion       uint16
@@ -169,3 +251,234 @@ func TestWithTLSConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestWithTLSConfig_Clone(t *testing.T) {
+	shared := &tls.Config{
+		MinVersion: tls.VersionTLS10,
+	}
+
+	tests := []struct {
+		name           string
+		opts           []GracefulServerOption
+		wantMinVersion uint16
+	}{
+		{
+			name:           "tls config only",
+			opts:           []GracefulServerOption{WithTLSConfig(shared)},
+			wantMinVersion: tls.VersionTLS10,
+		},
+		{
+			name:           "cloudflare after tls config hardens the copy",
+			opts:           []GracefulServerOption{WithTLSConfig(shared), WithCloudflareTLSConfig()},
+			wantMinVersion: defaultTLSMinVersion,
+		},
+		{
+			name:           "tls config after cloudflare replaces the hardening",
+			opts:           []GracefulServerOption{WithCloudflareTLSConfig(), WithTLSConfig(shared)},
+			wantMinVersion: tls.VersionTLS10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := GracefulServer{}
+			for _, opt := range tt.opts {
+				opt(&s)
+			}
+
+			if s.TLSConfig == shared {
+				t.Errorf("TLSConfig is the caller's configuration")
+			}
+
+			if got := s.TLSConfig.MinVersion; got != tt.wantMinVersion {
+				t.Errorf("MinVersion = %v, want %v", got, tt.wantMinVersion)
+			}
+
+			if shared.MinVersion != tls.VersionTLS10 {
+				t.Errorf("caller's configuration altered")
+			}
+		})
+	}
+
+	t.Run("cloudflare does not alter a config set on the server", func(t *testing.T) {
+		s := GracefulServer{}
+		s.TLSConfig = shared
+
+		WithCloudflareTLSConfig()(&s)
+
+		if shared.MinVersion != tls.VersionTLS10 {
+			t.Errorf("caller's configuration altered")
+		}
+	})
+}
+
+func TestWithALPN(t *testing.T) {
+	tests := []struct {
+		name          string
+		protos        []string
+		wantH2Enabled bool
+	}{
+		{
+			name:          "h2 and http/1.1",
+			protos:        []string{"h2", "http/1.1"},
+			wantH2Enabled: true,
+		},
+		{
+			name:          "http/1.1 only",
+			protos:        []string{"http/1.1"},
+			wantH2Enabled: false,
+		},
+		{
+			name:          "custom protocol",
+			protos:        []string{"acme-tls/1", "http/1.1"},
+			wantH2Enabled: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shared := &tls.Config{NextProtos: []string{"h3"}}
+
+			s := GracefulServer{}
+			WithTLSConfig(shared)(&s)
+			WithALPN(tt.protos...)(&s)
+
+			if got := s.TLSConfig.NextProtos; !reflect.DeepEqual(got, tt.protos) {
+				t.Errorf("NextProtos = %v, want %v", got, tt.protos)
+			}
+
+			if !reflect.DeepEqual(shared.NextProtos, []string{"h3"}) {
+				t.Errorf("caller's configuration altered")
+			}
+
+			if got := s.TLSNextProto == nil; got != tt.wantH2Enabled {
+				t.Errorf("HTTP/2 enabled = %v, want %v", got, tt.wantH2Enabled)
+			}
+		})
+	}
+}
+
+func TestWithProtocols(t *testing.T) {
+	tests := []struct {
+		name             string
+		http1            bool
+		http2            bool
+		unencryptedHTTP2 bool
+		want             string
+	}{
+		{
+			name:  "http1 and http2",
+			http1: true,
+			http2: true,
+			want:  "{HTTP1,HTTP2}",
+		},
+		{
+			name:  "http1 only",
+			http1: true,
+			want:  "{HTTP1}",
+		},
+		{
+			name:             "unencrypted http2 only",
+			unencryptedHTTP2: true,
+			want:             "{UnencryptedHTTP2}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := GracefulServer{}
+			WithALPN("http/1.1")(&s)
+			WithProtocols(tt.http1, tt.http2, tt.unencryptedHTTP2)(&s)
+
+			if s.Protocols == nil {
+				t.Fatalf("Protocols is nil")
+			}
+
+			if got := s.Protocols.String(); got != tt.want {
+				t.Errorf("Protocols = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTLSNextProto(t *testing.T) {
+	nextProto := map[string]func(*http.Server, *tls.Conn, http.Handler){
+		"echo/1": func(_ *http.Server, c *tls.Conn, _ http.Handler) {
+			_, _ = io.Copy(c, c)
+		},
+	}
+
+	certFile, keyFile := testKeyPairFiles(t)
+	s := Bind("localhost:0", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, certFile, keyFile,
+			WithTLSNextProto(nextProto), WithALPN("echo/1", "http/1.1"), WithShutdownTimeout(100*time.Millisecond))
+	}()
+	<-s.Ready()
+
+	c, err := tls.Dial("tcp", s.ListenerAddr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"echo/1"}})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	if got := c.ConnectionState().NegotiatedProtocol; got != "echo/1" {
+		t.Errorf("NegotiatedProtocol = %v, want echo/1", got)
+	}
+
+	_, _ = c.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(c, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("echo = %q, %v, want ping", buf, err)
+	}
+
+	if _, ok := s.TLSNextProto["h2"]; ok {
+		t.Errorf("HTTP/2 enabled, want disabled")
+	}
+
+	if _, ok := nextProto["h2"]; ok || len(nextProto) != 1 {
+		t.Errorf("caller's map altered")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestWithTCPKeepAlive(t *testing.T) {
+	tests := []struct {
+		name    string
+		period  time.Duration
+		enabled bool
+		want    time.Duration
+	}{
+		{
+			name:    "custom period",
+			period:  time.Minute,
+			enabled: true,
+			want:    time.Minute,
+		},
+		{
+			name:    "default period",
+			period:  -time.Second,
+			enabled: true,
+			want:    0,
+		},
+		{
+			name:    "disabled",
+			period:  time.Minute,
+			enabled: false,
+			want:    -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := GracefulServer{}
+			WithListenConfig(net.ListenConfig{KeepAlive: time.Hour})(&s)
+			WithTCPKeepAlive(tt.period, tt.enabled)(&s)
+
+			if got := s.listenConfig.KeepAlive; got != tt.want {
+				t.Errorf("KeepAlive = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
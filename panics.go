@@ -0,0 +1,66 @@
+package gracefulhttp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// panicTracker counts the recent panics of the handler, to shut the server down once
+// they exceed a limit.
+type panicTracker struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	times  []time.Time
+}
+
+// add records a panic at the given time, reporting whether the limit is reached
+// within the window, if any.
+func (t *panicTracker) add(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.times = append(t.times, now)
+	if t.window > 0 {
+		for len(t.times) > 0 && now.Sub(t.times[0]) > t.window {
+			t.times = t.times[1:]
+		}
+	}
+
+	return len(t.times) >= t.limit
+}
+
+// recordPanic counts a panic of the handler, starting the graceful shutdown if too many occurred.
+func (s *GracefulServer) recordPanic() {
+	s.panics.Add(1)
+	s.addCounter(MetricHandlerPanics, 1)
+
+	if s.panicShutdown != nil && s.panicShutdown.add(s.clock.Now()) {
+		s.cancelServing(ErrPanicShutdown)
+	}
+}
+
+// cancelServing starts the shutdown with the given cause, if serving.
+func (s *GracefulServer) cancelServing(cause error) {
+	s.mu.Lock()
+	cancel := s.cancelServe
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel(cause)
+	}
+}
+
+// withServeCancel returns a copy of the serving context that the server can cancel on its own.
+func (s *GracefulServer) withServeCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	s.mu.Lock()
+	s.cancelServe = cancel
+	s.mu.Unlock()
+
+	return ctx, func() {
+		cancel(nil)
+	}
+}
@@ -0,0 +1,62 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_panicTracker_add(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name    string
+		limit   int
+		window  time.Duration
+		offsets []time.Duration
+		want    bool
+	}{
+		{name: "single panic", limit: 1, offsets: []time.Duration{0}, want: true},
+		{name: "below the limit", limit: 3, window: time.Minute, offsets: []time.Duration{0, time.Second}, want: false},
+		{name: "within the window", limit: 2, window: time.Minute, offsets: []time.Duration{0, 30 * time.Second}, want: true},
+		{name: "outside of the window", limit: 2, window: time.Minute, offsets: []time.Duration{0, 2 * time.Minute}, want: false},
+		{name: "no window", limit: 2, offsets: []time.Duration{0, time.Hour}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &panicTracker{limit: tt.limit, window: tt.window}
+
+			var got bool
+			for _, offset := range tt.offsets {
+				got = tracker.add(start.Add(offset))
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithPanicShutdown(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("corrupted")
+	}))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(context.Background(), WithPanicShutdown(2, time.Minute))
+	}()
+	<-s.Ready()
+
+	for range 2 {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_ = r.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	}
+
+	assert.ErrorIs(t, <-errCh, ErrPanicShutdown)
+}
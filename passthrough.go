@@ -0,0 +1,231 @@
+package gracefulhttp
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPeekTimeout is the default time allowed to read the TLS ClientHello of a connection,
+	// to route it by its server name.
+	defaultPeekTimeout = 5 * time.Second
+	// passthroughDialTimeout is the time allowed to connect to the backend of a passthrough connection.
+	passthroughDialTimeout = 10 * time.Second
+)
+
+// errHelloRead aborts the handshake used to read the TLS ClientHello of a connection.
+var errHelloRead = errors.New("gracefulhttp: client hello read")
+
+// WithTLSPassthrough routes the TLS connections by the server name indicated by the client (SNI): those for
+// the hosts of routes, which may be wildcards (e.g. "*.example.com"), are not terminated but proxied as is to
+// the backend address of their host, e.g. a legacy TLS service sharing the port, while the others are served
+// by the handler. The proxied connections take part in the graceful shutdown as the hijacked ones: the shutdown
+// waits for them to end, and they are forcibly closed once the graceful timeout expires.
+func WithTLSPassthrough(routes map[string]string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.passthroughRoutes = make(map[string]string, len(routes))
+		for host, addr := range routes {
+			if addr == "" {
+				s.invalidOption("WithTLSPassthrough", "empty backend address for %s", host)
+				continue
+			}
+
+			s.passthroughRoutes[strings.ToLower(host)] = addr
+		}
+	}
+}
+
+// passthroughListener accepts the connections in the background, proxying the ones routed to a backend
+// and returning the others once their TLS ClientHello is read, so that a slow client never blocks the others.
+type passthroughListener struct {
+	net.Listener
+
+	s     *GracefulServer
+	conns chan net.Conn
+	errs  chan error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newPassthroughListener returns a passthroughListener accepting the connections of ln.
+func (s *GracefulServer) newPassthroughListener(ln net.Listener) *passthroughListener {
+	l := &passthroughListener{
+		Listener: ln,
+		s:        s,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+
+	go l.acceptLoop()
+
+	return l
+}
+
+// Accept returns the next connection to be served by the handler.
+func (l *passthroughListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting the connections.
+func (l *passthroughListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+
+	return l.Listener.Close()
+}
+
+// acceptLoop accepts the connections and routes each one in its own goroutine, until the listener is closed.
+// The accept errors are returned by Accept, so that [http.Server] handles them as usual.
+func (l *passthroughListener) acceptLoop() {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		go l.route(c)
+	}
+}
+
+// route proxies the connection to the backend of its server name, if any, or returns it through Accept.
+func (l *passthroughListener) route(c net.Conn) {
+	timeout := cmp.Or(l.s.handshakeTimeout, l.s.ReadHeaderTimeout, defaultPeekTimeout)
+	name, hello, err := peekServerName(c, timeout)
+	c = &prefixConn{Conn: c, prefix: hello}
+
+	if addr, ok := lookupHost(l.s.passthroughRoutes, name); ok && err == nil {
+		l.s.passThrough(c, addr)
+		return
+	}
+
+	select {
+	case l.conns <- c:
+	case <-l.done:
+		_ = c.Close()
+	}
+}
+
+// peekServerName reads the TLS ClientHello of the connection, returning the server name it indicates
+// and the bytes read, to be replayed.
+func peekServerName(c net.Conn, timeout time.Duration) (string, []byte, error) {
+	var buf bytes.Buffer
+	var name string
+
+	_ = c.SetReadDeadline(time.Now().Add(timeout))
+	err := tls.Server(readOnlyConn{Conn: c, r: io.TeeReader(c, &buf)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			name = hello.ServerName
+			return nil, errHelloRead
+		},
+	}).Handshake()
+	_ = c.SetReadDeadline(time.Time{})
+
+	if !errors.Is(err, errHelloRead) {
+		return "", buf.Bytes(), err
+	}
+
+	return name, buf.Bytes(), nil
+}
+
+// passThrough proxies the connection to the backend, tracking it as a hijacked connection
+// so that it takes part in the graceful shutdown.
+func (s *GracefulServer) passThrough(c net.Conn, addr string) {
+	if hc, ok := findConn[*handshakeConn](c); ok {
+		hc.established()
+	}
+
+	untrack := s.TrackHijacked(c, nil)
+	defer untrack()
+	defer func() { _ = c.Close() }()
+
+	backend, err := net.DialTimeout("tcp", addr, passthroughDialTimeout)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("gracefulhttp: passthrough backend unreachable", "addr", addr, "error", err)
+		}
+		return
+	}
+	defer func() { _ = backend.Close() }()
+
+	done := make(chan struct{}, 2)
+	proxy := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		if tc, ok := findConn[*net.TCPConn](dst); ok {
+			_ = tc.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+
+	go proxy(backend, c)
+	go proxy(c, backend)
+
+	<-done
+	<-done
+}
+
+// readOnlyConn is a connection whose reads go through r and whose writes are discarded.
+type readOnlyConn struct {
+	net.Conn
+
+	r io.Reader
+}
+
+// Read reads through r.
+func (c readOnlyConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// Write discards p.
+func (c readOnlyConn) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+// prefixConn is a connection replaying the bytes already read from it.
+type prefixConn struct {
+	net.Conn
+
+	prefix []byte
+}
+
+// Read reads the bytes left to replay first, then from the connection.
+func (c *prefixConn) Read(p []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(p, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+
+	return c.Conn.Read(p)
+}
+
+// NetConn returns the wrapped connection.
+func (c *prefixConn) NetConn() net.Conn {
+	return c.Conn
+}
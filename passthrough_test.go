@@ -0,0 +1,82 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTLSPassthrough(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "backend")
+	}))
+	defer backend.Close()
+
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "local")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdownCert(ctx, []tls.Certificate{cert},
+			WithTLSPassthrough(map[string]string{"*.legacy.example.com": backend.Listener.Addr().String()}),
+			WithShutdownTimeout(200*time.Millisecond))
+	}()
+	<-s.Ready()
+	addr := s.ListenerAddr().String()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+	pool.AddCert(backend.Certificate())
+
+	get := func(serverName string) string {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: serverName, InsecureSkipVerify: serverName != "localhost"},
+		}}
+		defer client.CloseIdleConnections()
+
+		r, err := client.Get("https://" + addr)
+		require.NoError(t, err)
+		defer func() { _ = r.Body.Close() }()
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "local", get("localhost"))
+	assert.Equal(t, "backend", get("api.Legacy.example.com"))
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: "api.legacy.example.com", InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	assert.Equal(t, backend.Certificate().Raw, conn.ConnectionState().PeerCertificates[0].Raw)
+
+	// The passthrough connection is still open when the shutdown begins: it is forcibly closed.
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown not completed")
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), s.Stats().ForceClosedConns)
+}
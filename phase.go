@@ -0,0 +1,102 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+)
+
+// Phase is a phase of the lifecycle of a [GracefulServer], in which an error may occur.
+type Phase int
+
+const (
+	// PhaseListen is the creation of the listeners.
+	PhaseListen Phase = iota
+	// PhaseServe is the serving of the connections, until the shutdown begins.
+	PhaseServe
+	// PhaseDrain is the graceful shutdown, waiting for the active connections to complete.
+	PhaseDrain
+	// PhaseForceClose is the forced close of the connections left once the graceful timeout expires.
+	PhaseForceClose
+	// PhaseCleanup is the run of the cleanup hooks registered through [GracefulServer.RegisterCleanup].
+	PhaseCleanup
+)
+
+// String returns the name of the phase.
+func (p Phase) String() string {
+	switch p {
+	case PhaseListen:
+		return "listen"
+	case PhaseServe:
+		return "serve"
+	case PhaseDrain:
+		return "drain"
+	case PhaseForceClose:
+		return "force close"
+	case PhaseCleanup:
+		return "cleanup"
+	default:
+		return "unknown"
+	}
+}
+
+// GracefulError is an error returned by the serving methods of a [GracefulServer], with the phase
+// of the lifecycle in which it occurred, so that e.g. a listener failing to bind can be told apart
+// from a failed drain through [errors.As]. The errors of several phases are joined.
+type GracefulError struct {
+	// Phase is the phase in which the error occurred.
+	Phase Phase
+	// Err is the error.
+	Err error
+}
+
+// Error returns the message of the wrapped error.
+func (e *GracefulError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *GracefulError) Unwrap() error {
+	return e.Err
+}
+
+// phaseError wraps the error, if any, in a [GracefulError] of the phase.
+func phaseError(phase Phase, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &GracefulError{Phase: phase, Err: err}
+}
+
+// WithOnError sets a callback invoked as soon as an error occurs: when a listener cannot be created,
+// when serving fails, when the graceful shutdown fails, when the connections are forcibly closed,
+// reporting [ErrDrainTimeout] if the graceful timeout expired, and when a cleanup hook fails.
+// It lets the errors be reported at the moment of the failure, rather than once the serving method
+// returns. It may be invoked concurrently.
+func WithOnError(fn func(phase Phase, err error)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.onError = fn
+	}
+}
+
+// reportError emits the error event and invokes the error callback, if any.
+func (s *GracefulServer) reportError(phase Phase, err error) {
+	if err == nil {
+		return
+	}
+
+	s.emit(Event{Type: EventError, Phase: phase, Err: err})
+
+	if s.onError != nil {
+		s.onError(phase, err)
+	}
+}
+
+// reportForceClose reports the forced close of the connections, and why it was needed.
+func (s *GracefulServer) reportForceClose(drainCtx context.Context, err error) {
+	if !errors.Is(err, ErrDrainTimeout) && errors.Is(drainCtx.Err(), context.DeadlineExceeded) {
+		err = errors.Join(ErrDrainTimeout, err)
+	}
+
+	s.reportError(PhaseForceClose, err)
+}
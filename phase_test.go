@@ -0,0 +1,100 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorRecorder records the errors reported through [WithOnError].
+type errorRecorder struct {
+	mu     sync.Mutex
+	phases []Phase
+	errs   []error
+}
+
+func (r *errorRecorder) record(phase Phase, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.phases = append(r.phases, phase)
+	r.errs = append(r.errs, err)
+}
+
+func TestPhase_String(t *testing.T) {
+	assert.Equal(t, "listen", PhaseListen.String())
+	assert.Equal(t, "force close", PhaseForceClose.String())
+	assert.Equal(t, "unknown", Phase(42).String())
+}
+
+func TestWithOnError(t *testing.T) {
+	t.Run("report the listen errors", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		var rec errorRecorder
+		s := Bind(ln.Addr().String(), nil)
+		err = s.ListenAndServeWithShutdown(context.Background(), WithOnError(rec.record))
+
+		var gerr *GracefulError
+		require.ErrorAs(t, err, &gerr)
+		assert.Equal(t, PhaseListen, gerr.Phase)
+		assert.Equal(t, []Phase{PhaseListen}, rec.phases)
+		assert.Equal(t, []error{gerr.Err}, rec.errs)
+	})
+
+	t.Run("report the drain errors", func(t *testing.T) {
+		var rec errorRecorder
+		drainErr := errors.New("drain failed")
+		s := Bind("localhost:0", nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithOnError(rec.record),
+				WithDrainStrategy(DrainStrategyFunc(func(context.Context, *http.Server) error { return drainErr })))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		err := <-done
+		assert.ErrorIs(t, err, drainErr)
+		assert.Contains(t, rec.phases, PhaseDrain)
+
+		var gerr *GracefulError
+		require.ErrorAs(t, err, &gerr)
+		assert.Equal(t, PhaseDrain, gerr.Phase)
+	})
+
+	t.Run("report the forced close", func(t *testing.T) {
+		var rec errorRecorder
+		s := Bind("localhost:0", &delayedHandler{delay: 10 * time.Second})
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithOnError(rec.record))
+		}()
+
+		<-s.Ready()
+		go forceShutdown(s, cancel, clock)
+
+		_, err := http.Get("http://" + s.ListenerAddr().String())
+		require.Error(t, err)
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []Phase{PhaseForceClose}, rec.phases)
+		assert.ErrorIs(t, rec.errs[0], ErrDrainTimeout)
+	})
+}
@@ -0,0 +1,56 @@
+package gracefulhttp
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// defaultPprofPrefix is the path prefix of the profiles served by [WithPprof] when none is given.
+const defaultPprofPrefix = "/debug/pprof"
+
+// WithPprof serves the profiles of [net/http/pprof] under the path prefix, "/debug/pprof" if empty,
+// ahead of the handler and of the drain rejection, so that a stuck drain can be profiled.
+// When [WithAdminServer] is set, they are served by the admin server only.
+// The profiles expose internal details of the process: serve them only on trusted networks.
+func WithPprof(prefix string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.pprofPrefix = strings.TrimSuffix(prefix, "/")
+		if s.pprofPrefix == "" {
+			s.pprofPrefix = defaultPprofPrefix
+		}
+	}
+}
+
+// pprofHandler serves the profiles under the prefix, and any other request through next.
+func pprofHandler(next http.Handler, prefix string) http.Handler {
+	profiles := http.StripPrefix(prefix, http.HandlerFunc(servePprof))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		profiles.ServeHTTP(w, r)
+	})
+}
+
+// servePprof serves the profile named by the path, or the index of the profiles.
+// [pprof.Index] only serves the profiles under "/debug/pprof/", hence the dispatch.
+func servePprof(w http.ResponseWriter, r *http.Request) {
+	switch name := strings.TrimPrefix(r.URL.Path, "/"); name {
+	case "":
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+}
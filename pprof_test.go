@@ -0,0 +1,58 @@
+package gracefulhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPprof(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "serve the index",
+			prefix:     "/admin/pprof/",
+			path:       "/admin/pprof/",
+			wantStatus: http.StatusOK,
+			wantBody:   "goroutine",
+		},
+		{
+			name:       "serve a profile",
+			prefix:     "/admin/pprof",
+			path:       "/admin/pprof/goroutine?debug=1",
+			wantStatus: http.StatusOK,
+			wantBody:   "goroutine profile",
+		},
+		{
+			name:       "serve the default prefix",
+			path:       "/debug/pprof/cmdline",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject other paths while draining",
+			path:       "/api",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", &delayedHandler{})
+			s.initialize([]GracefulServerOption{WithDrainRejection(time.Second), WithPprof(tt.prefix)})
+			s.beginDrain()
+
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package gracefulhttp
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// initializeHTTPRedirect registers the companion server redirecting the HTTP requests to HTTPS,
+// unless the companion server answering the ACME HTTP-01 challenges on the same address
+// already does.
+func (s *GracefulServer) initializeHTTPRedirect() {
+	if s.httpRedirectAddr == "" {
+		return
+	}
+
+	if _, ok := s.certManager.(HTTPChallengeHandler); ok && s.acmeChallengeAddr == s.httpRedirectAddr {
+		return
+	}
+
+	s.companions = append(s.companions, Bind(s.httpRedirectAddr, http.HandlerFunc(s.redirectToHTTPS)))
+}
+
+// redirectToHTTPS permanently redirects the request to the same URL served by the HTTPS listener.
+func (s *GracefulServer) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := stripPort(r.Host)
+
+	if addr, ok := s.ListenerAddr().(*net.TCPAddr); ok && addr.Port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(addr.Port))
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
@@ -0,0 +1,101 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_redirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   int
+		target string
+		want   string
+	}{
+		{
+			name:   "default port",
+			port:   443,
+			target: "http://example.com/path?q=1",
+			want:   "https://example.com/path?q=1",
+		},
+		{
+			name:   "custom port",
+			port:   8443,
+			target: "http://example.com:8080/path",
+			want:   "https://example.com:8443/path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &GracefulServer{listenerAddrs: []net.Addr{&net.TCPAddr{IP: net.IPv4zero, Port: tt.port}}}
+
+			w := httptest.NewRecorder()
+			s.redirectToHTTPS(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, http.StatusMovedPermanently, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestWithHTTPRedirect(t *testing.T) {
+	t.Run("register the companion server", func(t *testing.T) {
+		s := &GracefulServer{}
+		WithHTTPRedirect(":8080")(s)
+		s.initializeHTTPRedirect()
+
+		require.Len(t, s.companions, 1)
+		assert.Equal(t, ":8080", s.companions[0].Addr)
+	})
+
+	t.Run("rely on the acme challenge server", func(t *testing.T) {
+		s := &GracefulServer{acmeChallengeAddr: defaultACMEChallengeAddr}
+		WithCertificateManager(CertMagic(&fakeCertManager{}, fakeIssuer{}))(s)
+		WithHTTPRedirect(defaultACMEChallengeAddr)(s)
+		s.initializeHTTPRedirect()
+
+		assert.Empty(t, s.companions)
+	})
+
+	t.Run("redirect and shut down together", func(t *testing.T) {
+		certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+
+		s := Bind("localhost:0", &delayedHandler{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithKeyPairPEM(certPEM, keyPEM), WithHTTPRedirect("localhost:0"))
+		}()
+
+		<-s.Ready()
+		require.Len(t, s.companions, 1)
+		redirect := s.companions[0]
+		<-redirect.Ready()
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+
+		r, err := client.Get("http://" + redirect.ListenerAddr().String() + "/path")
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+
+		port := s.ListenerAddr().(*net.TCPAddr).Port
+		assert.Equal(t, http.StatusMovedPermanently, r.StatusCode)
+		assert.Equal(t, "https://"+net.JoinHostPort("127.0.0.1", strconv.Itoa(port))+"/path", r.Header.Get("Location"))
+
+		cancel()
+
+		require.NoError(t, <-done)
+		assert.True(t, redirect.isDraining())
+	})
+}
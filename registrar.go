@@ -0,0 +1,89 @@
+package gracefulhttp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Registrar registers the server with a service discovery system, such as Consul, etcd or a cloud registry,
+// so that any system can be plugged in through [WithRegistrar].
+type Registrar interface {
+	// Register registers the server, listening on the addresses of the info.
+	Register(ctx context.Context, info RegistrationInfo) error
+	// Deregister deregisters the server, once registered.
+	Deregister(ctx context.Context) error
+}
+
+// RegistrationInfo describes the server registered through a [Registrar].
+type RegistrationInfo struct {
+	// Addrs are the addresses the server is listening on, in the order they were provided.
+	Addrs []net.Addr
+}
+
+// registrar is a [Registrar] set through [WithRegistrar], with whether the server is registered.
+type registrar struct {
+	Registrar
+
+	registered atomic.Bool
+}
+
+// WithRegistrar registers the server through the registrar once it is listening, as a startup check gating
+// the readiness of the server, retried as set by [WithStartupProbe] until it succeeds, then deregisters it
+// at the very start of the shutdown, as [WithDeregistration] does, waiting for the settle time, serving as
+// usual, so that the traffic stops being routed to the server before the drain begins. The registrars set
+// through several options are deregistered one after the other, then the longest settle time is waited for.
+func WithRegistrar(r Registrar, settle time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if r == nil {
+			s.invalidOption("WithRegistrar", "nil registrar")
+			return
+		}
+
+		if settle < 0 {
+			s.invalidOption("WithRegistrar", "negative settle time %v", settle)
+		}
+
+		reg := &registrar{Registrar: r}
+		s.registrars = append(s.registrars, reg)
+		s.registrarSettle = max(s.registrarSettle, settle)
+		s.startupChecks = append(s.startupChecks, func(ctx context.Context) error {
+			return s.register(ctx, reg)
+		})
+	}
+}
+
+// register registers the server through the registrar.
+func (s *GracefulServer) register(ctx context.Context, reg *registrar) error {
+	if err := reg.Register(ctx, RegistrationInfo{Addrs: s.ListenerAddrs()}); err != nil {
+		if s.logger != nil {
+			s.logger.Warn("gracefulhttp: registration failed", "error", err)
+		}
+
+		return err
+	}
+
+	reg.registered.Store(true)
+
+	return nil
+}
+
+// deregisterRegistrars deregisters the server through the registrars it was registered with.
+func (s *GracefulServer) deregisterRegistrars(ctx context.Context) []error {
+	var errs []error
+	for _, reg := range s.registrars {
+		if !reg.registered.Swap(false) {
+			continue
+		}
+
+		if err := reg.Deregister(ctx); err != nil {
+			err = fmt.Errorf("gracefulhttp: deregistration: %w", err)
+			s.reportError(PhaseDrain, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
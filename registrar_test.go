@@ -0,0 +1,115 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistrar records the registrations, failing the first ones as configured.
+type fakeRegistrar struct {
+	mu           sync.Mutex
+	failures     int
+	registered   []RegistrationInfo
+	deregistered int
+	draining     bool
+	server       *GracefulServer
+}
+
+func (r *fakeRegistrar) Register(_ context.Context, info RegistrationInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("registry unavailable")
+	}
+
+	r.registered = append(r.registered, info)
+
+	return nil
+}
+
+func (r *fakeRegistrar) Deregister(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deregistered++
+	r.draining = r.server.isDraining()
+
+	return nil
+}
+
+func (r *fakeRegistrar) registrations() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.registered)
+}
+
+func TestWithRegistrar(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+	registrar := &fakeRegistrar{failures: 1, server: s}
+	unregistered := &fakeRegistrar{failures: 1 << 10, server: s}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithStartupProbe(time.Second, time.Millisecond),
+			WithRegistrar(registrar, 0), WithRegistrar(unregistered, 0))
+	}()
+	<-s.Ready()
+
+	// The failed registration is retried.
+	require.Eventually(t, func() bool {
+		return registrar.registrations() == 1
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []RegistrationInfo{{Addrs: []net.Addr{s.ListenerAddr()}}}, registrar.registered)
+	assert.Equal(t, 1, registrar.deregistered)
+	assert.False(t, registrar.draining)
+	assert.Zero(t, unregistered.deregistered)
+
+	assert.ErrorIs(t, ValidateOptions(WithRegistrar(nil, 0)), ErrInvalidOption)
+	assert.ErrorIs(t, ValidateOptions(WithRegistrar(registrar, -time.Second)), ErrInvalidOption)
+}
+
+func TestWithRegistrar_settle(t *testing.T) {
+	clock := newManualClock()
+	s := Bind("localhost:0", &delayedHandler{})
+	registrar := &fakeRegistrar{server: s}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithRegistrar(registrar, 10*time.Second))
+	}()
+	<-s.Ready()
+
+	require.Eventually(t, func() bool {
+		return registrar.registrations() == 1
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+
+	// The server keeps serving during the settle time.
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+	assert.False(t, s.isDraining())
+
+	clock.fire()
+
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, registrar.deregistered)
+}
@@ -0,0 +1,95 @@
+package gracefulhttp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// ErrNotReloadable is wrapped by the errors of [GracefulServer.Reload] reporting an option
+// that cannot be applied to a running server.
+var ErrNotReloadable = errors.New("gracefulhttp: option not reloadable")
+
+// reloadableFields are the settings that [GracefulServer.Reload] may change while serving.
+var reloadableFields = map[string]bool{
+	"gracefulTimeout":    true,
+	"forceTimeout":       true,
+	"drainStrategy":      true,
+	"closeIdleOnDrain":   true,
+	"drainLogInterval":   true,
+	"maxInFlight":        true,
+	"inFlightRetryAfter": true,
+}
+
+// bookkeepingFields are the fields recording how the options were applied, rather than settings.
+var bookkeepingFields = map[string]bool{
+	"claims":      true,
+	"conflicts":   true,
+	"invalidOpts": true,
+	"optionErrs":  true,
+}
+
+// Reload applies the options to the running server, e.g. to raise the graceful timeout before a risky deploy.
+// Only the options tuning the shutdown and the limits can be reloaded: [WithShutdownTimeout], [WithForceCloseTimeout],
+// [WithDrainStrategy], [WithCloseIdleOnDrain], [WithDrainLogInterval], and [WithMaxInFlightRequests] if a limit was
+// set when the server started. A shutdown already in progress keeps the settings it started with.
+//
+// The options are applied all together or not at all: if any of them is given an invalid value, or would need
+// the listener to be restarted, an error wrapping [ErrInvalidOption] or [ErrNotReloadable] is returned instead.
+// The logging level is not an option: use a [log/slog.LevelVar] in the handler of the logger to change it at runtime.
+func (s *GracefulServer) Reload(opts ...GracefulServerOption) error {
+	var errs []error
+	for _, opt := range opts {
+		var probe GracefulServer
+		opt(&probe)
+
+		errs = append(errs, probe.optionErrs...)
+		errs = append(errs, probe.invalidOpts...)
+
+		if !s.reloadable(&probe) {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrNotReloadable, optionName(opt)))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return nil
+}
+
+// reloadable reports whether the settings of the probe, to which an option was applied,
+// can be changed while serving.
+func (s *GracefulServer) reloadable(probe *GracefulServer) bool {
+	v := reflect.ValueOf(probe).Elem()
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Name
+		if !v.Field(i).IsZero() && !reloadableFields[name] && !bookkeepingFields[name] {
+			return false
+		}
+	}
+
+	// The limit is enforced by a middleware installed only if a limit was set at start.
+	return probe.maxInFlight <= 0 || s.limitingInFlight
+}
+
+// optionName returns the name of the function that created the option, e.g. "gracefulhttp.WithH2C".
+func optionName(opt GracefulServerOption) string {
+	name := runtime.FuncForPC(reflect.ValueOf(opt).Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+
+	if parts := strings.SplitN(name, ".", 3); len(parts) >= 2 {
+		return parts[0] + "." + parts[1]
+	}
+
+	return name
+}
@@ -0,0 +1,68 @@
+package gracefulhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_Reload(t *testing.T) {
+	t.Run("apply the tunable options while serving", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{})
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx)
+		}()
+		<-s.Ready()
+
+		require.NoError(t, s.Reload(WithShutdownTimeout(time.Minute), WithForceCloseTimeout(time.Second), WithCloseIdleOnDrain()))
+
+		status := s.status()
+		assert.Equal(t, Duration(time.Minute), status.Timeouts.Shutdown)
+		assert.Equal(t, Duration(time.Second), status.Timeouts.ForceClose)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("reject the options needing a restart", func(t *testing.T) {
+		s := Bind("", nil)
+		require.NoError(t, s.initialize(nil))
+
+		err := s.Reload(WithShutdownTimeout(time.Minute), WithH2C())
+
+		assert.ErrorIs(t, err, ErrNotReloadable)
+		assert.ErrorContains(t, err, "gracefulhttp.WithH2C")
+		assert.Equal(t, defaultGracefulTimeout, s.gracefulTimeout)
+	})
+
+	t.Run("reject the invalid values", func(t *testing.T) {
+		s := Bind("", nil)
+		require.NoError(t, s.initialize(nil))
+
+		err := s.Reload(WithShutdownTimeout(-time.Second))
+
+		assert.ErrorIs(t, err, ErrInvalidOption)
+		assert.Equal(t, defaultGracefulTimeout, s.gracefulTimeout)
+	})
+
+	t.Run("change the limit of the in-flight requests", func(t *testing.T) {
+		s := Bind("", nil)
+		require.NoError(t, s.initialize([]GracefulServerOption{WithMaxInFlightRequests(10, 0)}))
+
+		require.NoError(t, s.Reload(WithMaxInFlightRequests(20, time.Second)))
+		assert.Equal(t, int64(20), s.inFlightLimit())
+	})
+
+	t.Run("reject a limit of the in-flight requests not set at start", func(t *testing.T) {
+		s := Bind("", nil)
+		require.NoError(t, s.initialize(nil))
+
+		assert.ErrorIs(t, s.Reload(WithMaxInFlightRequests(20, time.Second)), ErrNotReloadable)
+	})
+}
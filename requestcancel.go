@@ -0,0 +1,68 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithRequestCancelMargin cancels the contexts of the requests still in flight the margin before the graceful
+// timeout expires, with [ErrRequestCanceled] as the cause, so that the handlers can write a clean error response
+// before the connections are forcibly closed, instead of the clients seeing them reset. The deadline extended
+// through [GracefulServer.ExtendShutdown] is taken into account.
+func WithRequestCancelMargin(margin time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if margin < 0 {
+			s.invalidOption("WithRequestCancelMargin", "negative margin %v", margin)
+		}
+
+		s.cancelMargin = max(margin, 0)
+	}
+}
+
+// initializeRequestCancel creates the context whose cancellation is propagated to the requests, if needed.
+func (s *GracefulServer) initializeRequestCancel() {
+	if s.cancelMargin <= 0 {
+		return
+	}
+
+	s.requestsCtx, s.cancelRequests = context.WithCancelCause(context.Background())
+}
+
+// requestCancelHandler cancels the context of the request once the requests are canceled ahead of the forced close.
+func (s *GracefulServer) requestCancelHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+
+		stop := context.AfterFunc(s.requestsCtx, func() {
+			cancel(context.Cause(s.requestsCtx))
+		})
+		defer stop()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// cancelRequestsBefore cancels the requests the margin before the deadline of the drain,
+// waiting again if the deadline was extended meanwhile, unless the drain completes first.
+func (s *GracefulServer) cancelRequestsBefore(drainCtx context.Context) {
+	for {
+		deadline, _ := drainCtx.Deadline()
+		wait := deadline.Add(-s.cancelMargin).Sub(s.clock.Now())
+		if wait <= 0 {
+			s.cancelRequests(ErrRequestCanceled)
+			return
+		}
+
+		select {
+		case <-drainCtx.Done():
+			return
+		case <-s.clock.After(wait):
+			if extended, _ := drainCtx.Deadline(); extended.Equal(deadline) {
+				s.cancelRequests(ErrRequestCanceled)
+				return
+			}
+		}
+	}
+}
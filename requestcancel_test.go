@@ -0,0 +1,73 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRequestCancelMargin(t *testing.T) {
+	started := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+
+		if errors.Is(context.Cause(r.Context()), ErrRequestCanceled) {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Second), WithRequestCancelMargin(900*time.Millisecond))
+	}()
+	<-s.Ready()
+
+	type response struct {
+		status int
+		body   string
+	}
+	respCh := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + s.ListenerAddr().String())
+		if !assert.NoError(t, err) {
+			respCh <- response{}
+			return
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		respCh <- response{status: resp.StatusCode, body: string(body)}
+	}()
+
+	<-started
+	cancel()
+
+	resp := <-respCh
+	assert.Equal(t, http.StatusServiceUnavailable, resp.status)
+	assert.Equal(t, "shutting down\n", resp.body)
+	require.NoError(t, <-done)
+}
+
+func TestWithRequestCancelMargin_DrainedInTime(t *testing.T) {
+	s := Bind("", &delayedHandler{})
+	require.NoError(t, s.initialize([]GracefulServerOption{WithRequestCancelMargin(time.Second)}))
+
+	ctx, cancel := withTimeout(realClock{}, time.Hour)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.cancelRequestsBefore(ctx)
+	}()
+
+	cancel()
+	<-done
+	assert.NoError(t, context.Cause(s.requestsCtx))
+}
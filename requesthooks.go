@@ -0,0 +1,103 @@
+package gracefulhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestHooks are the hooks invoked at the points of the processing of each request by [WithRequestHooks].
+// They run synchronously on the goroutine of the request, and must return quickly. Nil hooks are skipped.
+type RequestHooks struct {
+	// Start is invoked when the request starts being served.
+	Start func(r *http.Request)
+	// FirstByte is invoked when the header of the response is written, with its status code.
+	FirstByte func(r *http.Request, status int)
+	// Complete is invoked once the request has been served.
+	Complete func(r *http.Request, outcome RequestOutcome)
+	// Aborted is invoked in place of Complete once the request has been served, if it was still in flight when
+	// the shutdown canceled the requests ahead of the forced close, see [WithRequestCancelMargin], or forcibly
+	// closed the connections, so that its response was likely cut off.
+	Aborted func(r *http.Request, outcome RequestOutcome)
+}
+
+// RequestOutcome is the outcome of a request reported to the [RequestHooks].
+type RequestOutcome struct {
+	// Status is the status code of the response, 200 if the header was not written explicitly.
+	Status int
+	// Bytes is the number of bytes written in the body of the response.
+	Bytes int64
+	// Duration is the time spent serving the request.
+	Duration time.Duration
+	// Draining reports whether the shutdown had begun when the request completed.
+	Draining bool
+}
+
+// WithRequestHooks invokes the hooks at the start of each request, when the first byte of its response is
+// written, and once it completes or is aborted by the shutdown, so that the shutdown can be correlated with
+// the outcome of the individual requests, e.g. in custom telemetry, without a full middleware stack.
+func WithRequestHooks(hooks RequestHooks) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.requestHooks = &hooks
+	}
+}
+
+// requestHooksHandler invokes the request hooks around the handler.
+func (s *GracefulServer) requestHooksHandler(next http.Handler) http.Handler {
+	hooks := s.requestHooks
+	// The channel of the run is captured, so that the requests outliving it are told apart as well.
+	aborted := make(chan struct{})
+	s.mu.Lock()
+	s.requestsAborted = aborted
+	s.mu.Unlock()
+	requestsCtx := s.requestsCtx
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := s.clock.Now()
+		if hooks.Start != nil {
+			hooks.Start(r)
+		}
+
+		rw := &responseWriter{ResponseWriter: w}
+		if hooks.FirstByte != nil {
+			rw.beforeWriteHeader = func(http.Header) {
+				hooks.FirstByte(r, rw.status)
+			}
+		}
+
+		defer func() {
+			outcome := RequestOutcome{
+				Status:   rw.status,
+				Bytes:    rw.written,
+				Duration: s.clock.Now().Sub(start),
+				Draining: s.isDraining(),
+			}
+			if outcome.Status == 0 {
+				outcome.Status = http.StatusOK
+			}
+
+			if isClosed(aborted) || (requestsCtx != nil && requestsCtx.Err() != nil) {
+				if hooks.Aborted != nil {
+					hooks.Aborted(r, outcome)
+				}
+
+				return
+			}
+
+			if hooks.Complete != nil {
+				hooks.Complete(r, outcome)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// abortRequests signals the request hooks that the requests still in flight are being forcibly closed.
+func (s *GracefulServer) abortRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.requestsAborted != nil && !isClosed(s.requestsAborted) {
+		close(s.requestsAborted)
+	}
+}
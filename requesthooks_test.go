@@ -0,0 +1,96 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRequestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	outcomes := make(chan RequestOutcome, 1)
+	hooks := RequestHooks{
+		Start: func(r *http.Request) {
+			record("start " + r.URL.Path)
+		},
+		FirstByte: func(r *http.Request, status int) {
+			record("first byte " + http.StatusText(status))
+		},
+		Complete: func(r *http.Request, outcome RequestOutcome) {
+			record("complete")
+			outcomes <- outcome
+		},
+		Aborted: func(r *http.Request, outcome RequestOutcome) {
+			record("aborted")
+			outcomes <- outcome
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("done"))
+	})
+	mux.HandleFunc("/hints", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		_, _ = w.Write([]byte("done"))
+	})
+	mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	s := Bind("localhost:0", mux)
+
+	clock := newManualClock()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithRequestHooks(hooks))
+	}()
+	<-s.Ready()
+
+	r, err := http.Get("http://" + s.ListenerAddr().String() + "/created")
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+
+	outcome := <-outcomes
+	assert.Equal(t, http.StatusCreated, outcome.Status)
+	assert.Equal(t, int64(4), outcome.Bytes)
+	assert.False(t, outcome.Draining)
+
+	// The informational headers are not the first byte of the response.
+	r, err = http.Get("http://" + s.ListenerAddr().String() + "/hints")
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+
+	outcome = <-outcomes
+	assert.Equal(t, http.StatusOK, outcome.Status)
+	assert.Equal(t, int64(4), outcome.Bytes)
+
+	go forceShutdown(s, cancel, clock)
+
+	_, err = http.Get("http://" + s.ListenerAddr().String() + "/stuck")
+	require.Error(t, err)
+
+	outcome = <-outcomes
+	assert.Equal(t, http.StatusOK, outcome.Status)
+	assert.True(t, outcome.Draining)
+
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []string{"start /created", "first byte Created", "complete",
+		"start /hints", "first byte OK", "complete", "start /stuck", "aborted"}, calls)
+}
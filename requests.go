@@ -0,0 +1,74 @@
+package gracefulhttp
+
+import (
+	"sync"
+	"time"
+)
+
+// requestInfo describes a request being served.
+type requestInfo struct {
+	method string
+	path   string
+	start  time.Time
+}
+
+// requestTracker records the requests being served.
+type requestTracker struct {
+	mu       sync.Mutex
+	requests map[*requestInfo]struct{}
+}
+
+// add records a request, returning the function removing it once served.
+func (t *requestTracker) add(method, path string, start time.Time) (remove func()) {
+	req := &requestInfo{
+		method: method,
+		path:   path,
+		start:  start,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.requests == nil {
+		t.requests = make(map[*requestInfo]struct{})
+	}
+
+	t.requests[req] = struct{}{}
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		delete(t.requests, req)
+	}
+}
+
+// snapshot returns the requests being served.
+func (t *requestTracker) snapshot() []requestInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests := make([]requestInfo, 0, len(t.requests))
+	for req := range t.requests {
+		requests = append(requests, *req)
+	}
+
+	return requests
+}
+
+// logInFlightRequests logs the requests still in flight during the drain,
+// with the time elapsed since they started.
+func (s *GracefulServer) logInFlightRequests(elapsed time.Duration) {
+	now := s.clock.Now()
+	requests := s.requests.snapshot()
+
+	s.logger.Info("gracefulhttp: draining", "elapsed", elapsed, "requests", len(requests))
+
+	for _, req := range requests {
+		s.logger.Info("gracefulhttp: request still in flight",
+			"method", req.method,
+			"path", req.path,
+			"duration", now.Sub(req.start),
+		)
+	}
+}
@@ -0,0 +1,83 @@
+package gracefulhttp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is a buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestWithLogger_InFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+
+	var logs lockedBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithLogger(logger), WithDrainLogInterval(10*time.Millisecond))
+	}()
+
+	<-s.Ready()
+
+	codes := make(chan int, 1)
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String() + "/slow")
+		if err != nil {
+			codes <- 0
+			return
+		}
+		_ = r.Body.Close()
+		codes <- r.StatusCode
+	}()
+
+	for len(s.requests.snapshot()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	for !strings.Contains(logs.String(), "request still in flight") {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+
+	assert.Equal(t, http.StatusOK, <-codes)
+	require.NoError(t, <-done)
+
+	assert.Contains(t, logs.String(), "method=GET path=/slow")
+	assert.Empty(t, s.requests.snapshot())
+}
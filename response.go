@@ -0,0 +1,89 @@
+package gracefulhttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// errHijackNotSupported is returned when the wrapped [http.ResponseWriter] cannot be hijacked.
+var errHijackNotSupported = errors.New("gracefulhttp: response writer does not implement http.Hijacker")
+
+// responseWriter wraps an [http.ResponseWriter] to run a hook right before the header
+// is written and one right after the connection is hijacked, while still exposing the [http.Flusher] and [http.Hijacker] interfaces
+// of the underlying writer. It records the status code and the number of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+
+	beforeWriteHeader func(header http.Header)
+	afterHijack       func(conn net.Conn) net.Conn
+	wroteHeader       bool
+	status            int
+	written           int64
+}
+
+// WriteHeader runs the hook, if any, and sends the header with the provided status code.
+// The informational headers, such as 103 Early Hints, are sent as they are, ahead of the final one.
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader && !isInformational(code) {
+		w.wroteHeader = true
+		w.status = code
+
+		if w.beforeWriteHeader != nil {
+			w.beforeWriteHeader(w.Header())
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the data, sending an implicit 200 OK header first if needed.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying writer supports it.
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, if the underlying writer supports it.
+// The hook, if any, may replace the hijacked connection.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil || w.afterHijack == nil {
+		return conn, rw, err
+	}
+
+	return w.afterHijack(conn), rw, nil
+}
+
+// Unwrap returns the underlying writer, as expected by [http.ResponseController].
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isInformational reports whether the status code is a 1xx one sent ahead of the final header,
+// as opposed to 101 Switching Protocols, which is final.
+func isInformational(code int) bool {
+	return code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
@@ -0,0 +1,264 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultRestartTimeout is the default time given to the new process started by [WithExecRestart] to be ready.
+	defaultRestartTimeout = 30 * time.Second
+
+	// listenFDsEnv is the environment variable set to the number of listeners inherited by the new process.
+	listenFDsEnv = "GRACEFULHTTP_LISTEN_FDS"
+	// readyFDEnv is the environment variable set to the file descriptor on which the new process signals its readiness.
+	readyFDEnv = "GRACEFULHTTP_READY_FD"
+)
+
+// ErrExecRestart is the cause of the shutdown started by [WithExecRestart] once the new process is ready,
+// returned by the serving methods when [WithReturnCause] is set.
+var ErrExecRestart = errors.New("gracefulhttp: restarted in a new process")
+
+// errRestartTimeout is returned when the new process is not ready within the restart timeout.
+var errRestartTimeout = errors.New("gracefulhttp: new process not ready in time")
+
+// restartCommand returns the command starting the new process: the executable, with the same arguments.
+// When the executable has been replaced, e.g. by an upgrade, the new one is started.
+var restartCommand = func() (*exec.Cmd, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	return exec.Command(path, os.Args[1:]...), nil
+}
+
+// WithExecRestart restarts the server in a new process every time one of the signals (SIGUSR2 by default)
+// is received, for single-binary deployments upgraded by replacing the executable: the executable is started
+// again with the same arguments and environment, inheriting the listeners, so that no connection is refused.
+// Once the new process is ready, the server drains and the serving method returns, reporting [ErrExecRestart]
+// when [WithReturnCause] is set. If the new process exits or is not ready within the timeout, 30 seconds by
+// default, it is killed and the server keeps serving.
+// The new process must serve with this option as well, to take over the listeners. It is only supported on Unix systems.
+func WithExecRestart(timeout time.Duration, sig ...os.Signal) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if !execRestartSupported {
+			s.optionErrs = append(s.optionErrs, fmt.Errorf("%w: WithExecRestart: not supported on %s", ErrInvalidOption, runtime.GOOS))
+			return
+		}
+
+		if timeout < 0 {
+			s.invalidOption("WithExecRestart", "negative timeout %v", timeout)
+		}
+
+		s.restartTimeout = max(timeout, 0)
+		s.restartSignals = sig
+		if len(sig) == 0 {
+			s.restartSignals = []os.Signal{defaultRestartSignal}
+		}
+	}
+}
+
+// initializeExecRestart watches the restart signals in the background, if any.
+func (s *GracefulServer) initializeExecRestart() {
+	if len(s.restartSignals) == 0 {
+		return
+	}
+
+	s.background = append(s.background, s.watchRestartSignals)
+}
+
+// watchRestartSignals restarts the server in a new process once one of the restart signals is received,
+// then starts the shutdown, until the context is done.
+func (s *GracefulServer) watchRestartSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, s.restartSignals...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			if s.logger != nil {
+				s.logger.Info("gracefulhttp: restart signal received", "signal", sig.String())
+			}
+		}
+
+		pid, err := s.execRestart(ctx)
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("gracefulhttp: restart failed", "error", err)
+			}
+
+			continue
+		}
+
+		if s.logger != nil {
+			s.logger.Info("gracefulhttp: new process ready, shutting down", "pid", pid)
+		}
+
+		s.cancelServing(ErrExecRestart)
+		return
+	}
+}
+
+// execRestart starts the new process, passing it the listeners, and waits for it to be ready,
+// returning its pid. The new process is killed if it is not ready within the timeout.
+func (s *GracefulServer) execRestart(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	lns := slices.Clone(s.listeners)
+	s.mu.Unlock()
+
+	files := make([]*os.File, 0, len(lns)+1)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	for _, ln := range lns {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return 0, fmt.Errorf("gracefulhttp: listener %v cannot be inherited", ln.Addr())
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			return 0, err
+		}
+
+		files = append(files, f)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	cmd, err := restartCommand()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd.Env = append(os.Environ(),
+		listenFDsEnv+"="+strconv.Itoa(len(lns)),
+		readyFDEnv+"="+strconv.Itoa(3+len(lns)),
+	)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	// The pipe reports EOF if the new process exits before being ready, once the write end is closed here.
+	_ = w.Close()
+	files = files[:len(files)-1]
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+	case <-s.clock.After(cmp.Or(s.restartTimeout, defaultRestartTimeout)):
+		err = errRestartTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return 0, fmt.Errorf("gracefulhttp: new process not ready: %w", err)
+	}
+
+	// The socket files are now served by the new process, and must outlive the listeners.
+	for _, ln := range lns {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+
+	return cmd.Process.Pid, nil
+}
+
+// inheritListeners returns the listeners inherited from the process restarted through [WithExecRestart],
+// if any, unsetting the environment variable so that they are not inherited twice.
+func (s *GracefulServer) inheritListeners() ([]net.Listener, error) {
+	if len(s.restartSignals) == 0 {
+		return nil, nil
+	}
+
+	v, ok := os.LookupEnv(listenFDsEnv)
+	if !ok {
+		return nil, nil
+	}
+	_ = os.Unsetenv(listenFDsEnv)
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("gracefulhttp: invalid %s %q", listenFDsEnv, v)
+	}
+
+	lns := make([]net.Listener, 0, n)
+	for i := range n {
+		f := os.NewFile(uintptr(3+i), "listener")
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			closeListeners(lns)
+			return nil, err
+		}
+
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+// notifyRestarted signals the process restarted through [WithExecRestart], if any, that the server is ready,
+// unsetting the environment variable so that it is signaled once.
+func (s *GracefulServer) notifyRestarted() {
+	if len(s.restartSignals) == 0 {
+		return
+	}
+
+	v, ok := os.LookupEnv(readyFDEnv)
+	if !ok {
+		return
+	}
+	_ = os.Unsetenv(readyFDEnv)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	_, _ = f.Write([]byte{1})
+	_ = f.Close()
+}
+
+// closeListeners closes the listeners.
+func closeListeners(lns []net.Listener) {
+	for _, ln := range lns {
+		_ = ln.Close()
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package gracefulhttp
+
+import (
+	"os"
+)
+
+// execRestartSupported reports whether [WithExecRestart] is supported on the platform.
+const execRestartSupported = false
+
+// defaultRestartSignal is unset, as [WithExecRestart] is not supported on the platform.
+var defaultRestartSignal os.Signal
@@ -0,0 +1,104 @@
+//go:build unix
+
+package gracefulhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExecRestartHelper is the new process started by TestWithExecRestart.
+func TestExecRestartHelper(t *testing.T) {
+	if os.Getenv("GRACEFULHTTP_TEST_RESTART") == "" {
+		t.Skip("started by TestWithExecRestart")
+	}
+
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "new")
+	}))
+	_ = s.ListenAndServeWithShutdown(context.Background(), WithExecRestart(0), WithShutdownSignals())
+}
+
+func TestWithExecRestart(t *testing.T) {
+	// The signals sent before the server watches them must not terminate the test.
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+
+	var cmd *exec.Cmd
+	defer func(prev func() (*exec.Cmd, error)) { restartCommand = prev }(restartCommand)
+	restartCommand = func() (*exec.Cmd, error) {
+		cmd = exec.Command(os.Args[0], "-test.run=^TestExecRestartHelper$")
+		return cmd, nil
+	}
+	t.Setenv("GRACEFULHTTP_TEST_RESTART", "1")
+
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "old")
+	}))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(context.Background(), WithExecRestart(10*time.Second), WithReturnCause())
+	}()
+	<-s.Ready()
+	addr := "http://" + s.ListenerAddr().String()
+
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+
+		select {
+		case err := <-errCh:
+			assert.ErrorIs(t, err, ErrExecRestart)
+			done = true
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("the server was not restarted on the signal")
+		}
+	}
+	defer func() {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		_ = cmd.Wait()
+	}()
+
+	// The new process serves on the listener inherited.
+	r, err := http.Get(addr)
+	require.NoError(t, err)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(body))
+
+	assert.ErrorIs(t, ValidateOptions(WithExecRestart(-time.Second)), ErrInvalidOption)
+}
+
+func TestGracefulServer_execRestart(t *testing.T) {
+	defer func(prev func() (*exec.Cmd, error)) { restartCommand = prev }(restartCommand)
+	restartCommand = func() (*exec.Cmd, error) {
+		return exec.Command("true"), nil
+	}
+
+	s := Bind("localhost:0", nil)
+	go func() {
+		_ = s.ListenAndServeWithShutdown(context.Background(), WithExecRestart(time.Second))
+	}()
+	<-s.Ready()
+	defer s.CloseNow()
+
+	// The new process exits before being ready, and the server keeps serving.
+	_, err := s.execRestart(context.Background())
+	assert.Error(t, err)
+	assert.False(t, s.isDraining())
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package gracefulhttp
+
+import (
+	"os"
+	"syscall"
+)
+
+// execRestartSupported reports whether [WithExecRestart] is supported on the platform.
+const execRestartSupported = true
+
+// defaultRestartSignal is the signal restarting the server through [WithExecRestart] by default.
+var defaultRestartSignal os.Signal = syscall.SIGUSR2
@@ -0,0 +1,50 @@
+package gracefulhttp
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// WithReusePort creates n listeners on each address, sharing the port through SO_REUSEPORT, each one
+// served by its own accept loop, so that the kernel spreads the incoming connections across them on
+// multi-core hosts. They are all served by the server and shut down together. With n set to 1, a single
+// listener is created, sharing the port with the other processes serving with this option, e.g. the
+// workers of a prefork deployment, each one draining on its own. It is not supported on Windows.
+func WithReusePort(n int) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if !reusePortSupported {
+			s.optionErrs = append(s.optionErrs, fmt.Errorf("%w: WithReusePort: not supported on %s", ErrInvalidOption, runtime.GOOS))
+			return
+		}
+
+		if n < 1 {
+			s.invalidOption("WithReusePort", "non-positive number of listeners %d", n)
+		}
+
+		s.reusePort = max(n, 1)
+	}
+}
+
+// withReusePort returns the Control function of a [net.ListenConfig] setting SO_REUSEPORT on the sockets,
+// before invoking control, if any.
+func withReusePort(control func(network, address string, c syscall.RawConn) error) func(string, string, syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = setReusePort(fd)
+		}); err != nil {
+			return err
+		}
+
+		if sockErr != nil {
+			return fmt.Errorf("gracefulhttp: setting SO_REUSEPORT: %w", sockErr)
+		}
+
+		if control != nil {
+			return control(network, address, c)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package gracefulhttp
+
+import (
+	"errors"
+)
+
+// reusePortSupported reports whether [WithReusePort] is supported on the platform.
+const reusePortSupported = false
+
+// setReusePort fails, as SO_REUSEPORT is not supported on the platform.
+func setReusePort(uintptr) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReusePort(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithReusePort(4))
+	}()
+	<-s.Ready()
+
+	require.Len(t, s.ListenerAddrs(), 1)
+	assert.Len(t, s.listeners, 4)
+
+	for range 8 {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		_ = r.Body.Close()
+	}
+
+	// Another process serving with the option may share the port.
+	lc := net.ListenConfig{Control: withReusePort(nil)}
+	ln, err := lc.Listen(context.Background(), "tcp", s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = ln.Close()
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.ErrorIs(t, ValidateOptions(WithReusePort(0)), ErrInvalidOption)
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package gracefulhttp
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// reusePortSupported reports whether [WithReusePort] is supported on the platform.
+const reusePortSupported = true
+
+// setReusePort sets SO_REUSEPORT on the socket.
+func setReusePort(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+}
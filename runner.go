@@ -0,0 +1,98 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Runner returns the two legs of [GracefulServer.ListenAndServeWithShutdown], to be run by an errgroup owned
+// by the caller, e.g. together with the other components of the application, without nesting a group:
+//
+//	serve, await := s.Runner(ctx)
+//	g.Go(serve)
+//	g.Go(await)
+//
+// serve listens and serves until the shutdown begins, once ctx is canceled, returning the errors that
+// occurred while creating the listeners or serving, while await waits for the shutdown to complete,
+// returning its errors. Each error is returned by a single leg, as by [GracefulServer.ListenAndServeWithShutdown],
+// so that it is neither reported twice nor wrapped once more. Both legs must be run, serve once.
+func (s *GracefulServer) Runner(ctx context.Context, opts ...GracefulServerOption) (serve func() error, await func() error) {
+	var (
+		once sync.Once
+		done = make(chan struct{})
+		// failed receives the first error serving a listener, before the shutdown it triggers begins.
+		failed = make(chan error, 1)
+		err    error
+		// served is set if serve returned the error of the run, once returned is closed.
+		served   bool
+		returned = make(chan struct{})
+	)
+
+	serve = func() error {
+		once.Do(func() {
+			go func() {
+				defer close(done)
+				err = s.listenAndServe(ctx, opts, failed)
+			}()
+		})
+		defer close(returned)
+
+		select {
+		case <-done:
+			served = true
+			return err
+		case <-s.Draining():
+		}
+
+		select {
+		case serveErr := <-failed:
+			served = true
+			return phaseError(PhaseServe, serveErr)
+		default:
+			return nil
+		}
+	}
+
+	await = func() error {
+		<-done
+		<-returned
+
+		// The serving error, if any, is the first one and thus the error of the run.
+		if served {
+			return nil
+		}
+
+		return err
+	}
+
+	return serve, await
+}
+
+// listenAndServe implements [GracefulServer.ListenAndServeWithShutdown], sending the first error serving
+// a listener to failed, if not nil.
+func (s *GracefulServer) listenAndServe(ctx context.Context, opts []GracefulServerOption, failed chan<- error) error {
+	if err := s.initialize(opts); err != nil {
+		return err
+	}
+	defer s.release()
+
+	lns, err := s.listen(ctx, ":http")
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ctx, serveEach(lns, func(ln net.Listener) error {
+		err := s.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			select {
+			case failed <- err:
+			default:
+			}
+		}
+
+		return err
+	})...)
+}
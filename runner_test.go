@@ -0,0 +1,81 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestGracefulServer_Runner(t *testing.T) {
+	cleanupErr := errors.New("cleanup failed")
+
+	s := Bind("localhost:0", nil)
+	s.RegisterCleanup("failing", 0, func(context.Context) error {
+		return cleanupErr
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serve, await := s.Runner(ctx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+	<-s.Ready()
+	cancel()
+
+	require.NoError(t, <-serveErr)
+
+	err := await()
+	assert.ErrorIs(t, err, cleanupErr)
+
+	var gerr *GracefulError
+	require.ErrorAs(t, err, &gerr)
+	assert.Equal(t, PhaseCleanup, gerr.Phase)
+}
+
+func TestGracefulServer_Runner_ListenFailure(t *testing.T) {
+	taken := Bind("localhost:0", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = taken.ListenAndServeWithShutdown(ctx) }()
+	<-taken.Ready()
+
+	s := Bind(taken.ListenerAddr().String(), nil)
+	serve, await := s.Runner(context.Background())
+
+	var g errgroup.Group
+	g.Go(serve)
+	g.Go(await)
+
+	var gerr *GracefulError
+	require.ErrorAs(t, g.Wait(), &gerr)
+	assert.Equal(t, PhaseListen, gerr.Phase)
+}
+
+func TestGracefulServer_Runner_ServeFailure(t *testing.T) {
+	s := Bind("localhost:0", nil)
+	serve, await := s.Runner(context.Background())
+
+	g, _ := errgroup.WithContext(context.Background())
+	awaitErr := make(chan error, 1)
+	g.Go(serve)
+	go func() {
+		awaitErr <- await()
+	}()
+	<-s.Ready()
+
+	s.mu.Lock()
+	ln := s.listeners[0]
+	s.mu.Unlock()
+	require.NoError(t, ln.Close())
+
+	var gerr *GracefulError
+	require.ErrorAs(t, g.Wait(), &gerr)
+	assert.Equal(t, PhaseServe, gerr.Phase)
+	assert.NoError(t, <-awaitErr)
+}
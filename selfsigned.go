@@ -0,0 +1,89 @@
+package gracefulhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+)
+
+// selfSignedValidity is the validity of the certificates generated by [GenerateKeyPair].
+const selfSignedValidity = 30 * 24 * time.Hour
+
+// WithSelfSignedTLS serves a self-signed certificate generated in memory when the server starts, valid for
+// the hosts, which are DNS names or IP addresses ("localhost", "127.0.0.1" and "::1" by default), so that
+// HTTPS can be served locally during development without any certificate file. Nothing is written to disk,
+// and a new certificate is generated at every start. Pass empty certFile and keyFile to
+// [GracefulServer.ListenAndServeTLSWithShutdown] when using this option. It is not meant for production.
+func WithSelfSignedTLS(hosts ...string) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.certLoaders = append(s.certLoaders, func() (tls.Certificate, error) {
+			certPEM, keyPEM, err := GenerateKeyPair(hosts...)
+			if err != nil {
+				return tls.Certificate{}, err
+			}
+
+			return tls.X509KeyPair(certPEM, keyPEM)
+		})
+	}
+}
+
+// GenerateKeyPair generates a throwaway self-signed certificate valid for the hosts, which are DNS names or
+// IP addresses ("localhost", "127.0.0.1" and "::1" by default), and returns it with its private key, PEM encoded,
+// e.g. as the input of [WithKeyPairPEM] in the integration tests of a [GracefulServer], without shipping any
+// certificate file. The certificate can be trusted by the clients through [x509.CertPool.AppendCertsFromPEM].
+func GenerateKeyPair(hosts ...string) (certPEM, keyPEM []byte, err error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"gracefulhttp self-signed"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
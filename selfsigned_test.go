@@ -0,0 +1,73 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSelfSignedTLS(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithSelfSignedTLS("localhost", "127.0.0.1"))
+	}()
+	<-s.Ready()
+
+	// The certificate is trusted once pinned, and valid for the hosts.
+	var cert *x509.Certificate
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			cert = cs.PeerCertificates[0]
+			return nil
+		},
+	}}}
+	r, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+
+	require.NotNil(t, cert)
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+	assert.NoError(t, cert.VerifyHostname("127.0.0.1"))
+	assert.Error(t, cert.VerifyHostname("example.com"))
+	assert.WithinDuration(t, time.Now().Add(selfSignedValidity), cert.NotAfter, time.Minute)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	certPEM, keyPEM, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithKeyPairPEM(certPEM, keyPEM))
+	}()
+	<-s.Ready()
+
+	// The certificate is trusted by the clients adding it to their pool.
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	r, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -1,18 +1,34 @@
 package gracefulhttp
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
 	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/net/http2"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 )
 
 const (
 	// defaultGracefulTimeout is the default timeout value used for a graceful shutdown.
 	defaultGracefulTimeout = 5 * time.Second
+	// defaultRetryAfter is the default delay advertised to clients rejected while draining.
+	defaultRetryAfter = 1 * time.Second
+	// defaultDrainProgressInterval is the default interval between the drain progress reports.
+	defaultDrainProgressInterval = 1 * time.Second
+	// defaultDrainLogInterval is the default interval between the logs of the requests in flight during the drain.
+	defaultDrainLogInterval = 5 * time.Second
 
 	// defaultReadTimeout is the maximum duration for reading the entire request, including the body
 	defaultReadTimeout = 5 * time.Second
@@ -23,6 +39,26 @@ const (
 	// defaultReadHeaderTimeout is the amount of time allowed to read request headers
 	defaultReadHeaderTimeout = 5 * time.Second
 
+	// gcpIdleTimeout outlasts the 600 seconds keepalive timeout of the Google Cloud load balancers,
+	// so that they never send a request on a connection being closed by the backend.
+	gcpIdleTimeout = 620 * time.Second
+	// gcpReadHeaderTimeout is the amount of time allowed to read request headers behind a Google Cloud load balancer.
+	gcpReadHeaderTimeout = 10 * time.Second
+
+	// nginxIdleTimeout outlasts the 60 seconds default keepalive_timeout of the nginx upstream connections,
+	// so that nginx never sends a request on a connection being closed by the backend.
+	nginxIdleTimeout = 75 * time.Second
+	// nginxReadHeaderTimeout is the amount of time allowed to read request headers behind nginx.
+	nginxReadHeaderTimeout = 10 * time.Second
+
+	// herokuRequestTimeout is the time after which the Heroku router gives up on a request.
+	herokuRequestTimeout = 30 * time.Second
+	// herokuIdleTimeout outlasts the 55 seconds the Heroku router keeps an idle connection open.
+	herokuIdleTimeout = 60 * time.Second
+	// herokuGracefulTimeout leaves room to close the connections within the 30 seconds between
+	// the SIGTERM and the SIGKILL sent to a dyno.
+	herokuGracefulTimeout = 25 * time.Second
+
 	// defaultTLSMinVersion defines the recommended minimum version to use for the TLS protocol (1.2)
 	defaultTLSMinVersion = tls.VersionTLS12
 )
@@ -50,6 +86,29 @@ var (
 	}
 )
 
+var (
+	// ErrImmediateStop, set as the cause of the cancellation of the serving context through
+	// [context.WithCancelCause], makes the server close the active connections right away instead of draining them.
+	ErrImmediateStop = errors.New("gracefulhttp: immediate stop")
+	// ErrDrainTimeout is reported when a force close timeout is set and the connections
+	// were still active at the end of the drain window.
+	ErrDrainTimeout = errors.New("gracefulhttp: drain timeout expired")
+	// ErrForceCloseTimeout is reported when the connections could not be closed within the force close timeout.
+	ErrForceCloseTimeout = errors.New("gracefulhttp: force close timeout expired")
+	// ErrPanicShutdown is returned when the server was shut down by [WithPanicShutdown], after too many panics of the handler.
+	ErrPanicShutdown = errors.New("gracefulhttp: shutdown after handler panics")
+	// ErrRequestCanceled is the cause of the cancellation of the requests by [WithRequestCancelMargin],
+	// ahead of the forced close of their connections.
+	ErrRequestCanceled = errors.New("gracefulhttp: request canceled ahead of the forced close")
+	// ErrTransferTooSlow is returned by the reads and writes of the connections closed by [WithMinDataRate],
+	// for transferring data below the minimum rate.
+	ErrTransferTooSlow = errors.New("gracefulhttp: transfer rate below the minimum")
+	// ErrAlreadyRunning is returned when serving a server which is already running, as its options
+	// cannot be applied safely while serving. [GracefulServer.Reload] applies the reloadable ones.
+	// Serving a server which has stopped fails with [http.ErrServerClosed] instead.
+	ErrAlreadyRunning = errors.New("gracefulhttp: server already running")
+)
+
 // A GracefulServer is an extension of the [http.Server] that enables graceful shutdown.
 // It allows the server to smoothly stop accepting new connections while
 // processing existing requests to completion within a specified timeout.
@@ -59,99 +118,1081 @@ type GracefulServer struct {
 	http.Server
 
 	gracefulTimeout time.Duration
+	forceTimeout    time.Duration
+	forceCloseCtx   context.Context
+	shutdownContext func() (context.Context, context.CancelFunc)
+	immediateClose  bool
+	countDrain      bool
+	returnCause     bool
+	reportClosed    bool
+	clock           Clock
+
+	rejectWhileDraining   bool
+	drainExemptPaths      map[string]struct{}
+	closeWhileDraining    bool
+	closeIdleOnDrain      bool
+	disableKeepAlives     bool
+	retryAfter            time.Duration
+	maxInFlight           int
+	limitingInFlight      bool
+	queueSize             int
+	queueTimeout          time.Duration
+	cancelMargin          time.Duration
+	trackBytes            bool
+	metrics               MetricsRecorder
+	brownoutEnabled       bool
+	classify              func(r *http.Request) string
+	inFlightRetryAfter    time.Duration
+	slowStart             time.Duration
+	maxBodySize           int64
+	onForceClose          func(conns []ConnInfo)
+	forceCloseBudget      time.Duration
+	drainStrategy         DrainStrategy
+	forceCloseOrder       []string
+	forceCloseInterval    time.Duration
+	onDrainProgress       func(remaining int, elapsed time.Duration)
+	drainProgressInterval time.Duration
+	logger                Logger
+	drainLogInterval      time.Duration
+	onReady               func()
+	onStateChange         func(from, to State)
+	deregister            func(ctx context.Context) error
+	deregisterWait        time.Duration
+	registrars            []*registrar
+	registrarSettle       time.Duration
+	idleShutdown          time.Duration
+	memoryThreshold       uint64
+	memoryInterval        time.Duration
+	memoryProbe           func() uint64
+	errorRateThreshold    float64
+	errorRateWindow       time.Duration
+	errorRateMinRequests  int
+	errorRateShutdown     bool
+	restartTimeout        time.Duration
+	restartSignals        []os.Signal
+	systemdNotify         bool
+	watchdogCheck         func(ctx context.Context) error
+	pprofPrefix           string
+	adminAddr             string
+	panicRecovery         bool
+	onPanic               func(w http.ResponseWriter, r *http.Request, v any)
+	panicShutdown         *panicTracker
+	handlerTimeout        time.Duration
+	handlerTimeoutMsg     string
+	accessLogger          *slog.Logger
+	requestHooks          *RequestHooks
+	requestsAborted       chan struct{}
+	trackTunnels          bool
+	tunnelLinger          time.Duration
+	onError               func(phase Phase, err error)
+	exitBudget            time.Duration
+	onExitWatchdog        func()
+	readinessPath         string
+	shutdownDelay         time.Duration
+	shutdownSignals       []os.Signal
+	warmups               []func(ctx context.Context) error
+	chaos                 ChaosConfig
+	startupChecks         []func(ctx context.Context) error
+	startupTimeout        time.Duration
+	startupBackoff        time.Duration
+	acmeChallengeAddr     string
+	httpRedirectAddr      string
+	certManager           CertManager
+	reloadCertFile        string
+	reloadKeyFile         string
+	reloadSignals         []os.Signal
+	certLoaders           []func() (tls.Certificate, error)
+	sniCertPairs          map[string]CertPair
+	acmeHosts             []string
+	ticketRotation        time.Duration
+	crlSources            []string
+	crlRefresh            time.Duration
+	clientCADir           string
+	clientCARefresh       time.Duration
+	clientCASignals       []os.Signal
+	passthroughRoutes     map[string]string
+	hostHandlers          map[string]http.Handler
+	h2c                   bool
+	h2Server              *http2.Server
+	h2GoAwayLead          time.Duration
+	h2GoAway              func()
+	listenConfig          net.ListenConfig
+	network               string
+	reusePort             int
+	listenerWrappers      []func(ln net.Listener) net.Listener
+	maxConns              int
+	handshakeTimeout      time.Duration
+	minDataRate           int64
+	minDataRateGrace      time.Duration
+	acceptLimiter         *rate.Limiter
+	bandwidthLimiter      *rate.Limiter
+	ipAllow               []netip.Prefix
+	ipDeny                []netip.Prefix
+	ipConns               *ipConnCounter
+
+	mu                sync.Mutex
+	drain             chan struct{}
+	drainOnce         sync.Once
+	drainStartedAt    time.Time
+	shutdownCtx       *deadlineContext
+	shutdownDeadline  time.Time
+	ready             chan struct{}
+	readyOnce         sync.Once
+	readyAt           time.Time
+	events            chan Event
+	eventsOnce        sync.Once
+	hijacked          map[*hijackedConn]struct{}
+	jobs              jobTracker
+	conns             connTracker
+	inFlight          atomic.Int64
+	inFlightReleased  chan struct{}
+	queued            atomic.Int64
+	requestsCtx       context.Context
+	cancelRequests    context.CancelCauseFunc
+	forceClosed       atomic.Bool
+	brownout          atomic.Pointer[brownout]
+	shedRequests      atomic.Int64
+	panics            atomic.Int64
+	requestCount      atomic.Int64
+	inFlightRequests  atomic.Int64
+	errorRateRequests atomic.Int64
+	errorRateErrors   atomic.Int64
+	busyRequests      atomic.Int64
+	lastRequest       atomic.Int64
+	bytesWritten      atomic.Int64
+	cleanShutdowns    atomic.Int64
+	forcedShutdowns   atomic.Int64
+	forceClosedConns  atomic.Int64
+	started           atomic.Bool
+	running           atomic.Bool
+	state             atomic.Int32
+	cancelServe       context.CancelCauseFunc
+	lastShutdown      *shutdownReport
+	requests          requestTracker
+
+	addrs          []string
+	listenerAddrs  []net.Addr
+	listeners      []net.Listener
+	companions     []*GracefulServer
+	cleanups       [][]CleanupHook
+	shutdownHooks  []shutdownHook
+	workers        []*worker
+	admin          *GracefulServer
+	services       []Service
+	background     []func(ctx context.Context)
+	ownedTLSConfig *tls.Config
+	opts           []GracefulServerOption
+	optionErrs     []error
+	invalidOpts    []error
+	strictOptions  bool
+	configWarning  func(err error)
+	claims         map[string]string
+	conflicts      []error
 }
 
 // Bind returns a new [GracefulServer] configured with the provided address and handler.
-func Bind(addr string, handler http.Handler) *GracefulServer {
+// The options are applied when serving, before the ones passed to the serving method,
+// so that the configuration can be assembled where the server is constructed.
+func Bind(addr string, handler http.Handler, opts ...GracefulServerOption) *GracefulServer {
 	return &GracefulServer{
 		Server: http.Server{
 			Addr:              addr,
 			Handler:           handler,
 			ReadHeaderTimeout: defaultReadHeaderTimeout,
 		},
+		opts: opts,
 	}
 }
 
+// BindMulti returns a new [GracefulServer] serving the handler on several addresses, e.g. on
+// multi-homed hosts. All the listeners share the same configuration and are shut down together.
+// The server address is set to the first one. The options are applied as by [Bind].
+func BindMulti(addrs []string, handler http.Handler, opts ...GracefulServerOption) *GracefulServer {
+	s := Bind("", handler, opts...)
+	if len(addrs) > 0 {
+		s.Addr = addrs[0]
+		s.addrs = slices.Clone(addrs)
+	}
+
+	return s
+}
+
 // ListenAndServeWithShutdown starts a [http.Server] with the given address and handler.
 // It blocks until the context is canceled or an error occurs.
 // If the context is canceled, the server will attempt a graceful shutdown.
 // If the graceful shutdown exceeds the provided timeout, the server will be forcefully closed.
 // The default timeout is set to 5 seconds.
+// If the cause of the cancellation is [ErrImmediateStop], the server is closed without draining.
 // The [context.Canceled] error is intentionally ignored and thus not returned by the method.
 // Upon timeout, the method returns only [context.DeadlineExceeded] error.
 func (s *GracefulServer) ListenAndServeWithShutdown(ctx context.Context, opts ...GracefulServerOption) error {
-	s.initialize(opts)
-
-	return s.listenAndServe(ctx, func() error {
-		return s.ListenAndServe()
-	})
+	return s.listenAndServe(ctx, opts, nil)
 }
 
 // ListenAndServeTLSWithShutdown starts a [http.Server] with the provided address, handler, certificate, and key.
 // It behaves similarly to [ListenAndServeWithShutdown] but for HTTPS connections.
 // For additional details, refer to the documentation of [ListenAndServeWithShutdown].
+// The certFile and keyFile may be empty if the certificates are provided through the options.
 func (s *GracefulServer) ListenAndServeTLSWithShutdown(ctx context.Context, certFile string, keyFile string, opts ...GracefulServerOption) error {
-	s.initialize(opts)
+	if err := s.initialize(opts); err != nil {
+		return err
+	}
+	defer s.release()
 
-	return s.listenAndServe(ctx, func() error {
-		return s.ListenAndServeTLS(certFile, keyFile)
-	})
+	certFile, keyFile, err := s.initializeTLS(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	lns, err := s.listen(ctx, ":https")
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ctx, serveEach(lns, func(ln net.Listener) error {
+		return s.ServeTLS(ln, certFile, keyFile)
+	})...)
 }
 
-// listenAndServe invokes the listener until the context is canceled, then invokes the shutdown method.
-func (s *GracefulServer) listenAndServe(ctx context.Context, lsFn func() error) error {
-	g := errgroup.Group{}
+// ListenAndServeTLSWithShutdownCert behaves like [GracefulServer.ListenAndServeTLSWithShutdown], serving
+// the certificates provided rather than loading them from files, e.g. when they are built programmatically
+// or obtained from a secret manager or a hardware security module.
+func (s *GracefulServer) ListenAndServeTLSWithShutdownCert(ctx context.Context, certs []tls.Certificate, opts ...GracefulServerOption) error {
+	return s.ListenAndServeTLSWithShutdown(ctx, "", "", append([]GracefulServerOption{withCertificates(certs)}, opts...)...)
+}
 
-	g.Go(func() error {
-		if err := lsFn(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			return err
+// withCertificates adds the certificates to the TLS configuration when serving.
+func withCertificates(certs []tls.Certificate) GracefulServerOption {
+	return func(s *GracefulServer) {
+		for _, cert := range certs {
+			s.certLoaders = append(s.certLoaders, func() (tls.Certificate, error) {
+				return cert, nil
+			})
 		}
+	}
+}
 
+// ListenAndServeBothWithShutdown serves the same handler over HTTP on httpAddr and over HTTPS
+// on httpsAddr, falling back to ":http" and ":https" if empty, ignoring the server address.
+// Both listeners are shut down together within the same graceful timeout.
+// For additional details, refer to the documentation of [ListenAndServeTLSWithShutdown].
+func (s *GracefulServer) ListenAndServeBothWithShutdown(ctx context.Context, httpAddr, httpsAddr, certFile, keyFile string, opts ...GracefulServerOption) error {
+	if err := s.initialize(opts); err != nil {
+		return err
+	}
+	defer s.release()
+
+	certFile, keyFile, err := s.initializeTLS(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	lns, err := s.listenAll(ctx, []string{httpAddr, httpsAddr}, []string{":http", ":https"})
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ctx, func() error {
+		return s.Serve(lns[0])
+	}, func() error {
+		return s.ServeTLS(lns[1], certFile, keyFile)
+	})
+}
+
+// ListenAndServeUnixWithShutdown serves the same handler over HTTP on the server address, for the external
+// traffic, and on a Unix socket at socketPath, e.g. for a local sidecar or agent. Both listeners share the
+// configuration and are shut down together. A stale socket left at the path by a previous process is removed.
+// For additional details, refer to the documentation of [ListenAndServeWithShutdown].
+func (s *GracefulServer) ListenAndServeUnixWithShutdown(ctx context.Context, socketPath string, opts ...GracefulServerOption) error {
+	if err := s.initialize(opts); err != nil {
+		return err
+	}
+	defer s.release()
+
+	lns, err := s.listen(ctx, ":http", socketPath)
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ctx, serveEach(lns, s.Serve)...)
+}
+
+// ServeWithShutdown serves on the provided listener, e.g. one created by an overlay network or a tunnel,
+// instead of creating one on the server address. The server takes ownership of the listener, which is
+// closed once the serving stops, or right away if the server cannot be started.
+// For additional details, refer to the documentation of [ListenAndServeWithShutdown].
+func (s *GracefulServer) ServeWithShutdown(ctx context.Context, ln net.Listener, opts ...GracefulServerOption) error {
+	if err := s.initialize(opts); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	defer s.release()
+
+	if err := s.warmUp(ctx); err != nil {
+		_ = ln.Close()
+		return err
+	}
+
+	lns := s.accept([]net.Listener{ln})
+
+	return s.serve(ctx, serveEach(lns, s.Serve)...)
+}
+
+// ListenerAddr returns the address the server is listening on, or nil if the listener
+// has not been created yet. It is useful to discover the actual port when binding to port 0.
+// When listening on several addresses, it returns the first one.
+func (s *GracefulServer) ListenerAddr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.listenerAddrs) == 0 {
 		return nil
+	}
+
+	return s.listenerAddrs[0]
+}
+
+// ListenerAddrs returns the addresses the server is listening on, in the order they were
+// provided, or nil if the listeners have not been created yet.
+func (s *GracefulServer) ListenerAddrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.listenerAddrs)
+}
+
+// listen creates the TCP listeners on the configured addresses, falling back to defaultAddr if empty,
+// and the Unix socket listeners on the paths, if any.
+// The TCP listeners are created through the [net.ListenConfig] set by the options.
+func (s *GracefulServer) listen(ctx context.Context, defaultAddr string, unixPaths ...string) ([]net.Listener, error) {
+	addrs := s.addrs
+	if len(addrs) == 0 {
+		addrs = []string{s.Addr}
+	}
+
+	defaultAddrs := make([]string, len(addrs))
+	for i := range defaultAddrs {
+		defaultAddrs[i] = defaultAddr
+	}
+
+	return s.listenAll(ctx, addrs, defaultAddrs, unixPaths...)
+}
+
+// listenAll runs the warmup hooks, then creates the TCP listeners on the addresses, each one falling back
+// to the matching default address if empty, and the Unix socket listeners on the paths, if any, wraps them,
+// and signals the readiness once all of them are bound.
+func (s *GracefulServer) listenAll(ctx context.Context, addrs, defaultAddrs []string, unixPaths ...string) ([]net.Listener, error) {
+	if err := s.warmUp(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := s.injectChaos(PhaseListen, s.chaos.ListenDelay, s.chaos.ListenErr); err != nil {
+		return nil, s.listenFailed(nil, err)
+	}
+
+	// The listeners inherited through WithExecRestart take the place of the ones created, in the same order.
+	inherited, err := s.inheritListeners()
+	if err != nil {
+		return nil, s.listenFailed(nil, err)
+	}
+
+	lc := s.listenConfig
+	if s.reusePort > 0 {
+		lc.Control = withReusePort(lc.Control)
+	}
+
+	lns := make([]net.Listener, 0, len(addrs)*max(s.reusePort, 1))
+
+	for i, addr := range addrs {
+		if addr == "" {
+			addr = defaultAddrs[i]
+		}
+
+		for range max(s.reusePort, 1) {
+			var ln net.Listener
+			if len(inherited) > 0 {
+				ln, inherited = inherited[0], inherited[1:]
+			} else if ln, err = lc.Listen(context.Background(), cmp.Or(s.network, "tcp"), addr); err != nil {
+				return nil, s.listenFailed(slices.Concat(lns, inherited), err)
+			}
+
+			lns = append(lns, ln)
+			// The listeners sharing the port through WithReusePort bind the one chosen for the first.
+			addr = ln.Addr().String()
+		}
+	}
+
+	for _, path := range unixPaths {
+		var ln net.Listener
+		if len(inherited) > 0 {
+			ln, inherited = inherited[0], inherited[1:]
+		} else if ln, err = listenUnix(path); err != nil {
+			return nil, s.listenFailed(slices.Concat(lns, inherited), err)
+		}
+
+		lns = append(lns, ln)
+	}
+
+	closeListeners(inherited)
+
+	return s.accept(lns), nil
+}
+
+// listenFailed reports the failure to create a listener, closing the ones created already.
+func (s *GracefulServer) listenFailed(lns []net.Listener, err error) error {
+	s.reportError(PhaseListen, err)
+	closeListeners(lns)
+
+	return phaseError(PhaseListen, err)
+}
+
+// listenUnix creates a Unix socket listener at the path, removing first the stale socket
+// left by a previous process, which no longer accepts connections.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if c, err := net.Dial("unix", path); err == nil {
+			_ = c.Close()
+		} else {
+			_ = os.Remove(path)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+// accept records the addresses of the bound listeners, wraps them, and signals the readiness.
+func (s *GracefulServer) accept(lns []net.Listener) []net.Listener {
+	// The listeners sharing a port through WithReusePort are recorded once.
+	addrs := make([]net.Addr, 0, len(lns))
+	for _, ln := range lns {
+		if !slices.ContainsFunc(addrs, func(addr net.Addr) bool { return addr.String() == ln.Addr().String() }) {
+			addrs = append(addrs, ln.Addr())
+		}
+	}
+
+	s.mu.Lock()
+	s.listenerAddrs = addrs
+	s.listeners = slices.Clone(lns)
+	s.mu.Unlock()
+
+	for _, addr := range addrs {
+		s.emit(Event{Type: EventListenerBound, Addr: addr})
+	}
+
+	for i := range lns {
+		lns[i] = s.wrapListener(lns[i])
+	}
+
+	s.markReady()
+	s.setState(StateRunning)
+
+	return lns
+}
+
+// Ready returns a channel that is closed once the listener is bound
+// and the server is about to accept connections.
+func (s *GracefulServer) Ready() <-chan struct{} {
+	return s.readyChan()
+}
+
+// readyChan lazily creates the channel closed once the listener is bound.
+func (s *GracefulServer) readyChan() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+
+	return s.ready
+}
+
+// markReady signals the readiness of the server, invoking the ready callback, if any.
+func (s *GracefulServer) markReady() {
+	ch := s.readyChan()
+	s.readyOnce.Do(func() {
+		s.mu.Lock()
+		s.readyAt = s.clock.Now()
+		s.mu.Unlock()
+
+		if len(s.startupChecks) == 0 {
+			s.started.Store(true)
+		}
+
+		close(ch)
+		s.emit(Event{Type: EventReady})
+		s.notifySystemd("READY=1")
+		s.notifyRestarted()
+
+		if s.onReady != nil {
+			s.onReady()
+		}
 	})
+}
+
+// serveEach returns a function serving each listener through serveFn.
+func serveEach(lns []net.Listener, serveFn func(ln net.Listener) error) []func() error {
+	fns := make([]func() error, 0, len(lns))
+	for _, ln := range lns {
+		fns = append(fns, func() error {
+			return serveFn(ln)
+		})
+	}
+
+	return fns
+}
+
+// serve invokes the serve functions, each one serving a listener, until the context is canceled,
+// then invokes the shutdown method.
+// The companion servers and the services, if any, are started and shut down together with the server,
+// while the background tasks run until serving stops.
+// If a serve function fails, the shutdown is invoked as well, and its error is returned.
+// Unless set, the base context of the requests carries the values of ctx, but not its cancellation,
+// which starts the graceful shutdown instead.
+func (s *GracefulServer) serve(ctx context.Context, serveFns ...func() error) (err error) {
+	ctx, cancel := s.withServeCancel(ctx)
+	defer cancel()
+
+	defer func() {
+		s.emit(Event{Type: EventStopped, Err: err})
+	}()
+
+	if s.BaseContext == nil {
+		base := context.WithoutCancel(ctx)
+		s.BaseContext = func(net.Listener) context.Context {
+			return base
+		}
+	}
+
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	var bg sync.WaitGroup
+	defer func() {
+		bgCancel()
+		bg.Wait()
+	}()
+
+	for _, fn := range s.background {
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			fn(bgCtx)
+		}()
+	}
+
+	g, groupCtx := errgroup.WithContext(ctx)
+
+	s.mu.Lock()
+	gracefulTimeout := s.gracefulTimeout
+	s.mu.Unlock()
+
+	for _, c := range s.companions {
+		g.Go(func() error {
+			return c.ListenAndServeWithShutdown(groupCtx, WithShutdownTimeout(gracefulTimeout), WithClock(s.clock))
+		})
+	}
+
+	// The admin server outlives the drain, to report its progress.
+	adminCtx, adminCancel := context.WithCancel(context.Background())
+	defer adminCancel()
+
+	if s.admin != nil {
+		g.Go(func() error {
+			return s.admin.ListenAndServeWithShutdown(adminCtx, WithShutdownTimeout(gracefulTimeout), WithClock(s.clock))
+		})
+	}
+
+	for _, svc := range s.services {
+		g.Go(func() error {
+			if err := svc.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return phaseError(PhaseServe, err)
+			}
+
+			return nil
+		})
+	}
+
+	for _, serveFn := range serveFns {
+		g.Go(func() error {
+			if err := serveFn(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.reportError(PhaseServe, err)
+				return phaseError(PhaseServe, err)
+			}
+
+			return nil
+		})
+	}
 	g.Go(func() error {
-		<-ctx.Done()
+		<-groupCtx.Done()
+		defer adminCancel()
+
+		started := s.clock.Now()
+		var err error
+		if s.immediateClose || errors.Is(context.Cause(groupCtx), ErrImmediateStop) {
+			err = s.stop()
+		} else {
+			err = s.shutdown()
+		}
+		err = errors.Join(err, s.stopWorkers(), s.cleanUp())
+		s.recordShutdown(started, err)
 
-		return s.shutdown()
+		return err
 	})
 
-	return g.Wait()
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrPanicShutdown) {
+		return ErrPanicShutdown
+	}
+
+	switch {
+	case s.returnCause && s.reportClosed:
+		return errors.Join(http.ErrServerClosed, cause)
+	case s.returnCause:
+		return cause
+	case s.reportClosed:
+		return http.ErrServerClosed
+	default:
+		return nil
+	}
 }
 
-// initialize set the default timeout to 5s and sets the GracefulServer options
-func (s *GracefulServer) initialize(opts []GracefulServerOption) {
+// initialize set the default timeout to 5s and sets the GracefulServer options.
+// It fails with [ErrAlreadyRunning] if the server is already running, as the options
+// cannot be applied safely while serving, and with [http.ErrServerClosed] if it has stopped.
+func (s *GracefulServer) initialize(opts []GracefulServerOption) (err error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return ErrAlreadyRunning
+	}
+	defer func() {
+		if err != nil {
+			s.running.Store(false)
+		}
+	}()
+
+	if State(s.state.Load()) == StateStopped {
+		return http.ErrServerClosed
+	}
+
 	s.gracefulTimeout = defaultGracefulTimeout
+	s.clock = realClock{}
+	s.acmeChallengeAddr = defaultACMEChallengeAddr
+	s.drainLogInterval = defaultDrainLogInterval
 
-	for _, opt := range opts {
+	for _, opt := range slices.Concat(s.opts, opts) {
 		opt(s)
 	}
+
+	if err := s.optionsError(); err != nil {
+		return err
+	}
+
+	if s.logger != nil && s.ErrorLog == nil {
+		s.ErrorLog = NewErrorLog(s.logger)
+	}
+
+	s.initializeAdmin()
+	s.initializeRequestCancel()
+	s.Handler = s.wrapHandler(s.Handler)
+	if err := s.initializeHTTP2(); err != nil {
+		return err
+	}
+
+	s.installConnState()
+	s.installConnContext()
+	if s.disableKeepAlives {
+		s.SetKeepAlivesEnabled(false)
+	}
+	s.initializeSystemd()
+	s.initializeStartup()
+	s.initializeWorkers()
+	s.initializeSignals()
+	s.initializeIdleShutdown()
+	s.initializeMemoryShutdown()
+	s.initializeErrorRateBreaker()
+	s.initializeExecRestart()
+	s.setState(StateStarting)
+
+	return nil
+}
+
+// Draining returns a channel that is closed once the shutdown sequence has begun.
+// Long-lived streaming handlers (e.g. server-sent events or chunked responses) can
+// select on it to flush a final event and return before the connections are forcibly closed.
+func (s *GracefulServer) Draining() <-chan struct{} {
+	return s.drainChan()
+}
+
+// serverContextKey is the context key of the server serving a request.
+type serverContextKey struct{}
+
+// DrainNotify returns a channel that is closed once the shutdown sequence of the server serving
+// the request has begun, given its context, or nil if the request is not served by a [GracefulServer].
+// Long-polling handlers can select on it to return an empty response (e.g. 204 or 304) right away,
+// instead of holding the connection until it is forcibly closed, without a reference to the server.
+func DrainNotify(ctx context.Context) <-chan struct{} {
+	s, ok := ctx.Value(serverContextKey{}).(*GracefulServer)
+	if !ok {
+		return nil
+	}
+
+	return s.Draining()
+}
+
+// drainChan lazily creates the channel closed once the shutdown sequence has begun.
+func (s *GracefulServer) drainChan() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.drain == nil {
+		s.drain = make(chan struct{})
+	}
+
+	return s.drain
+}
+
+// ShutdownDeadline returns the time at which the active connections will be forcibly closed.
+// The ok result is false until the shutdown sequence has begun. Handlers can use it, once
+// [GracefulServer.Draining] is closed, to wind down expensive work before being cut off.
+func (s *GracefulServer) ShutdownDeadline() (deadline time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.shutdownDeadline, !s.shutdownDeadline.IsZero()
+}
+
+// ExtendShutdown pushes out the deadline of an ongoing graceful shutdown by d, e.g. when
+// a large batch job is noticed mid-flight. It reports whether the deadline was extended,
+// which is not the case before the shutdown begins or once its deadline has expired.
+func (s *GracefulServer) ExtendShutdown(d time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdownCtx == nil || d <= 0 {
+		return false
+	}
+
+	deadline, ok := s.shutdownCtx.extend(d)
+	if ok {
+		s.shutdownDeadline = deadline
+	}
+
+	return ok
 }
 
-// shutdown invokes [http.Shutdown], and if there is a timeout,
-// it will forcibly close the active connections using [http.Close].
+// CloseNow stops the server right away, closing the active connections without draining them,
+// as when the serving context is canceled with [ErrImmediateStop]. If the graceful shutdown has
+// begun already, its window expires right away. It has no effect if the server is not serving.
+func (s *GracefulServer) CloseNow() {
+	s.cancelServing(ErrImmediateStop)
+
+	s.mu.Lock()
+	shutdownCtx := s.shutdownCtx
+	s.mu.Unlock()
+
+	if shutdownCtx != nil {
+		shutdownCtx.cancel(context.DeadlineExceeded)
+	}
+}
+
+// isDraining reports whether the shutdown sequence has begun.
+func (s *GracefulServer) isDraining() bool {
+	select {
+	case <-s.Draining():
+		return true
+	default:
+		return false
+	}
+}
+
+// beginDrain marks the server as draining. It is safe to call multiple times.
+func (s *GracefulServer) beginDrain() {
+	s.setState(StateDraining)
+
+	ch := s.drainChan()
+	s.drainOnce.Do(func() {
+		s.mu.Lock()
+		s.drainStartedAt = s.clock.Now()
+		s.mu.Unlock()
+
+		close(ch)
+		s.emit(Event{Type: EventDrainStarted})
+		s.setGauge(MetricDraining, 1)
+		s.notifySystemd("STOPPING=1")
+	})
+}
+
+// shutdown drains the server through the drain strategy, while notifying the tracked hijacked connections
+// and the services, running the shutdown hooks and waiting for the jobs registered through [GracefulServer.Go].
+// If the timeout expires, the active connections are forcibly closed using [http.Close] once the drain
+// returned, never concurrently with it.
+// Hijacked connections still tracked at the end of the shutdown are closed as well.
+// If a shutdown delay is set, the server is marked as draining and keeps serving during the delay first.
 func (s *GracefulServer) shutdown() error {
-	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.gracefulTimeout)
+	if s.metrics != nil {
+		start := s.clock.Now()
+		defer func() {
+			s.observeDuration(MetricDrainDuration, s.clock.Now().Sub(start))
+		}()
+	}
+
+	chaosErr := phaseError(PhaseDrain, s.injectChaos(PhaseDrain, s.chaos.DrainDelay, s.chaos.DrainErr))
+	deregisterErr := errors.Join(chaosErr, s.deregisterServer())
+
+	if s.shutdownDelay > 0 {
+		s.beginDrain()
+		<-s.clock.After(s.shutdownDelay)
+	}
+
+	s.mu.Lock()
+	gracefulTimeout, closeIdle, logInterval := s.gracefulTimeout, s.closeIdleOnDrain, s.drainLogInterval
+	s.mu.Unlock()
+
+	var ctxTimeout *deadlineContext
+	var cancel context.CancelFunc
+	if s.shutdownContext != nil {
+		stop, stopCancel := s.shutdownContext()
+		defer stopCancel()
+
+		ctxTimeout, cancel = withStopDeadline(stop)
+	} else if s.forceCloseCtx != nil {
+		ctxTimeout, cancel = withoutTimeout(s.forceCloseCtx)
+	} else {
+		ctxTimeout, cancel = withTimeout(s.clock, gracefulTimeout)
+	}
 	defer cancel()
 
-	done := make(chan struct{}, 1)
+	deadline, hasDeadline := ctxTimeout.Deadline()
+	s.mu.Lock()
+	s.shutdownCtx = ctxTimeout
+	s.shutdownDeadline = deadline
+	s.mu.Unlock()
 
-	g, groupCtx := errgroup.WithContext(ctxTimeout)
-	g.Go(func() error {
-		defer close(done)
-		return s.Shutdown(groupCtx)
-	})
-	g.Go(func() error {
-		select {
-		case <-groupCtx.Done():
-			return s.Close()
-		case <-done:
-			return nil
+	s.beginDrain()
+	defer s.closeHijacked()
+
+	if closeIdle {
+		// Disabling the keep-alives closes the idle connections right away, and
+		// the active ones once their response is written, without waiting for Shutdown.
+		s.SetKeepAlivesEnabled(false)
+	}
+
+	if s.cancelMargin > 0 && hasDeadline {
+		go s.cancelRequestsBefore(ctxTimeout)
+	}
+
+	if s.onDrainProgress != nil {
+		defer s.startTicker(s.drainProgressInterval, s.reportDrainProgress)()
+	}
+
+	if s.logger != nil {
+		defer s.startTicker(logInterval, s.logInFlightRequests)()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		s.shutdownHijacked(ctxTimeout)
+	}()
+	go func() {
+		defer wg.Done()
+		s.jobs.wait(ctxTimeout)
+	}()
+	var hooksErr error
+	go func() {
+		defer wg.Done()
+		hooksErr = s.runShutdownHooks(ctxTimeout)
+	}()
+
+	errs := make([]error, len(s.services)+1)
+	for i, svc := range s.services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i+1] = svc.Shutdown(ctxTimeout)
+		}()
+	}
+
+	if s.h2GoAway != nil {
+		s.goAwayFirst(ctxTimeout)
+	}
+
+	// The connections are forcibly closed only once the drain returned, so never while a Shutdown
+	// about to succeed is still running, and only if the window expired meanwhile: Shutdown returns
+	// as soon as it does, so that they are closed right after the deadline.
+	errs[0] = s.runDrainStrategy(ctxTimeout)
+
+	var forced bool
+	var forceErr error
+	forceCloseIfExpired := func() {
+		if ctxTimeout.Err() == nil || forced {
+			return
+		}
+		forced = true
+
+		forceErr = s.watchForceClose(func() error {
+			return s.forceCloseWithin(ctxTimeout)
+		})
+		s.reportForceClose(ctxTimeout, forceErr)
+		forceErr = phaseError(PhaseForceClose, forceErr)
+	}
+
+	forceCloseIfExpired()
+	wg.Wait()
+	// The window may expire while the hijacked connections, the jobs, the hooks or the services are still shutting down.
+	forceCloseIfExpired()
+
+	drainErr := errors.Join(errs...)
+	if !errors.Is(drainErr, context.DeadlineExceeded) && !errors.Is(drainErr, context.Canceled) {
+		s.reportError(PhaseDrain, drainErr)
+	}
+
+	if drainErr != nil && !errors.Is(drainErr, context.DeadlineExceeded) {
+		return errors.Join(deregisterErr, phaseError(PhaseDrain, drainErr), forceErr, hooksErr)
+	}
+
+	return errors.Join(deregisterErr, forceErr, hooksErr)
+}
+
+// startTicker invokes fn at every interval with the time elapsed since the call,
+// until the returned function is called.
+func (s *GracefulServer) startTicker(interval time.Duration, fn func(elapsed time.Duration)) func() {
+	start := s.clock.Now()
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-s.clock.After(interval):
+				fn(s.clock.Now().Sub(start))
+			}
 		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// reportDrainProgress invokes the drain progress callback with the connections still open.
+func (s *GracefulServer) reportDrainProgress(elapsed time.Duration) {
+	stats := s.ConnStats()
+	remaining := stats.New + stats.Active + stats.Idle + len(s.trackedHijacked())
+	s.onDrainProgress(remaining, elapsed)
+}
+
+// forceCloseWithin forcibly closes the active connections once the drain window is over.
+// If a force close timeout is set, the hijacked connections are closed as well, and the
+// expiration of each window is reported separately in the returned error.
+func (s *GracefulServer) forceCloseWithin(drainCtx context.Context) error {
+	s.mu.Lock()
+	forceTimeout := s.forceTimeout
+	s.mu.Unlock()
+
+	if forceTimeout <= 0 {
+		return s.forceClose(true)
+	}
+
+	var drainErr error
+	if errors.Is(drainCtx.Err(), context.DeadlineExceeded) {
+		drainErr = ErrDrainTimeout
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := s.forceClose(true)
+		s.closeHijacked()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return errors.Join(drainErr, err)
+	case <-s.clock.After(forceTimeout):
+		return errors.Join(drainErr, ErrForceCloseTimeout)
+	}
+}
+
+// stop forcibly closes the active connections right away, skipping the drain.
+func (s *GracefulServer) stop() error {
+	s.mu.Lock()
+	s.shutdownDeadline = s.clock.Now()
+	s.mu.Unlock()
+
+	s.beginDrain()
+	defer s.closeHijacked()
+
+	err := s.watchForceClose(func() error {
+		return s.forceClose(false)
 	})
+	s.reportError(PhaseForceClose, err)
 
-	if err := g.Wait(); !errors.Is(err, context.DeadlineExceeded) {
-		return err
+	return phaseError(PhaseForceClose, err)
+}
+
+// forceClose forcibly closes the active connections using [http.Close],
+// reporting them to the force close callback, if any. If ordered, the tagged
+// connections are closed beforehand in the order set by [WithForceCloseOrder].
+func (s *GracefulServer) forceClose(ordered bool) error {
+	s.abortRequests()
+	s.setState(StateForceClosing)
+	s.forceClosed.Store(true)
+	s.jobs.stop()
+	s.emit(Event{Type: EventForcedClose})
+	s.addCounter(MetricForcedCloses, 1)
+	s.forceClosedConns.Add(int64(s.conns.open() + len(s.trackedHijacked())))
+
+	var conns []ConnInfo
+	if s.onForceClose != nil {
+		conns = append(s.conns.snapshot(), s.hijackedInfos()...)
 	}
 
-	return nil
+	if s.forceCloseBudget > 0 {
+		s.answerPending()
+	}
+
+	if ordered {
+		s.closeTagged()
+	}
+
+	err := s.closeAll()
+
+	if s.onForceClose != nil {
+		s.onForceClose(conns)
+	}
+
+	return err
+}
+
+// closeTagged closes the connections tagged through [GracefulServer.TagConn]
+// group by group, in the order set by [WithForceCloseOrder], waiting for the interval between two groups.
+func (s *GracefulServer) closeTagged() {
+	for i, tag := range s.forceCloseOrder {
+		if i > 0 {
+			<-s.clock.After(s.forceCloseInterval)
+		}
+
+		for _, c := range s.conns.tagged(tag) {
+			_ = c.Close()
+		}
+	}
+}
+
+// closeAll forcibly closes the server and the services, if any.
+func (s *GracefulServer) closeAll() error {
+	errs := []error{s.Close()}
+	for _, svc := range s.services {
+		errs = append(errs, svc.Close())
+	}
+
+	return errors.Join(errs...)
 }
@@ -1,11 +1,18 @@
 package gracefulhttp
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -24,6 +31,17 @@ func (d *delayedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("{}"))
 }
 
+// forceShutdown cancels the context once a request is in flight,
+// then expires the graceful timeout.
+func forceShutdown(s *GracefulServer, cancel context.CancelFunc, clock *manualClock) {
+	for s.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	clock.fire()
+}
+
 func TestGracefulServer_ListenAndServeWithShutdown(t *testing.T) {
 	t.Parallel()
 
@@ -41,6 +59,8 @@ func TestGracefulServer_ListenAndServeWithShutdown(t *testing.T) {
 			done <- s.ListenAndServeWithShutdown(ctx)
 		}()
 
+		<-s.Ready()
+
 		r, err := http.Get("http://" + host)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, r.StatusCode)
@@ -64,6 +84,8 @@ func TestGracefulServer_ListenAndServeWithShutdown(t *testing.T) {
 			done <- s.ListenAndServeWithShutdown(ctx, WithCloudflareTimeouts())
 		}()
 
+		<-s.Ready()
+
 		r, err := http.Get("http://" + host)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, r.StatusCode)
@@ -80,17 +102,17 @@ func TestGracefulServer_ListenAndServeWithShutdown(t *testing.T) {
 			delay: 10 * time.Second,
 		})
 
+		clock := newManualClock()
 		ctx, cancel := context.WithCancel(context.Background())
 
 		done := make(chan error)
 		go func() {
-			done <- s.ListenAndServeWithShutdown(ctx)
+			done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock))
 		}()
 
-		go func() {
-			time.Sleep(1 * time.Second)
-			cancel()
-		}()
+		<-s.Ready()
+
+		go forceShutdown(s, cancel, clock)
 
 		_, err := http.Get("http://" + host)
 		require.Error(t, err)
@@ -100,18 +122,7 @@ func TestGracefulServer_ListenAndServeWithShutdown(t *testing.T) {
 }
 
 func TestGracefulServer_ListenAndServeTLSWithShutdown(t *testing.T) {
-	const CertFile = "certs/cert.pem"
-	const KeyFile = "certs/key.pem"
-
-	if _, err := os.Stat(CertFile); errors.Is(err, os.ErrNotExist) {
-		t.Errorf("%s not found", CertFile)
-		return
-	}
-
-	if _, err := os.Stat(KeyFile); errors.Is(err, os.ErrNotExist) {
-		t.Errorf("%s not found", KeyFile)
-		return
-	}
+	certFile, keyFile := testKeyPairFiles(t)
 
 	t.Run("gracefully shutdown on time", func(t *testing.T) {
 		host := "localhost:34572"
@@ -124,9 +135,11 @@ func TestGracefulServer_ListenAndServeTLSWithShutdown(t *testing.T) {
 
 		done := make(chan error, 1)
 		go func() {
-			done <- s.ListenAndServeTLSWithShutdown(ctx, CertFile, KeyFile)
+			done <- s.ListenAndServeTLSWithShutdown(ctx, certFile, keyFile)
 		}()
 
+		<-s.Ready()
+
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
@@ -157,13 +170,15 @@ func TestGracefulServer_ListenAndServeTLSWithShutdown(t *testing.T) {
 		go func() {
 			done <- s.ListenAndServeTLSWithShutdown(
 				ctx,
-				CertFile,
-				KeyFile,
+				certFile,
+				keyFile,
 				WithCloudflareTimeouts(),
 				WithCloudflareTLSConfig(),
 			)
 		}()
 
+		<-s.Ready()
+
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
@@ -188,17 +203,17 @@ func TestGracefulServer_ListenAndServeTLSWithShutdown(t *testing.T) {
 			delay: 10 * time.Second,
 		})
 
+		clock := newManualClock()
 		ctx, cancel := context.WithCancel(context.Background())
 
 		done := make(chan error)
 		go func() {
-			done <- s.ListenAndServeTLSWithShutdown(ctx, CertFile, KeyFile)
+			done <- s.ListenAndServeTLSWithShutdown(ctx, certFile, keyFile, WithClock(clock))
 		}()
 
-		go func() {
-			time.Sleep(1 * time.Second)
-			cancel()
-		}()
+		<-s.Ready()
+
+		go forceShutdown(s, cancel, clock)
 
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -247,3 +262,1261 @@ func TestBind(t *testing.T) {
 		})
 	}
 }
+
+func TestBind_Options(t *testing.T) {
+	s := Bind("localhost:0", nil, WithShutdownTimeout(time.Minute), WithForceCloseTimeout(time.Second))
+	require.NoError(t, s.initialize([]GracefulServerOption{WithShutdownTimeout(time.Hour)}))
+
+	assert.Equal(t, time.Hour, s.ShutdownTimeout())
+	assert.Equal(t, time.Second, s.ForceCloseTimeout())
+}
+
+func TestGracefulServer_Draining(t *testing.T) {
+	s := Bind("", nil)
+	s.initialize(nil)
+
+	select {
+	case <-s.Draining():
+		t.Fatal("draining before shutdown")
+	default:
+	}
+
+	require.NoError(t, s.shutdown())
+
+	select {
+	case <-s.Draining():
+	default:
+		t.Fatal("not draining after shutdown")
+	}
+}
+
+func TestDrainNotify(t *testing.T) {
+	assert.Nil(t, DrainNotify(context.Background()))
+
+	polling := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(polling)
+		select {
+		case <-DrainNotify(r.Context()):
+			w.WriteHeader(http.StatusNoContent)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(10*time.Second))
+	}()
+	<-s.Ready()
+
+	responses := make(chan int, 1)
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err != nil {
+			responses <- 0
+			return
+		}
+		_ = r.Body.Close()
+		responses <- r.StatusCode
+	}()
+
+	<-polling
+	cancel()
+
+	select {
+	case code := <-responses:
+		assert.Equal(t, http.StatusNoContent, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("long poll not ended by the drain")
+	}
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ShutdownDeadline(t *testing.T) {
+	clock := &fixedClock{now: time.Now()}
+
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithShutdownTimeout(3 * time.Second), WithClock(clock)})
+
+	_, ok := s.ShutdownDeadline()
+	assert.False(t, ok)
+
+	require.NoError(t, s.shutdown())
+
+	deadline, ok := s.ShutdownDeadline()
+	assert.True(t, ok)
+	assert.Equal(t, clock.now.Add(3*time.Second), deadline)
+}
+
+func TestGracefulServer_ExtendShutdown(t *testing.T) {
+	release := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+
+	clock := newManualClock()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock))
+	}()
+
+	<-s.Ready()
+
+	assert.False(t, s.ExtendShutdown(time.Minute))
+
+	codes := make(chan int, 1)
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err != nil {
+			codes <- 0
+			return
+		}
+		_ = r.Body.Close()
+		codes <- r.StatusCode
+	}()
+
+	for s.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-s.Draining()
+
+	before, _ := s.ShutdownDeadline()
+	assert.True(t, s.ExtendShutdown(time.Minute))
+
+	after, _ := s.ShutdownDeadline()
+	assert.Equal(t, before.Add(time.Minute), after)
+
+	// The expiration of the initial timeout schedules the extension instead of closing the connections.
+	clock.fire()
+	<-clock.timers
+
+	close(release)
+
+	assert.Equal(t, http.StatusOK, <-codes)
+	require.NoError(t, <-done)
+}
+
+func TestWithDrainProgress(t *testing.T) {
+	release := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	reports := make(chan int, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithDrainProgress(10*time.Millisecond, func(remaining int, elapsed time.Duration) {
+			select {
+			case reports <- remaining:
+			default:
+			}
+		}))
+	}()
+
+	<-s.Ready()
+
+	codes := make(chan int, 1)
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err != nil {
+			codes <- 0
+			return
+		}
+		_ = r.Body.Close()
+		codes <- r.StatusCode
+	}()
+
+	for s.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	assert.Equal(t, 1, <-reports)
+
+	close(release)
+
+	assert.Equal(t, http.StatusOK, <-codes)
+	require.NoError(t, <-done)
+}
+
+func TestWithForceCloseTimeout(t *testing.T) {
+	t.Run("report the drain timeout", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{
+			delay: 10 * time.Second,
+		})
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock), WithForceCloseTimeout(time.Second))
+		}()
+
+		<-s.Ready()
+
+		go forceShutdown(s, cancel, clock)
+
+		_, err := http.Get("http://" + s.ListenerAddr().String())
+		require.Error(t, err)
+
+		err = <-done
+		assert.ErrorIs(t, err, ErrDrainTimeout)
+		assert.NotErrorIs(t, err, ErrForceCloseTimeout)
+	})
+
+	t.Run("report the force close timeout", func(t *testing.T) {
+		svc := &stuckService{
+			blockingService: &blockingService{fakeService: newFakeService(nil)},
+			release:         make(chan struct{}),
+		}
+		defer close(svc.release)
+
+		s := Bind("localhost:0", &delayedHandler{})
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithService(svc), WithClock(clock), WithForceCloseTimeout(time.Second))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		clock.fire()
+		clock.fire()
+
+		err := <-done
+		assert.ErrorIs(t, err, ErrDrainTimeout)
+		assert.ErrorIs(t, err, ErrForceCloseTimeout)
+	})
+}
+
+// failingService fails to shut down right away.
+type failingService struct {
+	*fakeService
+	err error
+}
+
+func (f *failingService) Shutdown(ctx context.Context) error {
+	_ = f.fakeService.Shutdown(ctx)
+
+	return f.err
+}
+
+func TestGracefulServer_ShutdownOrdering(t *testing.T) {
+	t.Run("no force close once the drain failed before the deadline", func(t *testing.T) {
+		shutdownErr := errors.New("shutdown failed")
+		svc := &failingService{fakeService: newFakeService(nil), err: shutdownErr}
+
+		started := make(chan struct{})
+		s := Bind("localhost:0", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithService(svc), WithShutdownTimeout(time.Minute))
+		}()
+
+		<-s.Ready()
+
+		go func() {
+			<-started
+			cancel()
+		}()
+
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+
+		err = <-done
+		assert.ErrorIs(t, err, shutdownErr)
+
+		var gerr *GracefulError
+		require.ErrorAs(t, err, &gerr)
+		assert.Equal(t, PhaseDrain, gerr.Phase)
+		assert.Zero(t, s.Stats().ForcedShutdowns)
+		assert.False(t, svc.closed)
+	})
+
+	t.Run("force close once the deadline expired, after the drain returned", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{
+			delay: 10 * time.Second,
+		})
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var drained atomic.Bool
+		forced := make(chan bool, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithClock(clock),
+				WithDrainStrategy(DrainStrategyFunc(func(ctx context.Context, srv *http.Server) error {
+					defer drained.Store(true)
+					return srv.Shutdown(ctx)
+				})),
+				WithOnForceClose(func([]ConnInfo) {
+					forced <- drained.Load()
+				}),
+			)
+		}()
+
+		<-s.Ready()
+
+		go forceShutdown(s, cancel, clock)
+
+		_, err := http.Get("http://" + s.ListenerAddr().String())
+		require.Error(t, err)
+
+		require.NoError(t, <-done)
+		assert.True(t, <-forced)
+		assert.Equal(t, int64(1), s.Stats().ForcedShutdowns)
+	})
+}
+
+func TestWithNoForceClose(t *testing.T) {
+	t.Run("wait for the requests", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(10*time.Millisecond),
+				WithNoForceClose(context.Background()))
+		}()
+
+		<-s.Ready()
+
+		codes := make(chan int, 1)
+		go func() {
+			r, err := http.Get("http://" + s.ListenerAddr().String())
+			if err != nil {
+				codes <- 0
+				return
+			}
+			_ = r.Body.Close()
+			codes <- r.StatusCode
+		}()
+
+		<-started
+		cancel()
+
+		select {
+		case err := <-done:
+			t.Fatalf("shutdown completed before the request: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		_, ok := s.ShutdownDeadline()
+		assert.False(t, ok)
+		assert.False(t, s.ExtendShutdown(time.Minute))
+
+		close(release)
+
+		assert.Equal(t, http.StatusOK, <-codes)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("force close once stopped", func(t *testing.T) {
+		s := Bind("localhost:0", &delayedHandler{
+			delay: 10 * time.Second,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stop, forceClose := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(10*time.Millisecond), WithNoForceClose(stop))
+		}()
+
+		<-s.Ready()
+
+		errs := make(chan error, 1)
+		go func() {
+			r, err := http.Get("http://" + s.ListenerAddr().String())
+			if err == nil {
+				_ = r.Body.Close()
+			}
+			errs <- err
+		}()
+
+		for s.ConnStats().Active == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+		forceClose()
+
+		require.NoError(t, <-done)
+		assert.Error(t, <-errs)
+		assert.Equal(t, int64(1), s.Stats().ForcedShutdowns)
+	})
+}
+
+func TestGracefulServer_ImmediateStop(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{
+		delay: 5 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Minute))
+	}()
+
+	<-s.Ready()
+
+	errs := make(chan error, 1)
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err == nil {
+			_ = r.Body.Close()
+		}
+		errs <- err
+	}()
+
+	for s.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	cancel(ErrImmediateStop)
+
+	require.NoError(t, <-done)
+	assert.Error(t, <-errs)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWithImmediateClose(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{
+		delay: 5 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Minute), WithImmediateClose())
+	}()
+
+	<-s.Ready()
+
+	errs := make(chan error, 1)
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err == nil {
+			_ = r.Body.Close()
+		}
+		errs <- err
+	}()
+
+	for s.ConnStats().Active == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	cancel()
+
+	require.NoError(t, <-done)
+	assert.Error(t, <-errs)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWithReturnCause(t *testing.T) {
+	stopped := errors.New("stopped by the supervisor")
+
+	tests := []struct {
+		name string
+		stop func(s *GracefulServer, cancel context.CancelCauseFunc)
+		want error
+	}{
+		{
+			name: "canceled",
+			stop: func(_ *GracefulServer, cancel context.CancelCauseFunc) { cancel(nil) },
+			want: context.Canceled,
+		},
+		{
+			name: "canceled with a cause",
+			stop: func(_ *GracefulServer, cancel context.CancelCauseFunc) { cancel(stopped) },
+			want: stopped,
+		},
+		{
+			name: "closed now",
+			stop: func(s *GracefulServer, _ context.CancelCauseFunc) { s.CloseNow() },
+			want: ErrImmediateStop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("localhost:0", nil)
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.ListenAndServeWithShutdown(ctx, WithReturnCause())
+			}()
+			<-s.Ready()
+
+			tt.stop(s, cancel)
+			assert.ErrorIs(t, <-done, tt.want)
+		})
+	}
+}
+
+func TestWithReportServerClosed(t *testing.T) {
+	for _, returnCause := range []bool{false, true} {
+		s := Bind("localhost:0", nil)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		opts := []GracefulServerOption{WithReportServerClosed()}
+		if returnCause {
+			opts = append(opts, WithReturnCause())
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, opts...)
+		}()
+		<-s.Ready()
+
+		cancel()
+		err := <-done
+		assert.ErrorIs(t, err, http.ErrServerClosed)
+		assert.Equal(t, returnCause, errors.Is(err, context.Canceled))
+	}
+}
+
+func TestGracefulServer_CloseNow(t *testing.T) {
+	for name, draining := range map[string]bool{"serving": false, "draining": true} {
+		t.Run(name, func(t *testing.T) {
+			s := Bind("localhost:0", &delayedHandler{
+				delay: 5 * time.Second,
+			})
+			s.CloseNow()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Minute))
+			}()
+
+			<-s.Ready()
+
+			errs := make(chan error, 1)
+			go func() {
+				r, err := http.Get("http://" + s.ListenerAddr().String())
+				if err == nil {
+					_ = r.Body.Close()
+				}
+				errs <- err
+			}()
+
+			for s.ConnStats().Active == 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			start := time.Now()
+			if draining {
+				cancel()
+				for _, ok := s.ShutdownDeadline(); !ok; _, ok = s.ShutdownDeadline() {
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			s.CloseNow()
+
+			require.NoError(t, <-done)
+			assert.Error(t, <-errs)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestGracefulServer_AlreadyRunning(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx)
+	}()
+
+	<-s.Ready()
+
+	err := s.ListenAndServeWithShutdown(context.Background(), WithShutdownTimeout(time.Minute))
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+	assert.Equal(t, defaultGracefulTimeout, s.ShutdownTimeout())
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.ServeWithShutdown(context.Background(), ln), ErrAlreadyRunning)
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.False(t, s.running.Load())
+}
+
+func TestGracefulServer_ListenerAddr(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+	assert.Nil(t, s.ListenerAddr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx)
+	}()
+
+	<-s.Ready()
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ListenAndServeWithShutdown_BindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := Bind(ln.Addr().String(), &delayedHandler{})
+
+	require.Error(t, s.ListenAndServeWithShutdown(context.Background()))
+}
+
+func TestGracefulServer_ListenAndServeUnixWithShutdown(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gracefulhttp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A stale socket, left by a previous process, is replaced.
+	socketPath := filepath.Join(dir, "http.sock")
+	stale, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeUnixWithShutdown(ctx, socketPath)
+	}()
+	<-s.Ready()
+
+	addrs := s.ListenerAddrs()
+	require.Len(t, addrs, 2)
+	assert.Equal(t, "tcp", addrs[0].Network())
+	assert.Equal(t, "unix", addrs[1].Network())
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	defer unixClient.CloseIdleConnections()
+
+	for _, get := range []func() (*http.Response, error){
+		func() (*http.Response, error) { return http.Get("http://" + addrs[0].String()) },
+		func() (*http.Response, error) { return unixClient.Get("http://sidecar/") },
+	} {
+		r, err := get()
+		require.NoError(t, err)
+		_ = r.Body.Close()
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err = os.Stat(socketPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGracefulServer_ServeWithShutdown(t *testing.T) {
+	t.Run("serve on the listener", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+
+		s := Bind("", &delayedHandler{})
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ServeWithShutdown(ctx, ln)
+		}()
+
+		<-s.Ready()
+		assert.Equal(t, ln.Addr(), s.ListenerAddr())
+
+		r, err := http.Get("http://" + ln.Addr().String())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		_, err = ln.Accept()
+		assert.ErrorIs(t, err, net.ErrClosed)
+	})
+
+	t.Run("close the listener if the warmup fails", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+
+		warmupErr := errors.New("cold")
+		s := Bind("", &delayedHandler{})
+
+		err = s.ServeWithShutdown(context.Background(), ln, WithWarmup(func(context.Context) error { return warmupErr }))
+		assert.ErrorIs(t, err, warmupErr)
+
+		_, err = ln.Accept()
+		assert.ErrorIs(t, err, net.ErrClosed)
+	})
+}
+
+func TestWithOnReady(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ready := make(chan net.Addr, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithOnReady(func() {
+			ready <- s.ListenerAddr()
+		}))
+	}()
+
+	addr := <-ready
+	require.NotNil(t, addr)
+
+	r, err := http.Get("http://" + addr.String())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ListenAndServeTLSWithShutdown_KeyPairPEM(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithKeyPairPEM(certPEM, keyPEM))
+	}()
+
+	<-s.Ready()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"},
+	}}
+
+	r, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ListenAndServeTLSWithShutdownCert(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdownCert(ctx, []tls.Certificate{cert})
+	}()
+
+	<-s.Ready()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"},
+	}}
+
+	r, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ListenAndServeTLSWithShutdown_InvalidKeyPairPEM(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	err := s.ListenAndServeTLSWithShutdown(context.Background(), "", "", WithKeyPairPEM([]byte("cert"), []byte("key")))
+	require.Error(t, err)
+}
+
+func TestGracefulServer_ListenAndServeTLSWithShutdown_ALPN(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithKeyPairPEM(certPEM, keyPEM), WithALPN("http/1.1"))
+	}()
+
+	<-s.Ready()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	conn, err := tls.Dial("tcp", s.ListenerAddr().String(), &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http/1.1", conn.ConnectionState().NegotiatedProtocol)
+	require.NoError(t, conn.Close())
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ListenAndServeBothWithShutdown(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+
+	started := make(chan struct{}, 2)
+	s := Bind("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("{}"))
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeBothWithShutdown(ctx, "localhost:0", "localhost:0", "", "", WithKeyPairPEM(certPEM, keyPEM))
+	}()
+
+	<-s.Ready()
+
+	addrs := s.ListenerAddrs()
+	require.Len(t, addrs, 2)
+	assert.Equal(t, addrs[0], s.ListenerAddr())
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"},
+	}}
+
+	results := make(chan error, 2)
+	for _, url := range []string{"http://" + addrs[0].String(), "https://" + addrs[1].String()} {
+		go func() {
+			r, err := client.Get(url)
+			if err == nil {
+				err = r.Body.Close()
+			}
+			results <- err
+		}()
+	}
+
+	<-started
+	<-started
+	cancel()
+
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+	require.NoError(t, <-done)
+}
+
+func TestGracefulServer_ListenAndServeBothWithShutdown_BindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+
+	s := Bind("", &delayedHandler{})
+
+	err = s.ListenAndServeBothWithShutdown(context.Background(), "localhost:0", ln.Addr().String(), "", "", WithKeyPairPEM(certPEM, keyPEM))
+	require.Error(t, err)
+	assert.Nil(t, s.ListenerAddr())
+}
+
+// countingListener counts the accepted connections.
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+
+	return c, err
+}
+
+func TestWithListenerWrapper(t *testing.T) {
+	var wrapped *countingListener
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithListenerWrapper(func(ln net.Listener) net.Listener {
+			wrapped = &countingListener{Listener: ln}
+			return wrapped
+		}))
+	}()
+
+	<-s.Ready()
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+
+	cancel()
+
+	require.NoError(t, <-done)
+	assert.Equal(t, int32(1), wrapped.accepted.Load())
+}
+
+func TestWithListenConfig(t *testing.T) {
+	var controlled atomic.Bool
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithListenConfig(net.ListenConfig{
+			Control: func(network, address string, c syscall.RawConn) error {
+				controlled.Store(true)
+				return nil
+			},
+		}))
+	}()
+
+	<-s.Ready()
+	assert.True(t, controlled.Load())
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestWithNetwork(t *testing.T) {
+	s := Bind(":0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithNetwork("tcp4"))
+	}()
+
+	<-s.Ready()
+	addr, ok := s.ListenerAddr().(*net.TCPAddr)
+	require.True(t, ok)
+	assert.NotNil(t, addr.IP.To4())
+
+	cancel()
+
+	require.NoError(t, <-done)
+
+	assert.ErrorIs(t, ValidateOptions(WithNetwork("udp")), ErrInvalidOption)
+}
+
+func TestWithListenConfig_ControlError(t *testing.T) {
+	errControl := errors.New("control failed")
+
+	s := Bind("localhost:0", &delayedHandler{})
+
+	err := s.ListenAndServeWithShutdown(context.Background(), WithListenConfig(net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			return errControl
+		},
+	}))
+	assert.ErrorIs(t, err, errControl)
+}
+
+func TestBindMulti(t *testing.T) {
+	s := BindMulti([]string{"127.0.0.1:0", "localhost:0"}, &delayedHandler{})
+	assert.Equal(t, "127.0.0.1:0", s.Addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx)
+	}()
+
+	<-s.Ready()
+
+	addrs := s.ListenerAddrs()
+	require.Len(t, addrs, 2)
+
+	for _, addr := range addrs {
+		r, err := http.Get("http://" + addr.String())
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+	}
+
+	cancel()
+
+	require.NoError(t, <-done)
+}
+
+func TestBindMulti_BindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := BindMulti([]string{"localhost:0", ln.Addr().String()}, &delayedHandler{})
+
+	err = s.ListenAndServeWithShutdown(context.Background())
+	require.Error(t, err)
+	assert.Nil(t, s.ListenerAddr())
+}
+
+type contextKey string
+
+func TestGracefulServer_BaseContext(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []GracefulServerOption
+		want string
+	}{
+		{
+			name: "derive from the serving context",
+			want: "serve",
+		},
+		{
+			name: "custom base context",
+			opts: []GracefulServerOption{WithBaseContext(func(net.Listener) context.Context {
+				return context.WithValue(context.Background(), contextKey("key"), "base")
+			})},
+			want: "base",
+		},
+		{
+			name: "custom conn context",
+			opts: []GracefulServerOption{WithConnContext(func(ctx context.Context, c net.Conn) context.Context {
+				return context.WithValue(ctx, contextKey("key"), "conn")
+			})},
+			want: "conn",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			values := make(chan any, 1)
+			errs := make(chan error, 1)
+
+			s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				time.Sleep(100 * time.Millisecond)
+				values <- r.Context().Value(contextKey("key"))
+				errs <- r.Context().Err()
+			}))
+
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey("key"), "serve"))
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.ListenAndServeWithShutdown(ctx, tt.opts...)
+			}()
+
+			<-s.Ready()
+
+			go func() {
+				r, err := http.Get("http://" + s.ListenerAddr().String())
+				if err == nil {
+					_ = r.Body.Close()
+				}
+			}()
+
+			<-started
+			cancel()
+
+			assert.Equal(t, tt.want, <-values)
+			assert.NoError(t, <-errs)
+			require.NoError(t, <-done)
+		})
+	}
+}
+
+func TestWithDisableKeepAlives(t *testing.T) {
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithDisableKeepAlives())
+	}()
+	<-s.Ready()
+
+	r, err := http.Get("http://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+
+	// The connection is closed once the response is written, instead of being kept idle.
+	assert.True(t, r.Close)
+	assert.Eventually(t, func() bool { return s.ConnStats() == ConnStats{} }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWithDisableGeneralOptionsHandler(t *testing.T) {
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions && r.RequestURI == "*" {
+			w.Header().Set("Allow", "GET, OPTIONS")
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithDisableGeneralOptionsHandler())
+	}()
+	<-s.Ready()
+
+	conn, err := net.Dial("tcp", s.ListenerAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "OPTIONS * HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	require.NoError(t, err)
+
+	r, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	_ = r.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, r.StatusCode)
+	assert.Equal(t, "GET, OPTIONS", r.Header.Get("Allow"))
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWithShutdownContext(t *testing.T) {
+	started := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+
+	stop, stopCancel := context.WithCancel(context.Background())
+	deadline := time.Now().Add(time.Hour)
+	var factoryCalled, canceled atomic.Bool
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(10*time.Millisecond),
+			WithShutdownContext(func() (context.Context, context.CancelFunc) {
+				factoryCalled.Store(true)
+				ctx, cancel := context.WithDeadline(stop, deadline)
+				return ctx, func() {
+					canceled.Store(true)
+					cancel()
+				}
+			}))
+	}()
+	<-s.Ready()
+
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err == nil {
+			_ = r.Body.Close()
+		}
+	}()
+	<-started
+
+	cancel()
+	<-s.Draining()
+
+	// The graceful timeout does not apply: the drain lasts until the context of the caller is done.
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown completed before the context: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	assert.True(t, factoryCalled.Load())
+	got, ok := s.ShutdownDeadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Equal(got))
+	assert.False(t, s.ExtendShutdown(time.Minute))
+
+	stopCancel()
+
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(1), s.Stats().ForcedShutdowns)
+	assert.True(t, canceled.Load())
+}
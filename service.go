@@ -0,0 +1,95 @@
+package gracefulhttp
+
+import (
+	"context"
+	"sync"
+)
+
+// Service is a server running alongside a [GracefulServer] and sharing its lifecycle,
+// such as an HTTP/3 server. It is started once the listener of the server is bound,
+// and it is shut down together with the server within the same graceful timeout.
+type Service interface {
+	// Serve runs the service until it is shut down. Returning [http.ErrServerClosed]
+	// is not considered an error, while any other error stops the server as well.
+	Serve() error
+	// Shutdown gracefully stops the service, returning once it is stopped or the context is done.
+	Shutdown(ctx context.Context) error
+	// Close forcibly stops the service, and is invoked when the graceful timeout expires.
+	Close() error
+}
+
+// WithService runs the service alongside the server, starting it once the listener is bound and
+// shutting it down in parallel with the server. The service can read the handler and the TLS
+// configuration of the server when Serve is invoked, after the options have been applied:
+// when serving TLS, the certificate files are loaded into the TLS configuration to be shared.
+func WithService(svc Service) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.services = append(s.services, svc)
+	}
+}
+
+// GracefulStopper is a server running on its own, such as a *grpc.Server, that can be stopped
+// gracefully or forcibly.
+type GracefulStopper interface {
+	// GracefulStop stops accepting new work and blocks until the pending work is completed.
+	GracefulStop()
+	// Stop forcibly stops the server, canceling the pending work.
+	Stop()
+}
+
+// WithCoordinatedStop coordinates the stop of the provided server with the shutdown: GracefulStop
+// runs in parallel with the drain of the server under the same graceful timeout, and Stop is invoked
+// when the timeout expires. The server keeps being served by the caller, e.g. on its own listener.
+func WithCoordinatedStop(srv GracefulStopper) GracefulServerOption {
+	return WithService(&stopperService{
+		srv:     srv,
+		stopped: make(chan struct{}),
+	})
+}
+
+// stopperService adapts a [GracefulStopper] to a [Service].
+type stopperService struct {
+	srv GracefulStopper
+
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// Serve waits until the server is stopped, as it is served by the caller.
+func (s *stopperService) Serve() error {
+	<-s.stopped
+
+	return nil
+}
+
+// Shutdown gracefully stops the server, returning once it is stopped or the context is done.
+func (s *stopperService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.srv.GracefulStop()
+	}()
+
+	select {
+	case <-done:
+		s.markStopped()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close forcibly stops the server.
+func (s *stopperService) Close() error {
+	s.srv.Stop()
+	s.markStopped()
+
+	return nil
+}
+
+// markStopped releases Serve. It is safe to call multiple times.
+func (s *stopperService) markStopped() {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+	})
+}
@@ -0,0 +1,189 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeService records the calls of the server lifecycle.
+type fakeService struct {
+	serveErr error
+	stopped  chan struct{}
+
+	shutdownCtx context.Context
+	closed      bool
+}
+
+func newFakeService(serveErr error) *fakeService {
+	return &fakeService{
+		serveErr: serveErr,
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (f *fakeService) Serve() error {
+	if f.serveErr != nil {
+		return f.serveErr
+	}
+
+	<-f.stopped
+
+	return http.ErrServerClosed
+}
+
+func (f *fakeService) Shutdown(ctx context.Context) error {
+	f.shutdownCtx = ctx
+	close(f.stopped)
+
+	return nil
+}
+
+func (f *fakeService) Close() error {
+	f.closed = true
+
+	return nil
+}
+
+func TestWithService(t *testing.T) {
+	t.Run("shut down together with the server", func(t *testing.T) {
+		svc := newFakeService(nil)
+		s := Bind("localhost:0", &delayedHandler{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithService(svc))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		require.NoError(t, <-done)
+		require.NotNil(t, svc.shutdownCtx)
+		_, ok := svc.shutdownCtx.Deadline()
+		assert.True(t, ok)
+		assert.False(t, svc.closed)
+	})
+
+	t.Run("stop the server when the service fails", func(t *testing.T) {
+		errServe := errors.New("serve failed")
+		s := Bind("localhost:0", &delayedHandler{})
+
+		err := s.ListenAndServeWithShutdown(context.Background(), WithService(newFakeService(errServe)))
+		assert.ErrorIs(t, err, errServe)
+	})
+
+	t.Run("close when the timeout expires", func(t *testing.T) {
+		svc := &blockingService{fakeService: newFakeService(nil)}
+		s := Bind("localhost:0", &delayedHandler{})
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithService(svc), WithClock(clock))
+		}()
+
+		<-s.Ready()
+		cancel()
+		clock.fire()
+
+		require.NoError(t, <-done)
+		assert.True(t, svc.closed)
+	})
+}
+
+// blockingService is a service whose shutdown lasts until the context is done.
+type blockingService struct {
+	*fakeService
+}
+
+func (b *blockingService) Shutdown(ctx context.Context) error {
+	<-ctx.Done()
+	close(b.stopped)
+
+	return ctx.Err()
+}
+
+// stuckService is a service whose close lasts until released.
+type stuckService struct {
+	*blockingService
+	release chan struct{}
+}
+
+func (s *stuckService) Close() error {
+	<-s.release
+
+	return nil
+}
+
+// fakeStopper is a server whose graceful stop lasts until released.
+type fakeStopper struct {
+	release chan struct{}
+
+	gracefulStopped atomic.Bool
+	stopped         atomic.Bool
+}
+
+func (f *fakeStopper) GracefulStop() {
+	<-f.release
+	f.gracefulStopped.Store(true)
+}
+
+func (f *fakeStopper) Stop() {
+	f.stopped.Store(true)
+}
+
+func TestWithCoordinatedStop(t *testing.T) {
+	t.Run("stop gracefully together with the server", func(t *testing.T) {
+		srv := &fakeStopper{release: make(chan struct{})}
+		close(srv.release)
+
+		s := Bind("localhost:0", &delayedHandler{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithCoordinatedStop(srv))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		require.NoError(t, <-done)
+		assert.True(t, srv.gracefulStopped.Load())
+		assert.False(t, srv.stopped.Load())
+	})
+
+	t.Run("stop forcibly when the timeout expires", func(t *testing.T) {
+		srv := &fakeStopper{release: make(chan struct{})}
+		defer close(srv.release)
+
+		s := Bind("localhost:0", &delayedHandler{})
+
+		clock := newManualClock()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithCoordinatedStop(srv), WithClock(clock))
+		}()
+
+		<-s.Ready()
+		cancel()
+		clock.fire()
+
+		require.NoError(t, <-done)
+		assert.False(t, srv.gracefulStopped.Load())
+		assert.True(t, srv.stopped.Load())
+	})
+}
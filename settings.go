@@ -0,0 +1,48 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// ShutdownTimeout returns the timeout of the graceful shutdown, after which the connections are forcibly closed.
+// Like the other accessors of the settings, it reports the value in effect once the options, including
+// the presets and those applied through [GracefulServer.Reload], have interacted: the options are applied
+// when the server starts serving, and the defaults are reported before.
+func (s *GracefulServer) ShutdownTimeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.gracefulTimeout <= 0 {
+		return defaultGracefulTimeout
+	}
+
+	return s.gracefulTimeout
+}
+
+// ForceCloseTimeout returns the time given to forcibly close the connections, or 0 if it is unbounded.
+func (s *GracefulServer) ForceCloseTimeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.forceTimeout
+}
+
+// ShutdownDelay returns the time the server keeps serving once the shutdown begins, before draining.
+func (s *GracefulServer) ShutdownDelay() time.Duration {
+	return s.shutdownDelay
+}
+
+// MaxInFlightRequests returns the maximum number of requests processed concurrently, or 0 if there is no limit.
+func (s *GracefulServer) MaxInFlightRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return max(s.maxInFlight, 0)
+}
+
+// EffectiveTLSConfig returns a copy of the TLS configuration the server uses, as set by the TLS options,
+// or nil if there is none. The certificates loaded from the files given when serving are not included.
+func (s *GracefulServer) EffectiveTLSConfig() *tls.Config {
+	return s.TLSConfig.Clone()
+}
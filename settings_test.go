@@ -0,0 +1,53 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_EffectiveSettings(t *testing.T) {
+	s := Bind("localhost:0", nil)
+	assert.Equal(t, defaultGracefulTimeout, s.ShutdownTimeout())
+	assert.Zero(t, s.ForceCloseTimeout())
+	assert.Zero(t, s.MaxInFlightRequests())
+	assert.Nil(t, s.EffectiveTLSConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx,
+			WithHerokuTimeouts(),
+			WithShutdownTimeout(10*time.Second),
+			WithForceCloseTimeout(time.Second),
+			WithShutdownDelay(time.Millisecond),
+			WithMaxInFlightRequests(8, time.Second),
+			WithMozillaTLSProfile(MozillaModern),
+		)
+	}()
+
+	<-s.Ready()
+
+	assert.Equal(t, 10*time.Second, s.ShutdownTimeout())
+	assert.Equal(t, time.Second, s.ForceCloseTimeout())
+	assert.Equal(t, time.Millisecond, s.ShutdownDelay())
+	assert.Equal(t, 8, s.MaxInFlightRequests())
+
+	config := s.EffectiveTLSConfig()
+	require.NotNil(t, config)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+
+	config.MinVersion = tls.VersionTLS10
+	assert.Equal(t, uint16(tls.VersionTLS13), s.TLSConfig.MinVersion)
+
+	require.NoError(t, s.Reload(WithShutdownTimeout(20*time.Second)))
+	assert.Equal(t, 20*time.Second, s.ShutdownTimeout())
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,35 @@
+package gracefulhttp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// initializeSignals watches the shutdown signals in the background, if any.
+func (s *GracefulServer) initializeSignals() {
+	if len(s.shutdownSignals) == 0 {
+		return
+	}
+
+	s.background = append(s.background, s.watchShutdownSignals)
+}
+
+// watchShutdownSignals starts the shutdown once one of the shutdown signals is received,
+// unless the context is done first.
+func (s *GracefulServer) watchShutdownSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, s.shutdownSignals...)
+	defer signal.Stop(ch)
+
+	select {
+	case <-ctx.Done():
+	case sig := <-ch:
+		if s.logger != nil {
+			s.logger.Info("gracefulhttp: shutdown signal received", "signal", sig.String())
+		}
+
+		s.cancelServing(fmt.Errorf("gracefulhttp: received %v", sig))
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package gracefulhttp
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithShutdownSignals(t *testing.T) {
+	// The signals sent before the server watches them must not terminate the test.
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	defer signal.Stop(ch)
+
+	s := Bind("localhost:0", nil)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(context.Background(), WithShutdownSignals(syscall.SIGUSR1))
+	}()
+	<-s.Ready()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+		select {
+		case err := <-errCh:
+			assert.NoError(t, err)
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("the server did not shut down on the signal")
+		}
+	}
+}
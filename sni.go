@@ -0,0 +1,107 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// CertPair is the pair of files containing a PEM encoded certificate and its private key.
+type CertPair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// sniCertificates selects the certificate by the server name indicated by the client.
+type sniCertificates struct {
+	certs    map[string]*tls.Certificate
+	fallback func(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// newSNICertificates loads the certificates of the hosts.
+func newSNICertificates(pairs map[string]CertPair) (*sniCertificates, error) {
+	s := &sniCertificates{
+		certs: make(map[string]*tls.Certificate, len(pairs)),
+	}
+
+	for host, pair := range pairs {
+		cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		s.certs[strings.ToLower(host)] = &cert
+	}
+
+	return s, nil
+}
+
+// GetCertificate returns the certificate of the host matching the server name, trying
+// an exact match first and then a wildcard one (e.g. "*.example.com"). If none matches,
+// it delegates to the fallback, if any, or lets the TLS configuration choose among its Certificates.
+func (s *sniCertificates) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert, ok := lookupHost(s.certs, hello.ServerName); ok {
+		return cert, nil
+	}
+
+	if s.fallback != nil {
+		return s.fallback(hello)
+	}
+
+	return nil, nil
+}
+
+// lookupHost returns the value of the host matching the server name, whose keys are lower case,
+// trying an exact match first and then a wildcard one (e.g. "*.example.com").
+func lookupHost[T any](hosts map[string]T, serverName string) (T, bool) {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+
+	if v, ok := hosts[name]; ok {
+		return v, true
+	}
+
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if v, ok := hosts["*"+name[i:]]; ok {
+			return v, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// WithHostHandlers serves the requests of each host, which may be a wildcard (e.g. "*.example.com"),
+// with its own handler, so that a server hosts several virtual hosts without a host switching mux.
+// The requests of the other hosts are served by the handler of the server. Combined with [WithCertificates],
+// each host can be served with its own certificate as well.
+func WithHostHandlers(handlers map[string]http.Handler) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.hostHandlers = make(map[string]http.Handler, len(handlers))
+		for host, h := range handlers {
+			if h == nil {
+				s.invalidOption("WithHostHandlers", "nil handler for %s", host)
+				continue
+			}
+
+			s.hostHandlers[strings.ToLower(host)] = h
+		}
+	}
+}
+
+// hostHandler serves the requests with the handler of their host, or with the fallback if none matches.
+func hostHandler(hosts map[string]http.Handler, fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		if h, ok := lookupHost(hosts, host); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		fallback.ServeHTTP(w, r)
+	})
+}
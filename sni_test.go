@@ -0,0 +1,113 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCertificates(t *testing.T) {
+	dir := t.TempDir()
+
+	pairs := make(map[string]CertPair)
+	for _, host := range []string{"a.example.com", "*.example.org"} {
+		pair := CertPair{
+			CertFile: filepath.Join(dir, host+".crt"),
+			KeyFile:  filepath.Join(dir, host+".key"),
+		}
+		writeTestKeyPair(t, host, pair.CertFile, pair.KeyFile)
+		pairs[host] = pair
+	}
+
+	fallbackPEM, fallbackKey := generateTestKeyPair(t, "fallback")
+
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{
+		WithCertificates(pairs),
+		WithCertificateManager(&staticCertManager{certPEM: fallbackPEM, keyPEM: fallbackKey}),
+		WithACMEChallengeAddr(""),
+	})
+
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{serverName: "a.example.com", want: "a.example.com"},
+		{serverName: "A.Example.com.", want: "a.example.com"},
+		{serverName: "b.example.org", want: "*.example.org"},
+		{serverName: "b.example.com", want: "fallback"},
+		{serverName: "", want: "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverName, func(t *testing.T) {
+			cert, err := s.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, leafCommonName(t, cert))
+		})
+	}
+}
+
+func TestWithCertificates_MissingFiles(t *testing.T) {
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithCertificates(map[string]CertPair{
+		"example.com": {CertFile: "missing.crt", KeyFile: "missing.key"},
+	})})
+
+	_, _, err := s.initializeTLS("", "")
+	require.Error(t, err)
+}
+
+type staticCertManager struct {
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func (m *staticCertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(m.certPEM, m.keyPEM)
+	return &cert, err
+}
+
+func TestWithHostHandlers(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		})
+	}
+
+	s := Bind("", named("default"))
+	require.NoError(t, s.initialize([]GracefulServerOption{WithHostHandlers(map[string]http.Handler{
+		"api.example.com": named("api"),
+		"*.Example.org":   named("org"),
+	})}))
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "api.example.com", want: "api"},
+		{host: "API.example.com:8443", want: "api"},
+		{host: "www.example.org", want: "org"},
+		{host: "example.org", want: "default"},
+		{host: "[::1]:8080", want: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+
+	assert.ErrorIs(t, ValidateOptions(WithHostHandlers(map[string]http.Handler{"a.example.com": nil})), ErrInvalidOption)
+}
@@ -0,0 +1,128 @@
+package gracefulhttp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultStartupTimeout is the default time given to each attempt of the startup checks.
+	defaultStartupTimeout = 1 * time.Second
+	// defaultStartupBackoff is the default delay before retrying the failed startup checks.
+	defaultStartupBackoff = 1 * time.Second
+	// maxStartupBackoff bounds the delay between two attempts of the startup checks.
+	maxStartupBackoff = 30 * time.Second
+)
+
+// WithStartupCheck adds a check gating the readiness reported by [GracefulServer.ReadinessHandler],
+// as a Kubernetes startup probe would: the server accepts connections right away, but is reported
+// ready only once all the checks have passed. The failed checks are retried as set by [WithStartupProbe].
+func WithStartupCheck(check func(ctx context.Context) error) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.startupChecks = append(s.startupChecks, check)
+	}
+}
+
+// WithStartupProbe sets the time given to each attempt of the startup checks, one second by default,
+// and the delay before retrying them after a failure, one second by default, doubled at each failure
+// up to 30 seconds. Non-positive values fall back to the defaults.
+func WithStartupProbe(timeout, backoff time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if timeout < 0 {
+			s.invalidOption("WithStartupProbe", "negative timeout %v", timeout)
+		}
+
+		if backoff < 0 {
+			s.invalidOption("WithStartupProbe", "negative backoff %v", backoff)
+		}
+
+		s.startupTimeout = max(timeout, 0)
+		s.startupBackoff = max(backoff, 0)
+	}
+}
+
+// ReadinessHandler returns a handler answering 200 OK once the server is ready, i.e. listening and
+// with all the startup checks passed, and 503 Service Unavailable before then and once the drain begins.
+// While draining, the body reports as JSON what the drain is waiting on, so that it shows when probed
+// during a rollout, e.g. {"status": "draining", "in_flight": 3, "elapsed": "2.5s", "deadline": ...}:
+// the requests still in flight, the time elapsed since the drain began and, once the connections
+// stop being accepted, the time at which they will be forcibly closed.
+// It is served on /readyz by the admin server, if any.
+func (s *GracefulServer) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		switch {
+		case s.isDraining():
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(s.drainStatus())
+		case !s.started.Load():
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+		default:
+			_, _ = fmt.Fprintln(w, "ready")
+		}
+	})
+}
+
+// initializeStartup runs the startup checks in the background, if any.
+func (s *GracefulServer) initializeStartup() {
+	if len(s.startupChecks) == 0 {
+		return
+	}
+
+	s.background = append(s.background, s.runStartupChecks)
+}
+
+// runStartupChecks runs the startup checks until they all pass, backing off after each failure,
+// then marks the server as started, unless the context is done first.
+func (s *GracefulServer) runStartupChecks(ctx context.Context) {
+	select {
+	case <-s.Ready():
+	case <-ctx.Done():
+		return
+	}
+
+	timeout := cmp.Or(s.startupTimeout, defaultStartupTimeout)
+	backoff := cmp.Or(s.startupBackoff, defaultStartupBackoff)
+
+	pending := s.startupChecks
+	for {
+		pending = runChecks(ctx, pending, timeout)
+		if len(pending) == 0 {
+			s.started.Store(true)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(backoff):
+		}
+
+		backoff = min(2*backoff, maxStartupBackoff)
+	}
+}
+
+// runChecks runs the checks, each one within the timeout, returning the failed ones.
+func runChecks(ctx context.Context, checks []func(ctx context.Context) error, timeout time.Duration) []func(ctx context.Context) error {
+	var failed []func(ctx context.Context) error
+	for _, check := range checks {
+		if err := runCheck(ctx, check, timeout); err != nil {
+			failed = append(failed, check)
+		}
+	}
+
+	return failed
+}
+
+// runCheck runs the check within the timeout.
+func runCheck(ctx context.Context, check func(ctx context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return check(ctx)
+}
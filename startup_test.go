@@ -0,0 +1,109 @@
+package gracefulhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readiness returns the status code answered by the readiness handler.
+func readiness(s *GracefulServer) int {
+	w := httptest.NewRecorder()
+	s.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	return w.Code
+}
+
+func TestWithStartupCheck(t *testing.T) {
+	results := make(chan error, 1)
+	clock := newManualClock()
+	s := Bind("", nil)
+
+	require.NoError(t, s.initialize([]GracefulServerOption{
+		WithStartupCheck(func(context.Context) error { return <-results }),
+		WithClock(clock),
+	}))
+	require.Len(t, s.background, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[0](ctx)
+
+	assert.Equal(t, http.StatusServiceUnavailable, readiness(s))
+	s.markReady()
+
+	results <- errors.New("cache not primed")
+	clock.fire()
+	assert.Equal(t, http.StatusServiceUnavailable, readiness(s))
+
+	results <- nil
+	assert.Eventually(t, func() bool {
+		return readiness(s) == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	s.beginDrain()
+	assert.Equal(t, http.StatusServiceUnavailable, readiness(s))
+}
+
+func TestGracefulServer_ReadinessHandler(t *testing.T) {
+	s := Bind("", nil)
+	require.NoError(t, s.initialize(nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, readiness(s))
+
+	s.markReady()
+	assert.Equal(t, http.StatusOK, readiness(s))
+}
+
+func TestGracefulServer_ReadinessHandler_draining(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Minute))
+	}()
+	<-s.Ready()
+
+	go func() {
+		r, err := http.Get("http://" + s.ListenerAddr().String())
+		if err == nil {
+			_ = r.Body.Close()
+		}
+	}()
+	<-started
+
+	cancel()
+	<-s.Draining()
+
+	var status drainStatus
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		s.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+
+		return status.Deadline != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "draining", status.Status)
+	assert.Equal(t, int64(1), status.InFlight)
+	assert.GreaterOrEqual(t, status.Elapsed, Duration(0))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *status.Deadline, 5*time.Second)
+
+	close(release)
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,70 @@
+package gracefulhttp
+
+// State is a state of the lifecycle of a [GracefulServer]. The server goes through the states
+// in order, from StateIdle to StateStopped, skipping those that do not apply, e.g. StateForceClosing
+// when the drain completes in time. A stopped server cannot serve again, as an [http.Server] once shut down.
+type State int32
+
+const (
+	// StateIdle is the state of a server which has never served.
+	StateIdle State = iota
+	// StateStarting is the state of a server whose options are applied, while its listeners are created.
+	StateStarting
+	// StateRunning is the state of a server accepting connections.
+	StateRunning
+	// StateDraining is the state of a server whose shutdown sequence has begun.
+	StateDraining
+	// StateForceClosing is the state of a server forcibly closing its connections.
+	StateForceClosing
+	// StateStopped is the state of a server which has stopped serving.
+	StateStopped
+)
+
+// String returns the name of the state.
+func (st State) String() string {
+	switch st {
+	case StateIdle:
+		return "idle"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateForceClosing:
+		return "force closing"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the current lifecycle state of the server, e.g. to be reported by a health handler.
+func (s *GracefulServer) State() State {
+	return State(s.state.Load())
+}
+
+// WithOnStateChange sets a callback invoked on every transition of the lifecycle state of the server,
+// with the previous state and the new one. It is invoked synchronously, from the goroutine making the transition.
+func WithOnStateChange(fn func(from, to State)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.onStateChange = fn
+	}
+}
+
+// setState makes the server transition to the state, invoking the state change callback, if any.
+func (s *GracefulServer) setState(state State) {
+	old := State(s.state.Swap(int32(state)))
+	if old != state && s.onStateChange != nil {
+		s.onStateChange(old, state)
+	}
+}
+
+// release marks the server as stopped, for good: serving it again fails with [http.ErrServerClosed].
+// Hence the fields of the embedded http.Server replaced when serving, such as Handler, ConnState,
+// ConnContext or BaseContext, are left as they are rather than restored.
+func (s *GracefulServer) release() {
+	s.setState(StateStopped)
+	s.running.Store(false)
+}
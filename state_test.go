@@ -0,0 +1,103 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateRecorder records the state transitions of a server.
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []State
+}
+
+// record records the new state.
+func (r *stateRecorder) record(_, to State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.states = append(r.states, to)
+}
+
+func TestWithOnStateChange(t *testing.T) {
+	t.Run("graceful shutdown", func(t *testing.T) {
+		var rec stateRecorder
+		s := Bind("localhost:0", nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithOnStateChange(rec.record))
+		}()
+		<-s.Ready()
+
+		assert.Equal(t, StateRunning, s.State())
+
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.Equal(t, StateStopped, s.State())
+		assert.Equal(t, []State{StateStarting, StateRunning, StateDraining, StateStopped}, rec.states)
+	})
+
+	t.Run("forced close", func(t *testing.T) {
+		var rec stateRecorder
+		started := make(chan struct{})
+		s := Bind("localhost:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(50*time.Millisecond), WithOnStateChange(rec.record))
+		}()
+		<-s.Ready()
+
+		go func() {
+			if r, err := http.Get("http://" + s.ListenerAddr().String()); err == nil {
+				_ = r.Body.Close()
+			}
+		}()
+		<-started
+
+		cancel()
+		<-done
+
+		assert.Equal(t, []State{StateStarting, StateRunning, StateDraining, StateForceClosing, StateStopped}, rec.states)
+	})
+
+	t.Run("no serving once stopped", func(t *testing.T) {
+		var rec stateRecorder
+		s := Bind("localhost:0", nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithOnStateChange(rec.record))
+		}()
+		<-s.Ready()
+
+		cancel()
+		require.NoError(t, <-done)
+
+		err := s.ListenAndServeWithShutdown(context.Background())
+		assert.ErrorIs(t, err, http.ErrServerClosed)
+		assert.Equal(t, StateStopped, State(s.state.Load()))
+		assert.Equal(t, []State{StateStarting, StateRunning, StateDraining, StateStopped}, rec.states)
+		assert.False(t, s.running.Load())
+	})
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "idle", Bind("", nil).State().String())
+	assert.Equal(t, "force closing", StateForceClosing.String())
+	assert.Equal(t, "unknown", State(-1).String())
+}
@@ -0,0 +1,159 @@
+package gracefulhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// serverStatus is the state of the server rendered by [GracefulServer.StatusHandler].
+type serverStatus struct {
+	State        string          `json:"state"`
+	Uptime       Duration        `json:"uptime"`
+	Deadline     *time.Time      `json:"deadline,omitempty"`
+	Timeouts     statusTimeouts  `json:"timeouts"`
+	Connections  statusConns     `json:"connections"`
+	LastShutdown *shutdownReport `json:"last_shutdown,omitempty"`
+}
+
+// statusTimeouts are the timeouts configured on the server.
+type statusTimeouts struct {
+	Shutdown   Duration `json:"shutdown"`
+	ForceClose Duration `json:"force_close,omitempty"`
+	Read       Duration `json:"read"`
+	ReadHeader Duration `json:"read_header"`
+	Write      Duration `json:"write"`
+	Idle       Duration `json:"idle"`
+}
+
+// statusConns are the connections handled by the server.
+type statusConns struct {
+	New      int `json:"new"`
+	Active   int `json:"active"`
+	Idle     int `json:"idle"`
+	Hijacked int `json:"hijacked"`
+}
+
+// drainStatus is the progress of the drain rendered by [GracefulServer.ReadinessHandler].
+type drainStatus struct {
+	Status   string     `json:"status"`
+	InFlight int64      `json:"in_flight"`
+	Elapsed  Duration   `json:"elapsed"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// drainStatus returns the progress of the drain.
+func (s *GracefulServer) drainStatus() drainStatus {
+	s.mu.Lock()
+	started, deadline := s.drainStartedAt, s.shutdownDeadline
+	s.mu.Unlock()
+
+	status := drainStatus{
+		Status:   "draining",
+		InFlight: s.inFlightRequests.Load(),
+		Elapsed:  Duration(s.clock.Now().Sub(started)),
+	}
+
+	if !deadline.IsZero() {
+		status.Deadline = &deadline
+	}
+
+	return status
+}
+
+// shutdownReport describes the last completed shutdown.
+type shutdownReport struct {
+	Started   time.Time `json:"started"`
+	Duration  Duration  `json:"duration"`
+	Forced    bool      `json:"forced"`
+	DrainedBy string    `json:"drained_by"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// StatusHandler returns a handler rendering the current state of the server as JSON, such as
+// {"state": "draining", "uptime": "1h2m3s", "deadline": ..., "timeouts": {...}, "connections": {...}}:
+// the state is one of "starting", "running", "draining" and "stopped", and once the shutdown
+// completes, its duration, whether the connections were forcibly closed, whether the drain completed
+// once the connections were no longer active ("connections") or once the graceful timeout expired
+// ("deadline"), and its error are reported as "last_shutdown".
+// It can be mounted wherever needed, e.g. on /debug/graceful.
+func (s *GracefulServer) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.status())
+	})
+}
+
+// status returns the current state of the server.
+func (s *GracefulServer) status() serverStatus {
+	s.mu.Lock()
+	readyAt, deadline, report := s.readyAt, s.shutdownDeadline, s.lastShutdown
+	gracefulTimeout, forceTimeout := s.gracefulTimeout, s.forceTimeout
+	s.mu.Unlock()
+
+	status := serverStatus{
+		State: "starting",
+		Timeouts: statusTimeouts{
+			Shutdown:   Duration(gracefulTimeout),
+			ForceClose: Duration(forceTimeout),
+			Read:       Duration(s.ReadTimeout),
+			ReadHeader: Duration(s.ReadHeaderTimeout),
+			Write:      Duration(s.WriteTimeout),
+			Idle:       Duration(s.IdleTimeout),
+		},
+		LastShutdown: report,
+	}
+
+	switch {
+	case report != nil:
+		status.State = "stopped"
+	case s.isDraining():
+		status.State = "draining"
+	case !readyAt.IsZero():
+		status.State = "running"
+	}
+
+	if !readyAt.IsZero() {
+		status.Uptime = Duration(s.clock.Now().Sub(readyAt))
+	}
+
+	if !deadline.IsZero() {
+		status.Deadline = &deadline
+	}
+
+	stats := s.ConnStats()
+	status.Connections = statusConns{
+		New:      stats.New,
+		Active:   stats.Active,
+		Idle:     stats.Idle,
+		Hijacked: stats.Hijacked,
+	}
+
+	return status
+}
+
+// recordShutdown records the report of the shutdown started at the given time, once completed.
+func (s *GracefulServer) recordShutdown(started time.Time, err error) {
+	report := &shutdownReport{
+		Started:  started,
+		Duration: Duration(s.clock.Now().Sub(started)),
+		Forced:   s.forceClosed.Swap(false),
+	}
+
+	report.DrainedBy = "connections"
+	if report.Forced {
+		report.DrainedBy = "deadline"
+		s.forcedShutdowns.Add(1)
+	} else {
+		s.cleanShutdowns.Add(1)
+		s.emit(Event{Type: EventDrainCompleted})
+	}
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.lastShutdown = report
+	s.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package gracefulhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getStatus renders the status of the server.
+func getStatus(t *testing.T, s *GracefulServer) serverStatus {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	s.StatusHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/graceful", nil))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var status serverStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+
+	return status
+}
+
+func TestGracefulServer_StatusHandler(t *testing.T) {
+	s := Bind("localhost:0", nil)
+
+	assert.Equal(t, "starting", getStatus(t, s).State)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Minute), WithCloudflareTimeouts())
+	}()
+	<-s.Ready()
+
+	status := getStatus(t, s)
+	assert.Equal(t, "running", status.State)
+	assert.Equal(t, Duration(time.Minute), status.Timeouts.Shutdown)
+	assert.Equal(t, Duration(defaultIdleTimeout), status.Timeouts.Idle)
+	assert.Nil(t, status.Deadline)
+	assert.Nil(t, status.LastShutdown)
+
+	cancel()
+	require.NoError(t, <-errCh)
+
+	status = getStatus(t, s)
+	assert.Equal(t, "stopped", status.State)
+	assert.NotNil(t, status.Deadline)
+	require.NotNil(t, status.LastShutdown)
+	assert.False(t, status.LastShutdown.Forced)
+	assert.Equal(t, "connections", status.LastShutdown.DrainedBy)
+	assert.Empty(t, status.LastShutdown.Error)
+}
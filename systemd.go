@@ -0,0 +1,121 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// WithSystemdNotify reports the state of the server to systemd, for units of Type=notify:
+// READY=1 is sent once the listener is bound, and STOPPING=1 when the drain begins.
+// When the unit sets WatchdogSec, WATCHDOG=1 is sent at half of the watchdog timeout
+// while serving, provided that the check set by [WithSystemdWatchdogCheck] passes.
+// Nothing is sent unless the NOTIFY_SOCKET environment variable is set by systemd.
+func WithSystemdNotify() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.systemdNotify = true
+	}
+}
+
+// WithSystemdWatchdogCheck sets the check run before each ping of the systemd watchdog enabled by
+// [WithSystemdNotify]: the ping is skipped while the check fails, so that systemd restarts the server.
+// The check is given half of the watchdog timeout.
+func WithSystemdWatchdogCheck(check func(ctx context.Context) error) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.watchdogCheck = check
+	}
+}
+
+// initializeSystemd pings the systemd watchdog in the background, if enabled.
+func (s *GracefulServer) initializeSystemd() {
+	if !s.systemdNotify {
+		return
+	}
+
+	interval := watchdogInterval()
+	if interval <= 0 {
+		return
+	}
+
+	s.background = append(s.background, func(ctx context.Context) {
+		s.pingWatchdog(ctx, interval)
+	})
+}
+
+// pingWatchdog sends WATCHDOG=1 to systemd at every interval, as long as the check passes, until the context is done.
+func (s *GracefulServer) pingWatchdog(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(interval):
+			if s.watchdogHealthy(ctx, interval) {
+				s.notifySystemd("WATCHDOG=1")
+			}
+		}
+	}
+}
+
+// watchdogHealthy runs the watchdog check, if any, within the timeout.
+func (s *GracefulServer) watchdogHealthy(ctx context.Context, timeout time.Duration) bool {
+	if s.watchdogCheck == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return s.watchdogCheck(ctx) == nil
+}
+
+// watchdogInterval returns half of the watchdog timeout set by systemd in the WATCHDOG_USEC
+// environment variable, or zero if the watchdog is disabled or meant for another process.
+func watchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// notifySystemd sends the state to systemd, if enabled, logging the failures if possible.
+func (s *GracefulServer) notifySystemd(state string) {
+	if !s.systemdNotify {
+		return
+	}
+
+	if err := sdNotify(state); err != nil && s.logger != nil {
+		s.logger.Warn("gracefulhttp: systemd notification failed", "state", state, "error", err)
+	}
+}
+
+// sdNotify sends the state to the socket set by systemd in the NOTIFY_SOCKET environment variable,
+// as sd_notify(3) does. Nothing is sent if the variable is not set.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+
+	// A leading @ denotes a socket in the abstract namespace.
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+
+	return err
+}
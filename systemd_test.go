@@ -0,0 +1,119 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenNotifySocket listens on a notification socket set in the NOTIFY_SOCKET environment variable.
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "notify.sock"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", addr.Name)
+
+	return conn
+}
+
+// readNotification reads the next state sent to the notification socket.
+func readNotification(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	buf := make([]byte, 256)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestWithSystemdNotify(t *testing.T) {
+	conn := listenNotifySocket(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := Bind("localhost:0", nil)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ListenAndServeWithShutdown(ctx, WithSystemdNotify())
+	}()
+
+	assert.Equal(t, "READY=1", readNotification(t, conn))
+
+	cancel()
+	assert.Equal(t, "STOPPING=1", readNotification(t, conn))
+	assert.NoError(t, <-errCh)
+}
+
+func TestWithSystemdWatchdogCheck(t *testing.T) {
+	conn := listenNotifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "2000000")
+
+	healthy := make(chan error, 1)
+	clock := newManualClock()
+
+	s := Bind("", nil)
+	require.NoError(t, s.initialize([]GracefulServerOption{
+		WithSystemdNotify(),
+		WithSystemdWatchdogCheck(func(context.Context) error { return <-healthy }),
+		WithClock(clock),
+	}))
+	require.Len(t, s.background, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[0](ctx)
+
+	healthy <- errors.New("hung")
+	clock.fire()
+	healthy <- nil
+	clock.fire()
+
+	assert.Equal(t, "WATCHDOG=1", readNotification(t, conn))
+}
+
+func Test_watchdogInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{name: "disabled", want: 0},
+		{name: "half of the timeout", usec: "30000000", want: 15 * time.Second},
+		{name: "another process", usec: "30000000", pid: "1", want: 0},
+		{name: "invalid timeout", usec: "30s", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+
+			assert.Equal(t, tt.want, watchdogInterval())
+		})
+	}
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Run("no socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+
+		assert.NoError(t, sdNotify("READY=1"))
+	})
+
+	t.Run("missing socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+
+		assert.Error(t, sdNotify("READY=1"))
+	})
+}
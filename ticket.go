@@ -0,0 +1,111 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// ticketKeys encrypts the TLS session tickets with a key rotated on a schedule,
+// keeping the previous key valid for resumption.
+type ticketKeys struct {
+	mu   sync.RWMutex
+	keys []cipher.AEAD
+}
+
+// newTicketKeys returns a ticketKeys with a fresh random key.
+func newTicketKeys() (*ticketKeys, error) {
+	k := &ticketKeys{}
+	if err := k.rotate(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// rotate generates a new key, keeping the current one valid for resumption.
+func (k *ticketKeys) rotate() error {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = append([]cipher.AEAD{aead}, k.keys...)
+	if len(k.keys) > 2 {
+		k.keys = k.keys[:2]
+	}
+
+	return nil
+}
+
+// rotateEvery rotates the key at every interval, until the context is done.
+func (k *ticketKeys) rotateEvery(ctx context.Context, clock Clock, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+			_ = k.rotate()
+		}
+	}
+}
+
+// WrapSession encrypts the session state with the current key, as expected by [tls.Config.WrapSession].
+func (k *ticketKeys) WrapSession(_ tls.ConnectionState, ss *tls.SessionState) ([]byte, error) {
+	plaintext, err := ss.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	aead := k.keys[0]
+	k.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// UnwrapSession decrypts the session ticket with the current or the previous key, as expected
+// by [tls.Config.UnwrapSession]. Tickets that cannot be decrypted are ignored.
+func (k *ticketKeys) UnwrapSession(identity []byte, _ tls.ConnectionState) (*tls.SessionState, error) {
+	k.mu.RLock()
+	keys := k.keys
+	k.mu.RUnlock()
+
+	for _, aead := range keys {
+		if len(identity) < aead.NonceSize() {
+			continue
+		}
+
+		nonce, ciphertext := identity[:aead.NonceSize()], identity[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+
+		return tls.ParseSessionState(plaintext)
+	}
+
+	return nil, nil
+}
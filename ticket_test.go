@@ -0,0 +1,82 @@
+package gracefulhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSessionTicketRotation(t *testing.T) {
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithSessionTicketRotation(time.Hour)})
+
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+
+	assert.NotNil(t, s.TLSConfig.WrapSession)
+	assert.NotNil(t, s.TLSConfig.UnwrapSession)
+	assert.Len(t, s.background, 1)
+}
+
+func Test_ticketKeys(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	keys, err := newTicketKeys()
+	require.NoError(t, err)
+
+	ln, err := tls.Listen("tcp", "localhost:0", &tls.Config{
+		Certificates:  []tls.Certificate{cert},
+		WrapSession:   keys.WrapSession,
+		UnwrapSession: keys.UnwrapSession,
+	})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = c.(*tls.Conn).Handshake()
+			_ = c.Close()
+		}
+	}()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	clientConfig := &tls.Config{
+		RootCAs:            pool,
+		ServerName:         "localhost",
+		MaxVersion:         tls.VersionTLS12,
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+
+	resumed := func() bool {
+		c, err := tls.Dial("tcp", ln.Addr().String(), clientConfig)
+		require.NoError(t, err)
+		defer c.Close()
+
+		return c.ConnectionState().DidResume
+	}
+
+	assert.False(t, resumed())
+	assert.True(t, resumed())
+
+	// The previous key is still valid after a rotation.
+	require.NoError(t, keys.rotate())
+	assert.True(t, resumed())
+
+	// After two more rotations the cached ticket can no longer be decrypted.
+	require.NoError(t, keys.rotate())
+	require.NoError(t, keys.rotate())
+	assert.False(t, resumed())
+}
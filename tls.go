@@ -0,0 +1,168 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/fs"
+)
+
+// initializeTLS configures the certificates provided through the options. It returns the
+// certificate and key files left to be loaded by [http.Server.ServeTLS], which are empty
+// when they are taken over by the certificate reloader.
+func (s *GracefulServer) initializeTLS(certFile, keyFile string) (string, string, error) {
+	s.initializeCertManager()
+	s.initializeHTTPRedirect()
+
+	for _, load := range s.certLoaders {
+		cert, err := load()
+		if err != nil {
+			return "", "", err
+		}
+
+		cfg := s.ownTLSConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+
+	if len(s.sniCertPairs) > 0 {
+		certs, err := newSNICertificates(s.sniCertPairs)
+		if err != nil {
+			return "", "", err
+		}
+
+		cfg := s.ownTLSConfig()
+		certs.fallback = cfg.GetCertificate
+		cfg.GetCertificate = certs.GetCertificate
+	}
+
+	if s.ticketRotation > 0 {
+		keys, err := newTicketKeys()
+		if err != nil {
+			return "", "", err
+		}
+
+		cfg := s.ownTLSConfig()
+		cfg.WrapSession = keys.WrapSession
+		cfg.UnwrapSession = keys.UnwrapSession
+		s.background = append(s.background, func(ctx context.Context) {
+			keys.rotateEvery(ctx, s.clock, s.ticketRotation)
+		})
+	}
+
+	if len(s.crlSources) > 0 {
+		if err := s.initializeCRL(); err != nil {
+			return "", "", err
+		}
+	}
+
+	if s.clientCADir != "" {
+		// Set up after the CRL check, so that the revocation lists are checked on the chains it verifies.
+		if err := s.initializeClientCAs(); err != nil {
+			return "", "", err
+		}
+	}
+
+	reloadCertFile, reloadKeyFile := s.reloadCertFile, s.reloadKeyFile
+	if s.reloadSignals != nil && reloadCertFile == "" && reloadKeyFile == "" {
+		reloadCertFile, reloadKeyFile = certFile, keyFile
+		certFile, keyFile = "", ""
+	}
+
+	if len(s.services) > 0 && (certFile != "" || keyFile != "") {
+		// The services share the TLS configuration, so the certificate files
+		// are loaded here rather than by http.Server.ServeTLS.
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return "", "", err
+		}
+
+		cfg := s.ownTLSConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+		certFile, keyFile = "", ""
+	}
+
+	if reloadCertFile == "" && reloadKeyFile == "" {
+		return certFile, keyFile, nil
+	}
+
+	r, err := newCertReloader(reloadCertFile, reloadKeyFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.ownTLSConfig().GetCertificate = r.GetCertificate
+
+	if s.reloadCertFile != "" || s.reloadKeyFile != "" {
+		s.background = append(s.background, func(ctx context.Context) {
+			r.watch(ctx, s.clock, defaultCertReloadInterval)
+		})
+	}
+
+	if s.reloadSignals != nil {
+		s.background = append(s.background, func(ctx context.Context) {
+			r.watchSignals(ctx, s.reloadSignals)
+		})
+	}
+
+	return certFile, keyFile, nil
+}
+
+// initializeCRL rejects the revoked client certificates during the TLS handshake,
+// composing with the VerifyPeerCertificate callback of the configuration, if any.
+func (s *GracefulServer) initializeCRL() error {
+	c, err := newCRLChecker(context.Background(), s.clock, s.crlSources)
+	if err != nil {
+		return err
+	}
+
+	cfg := s.ownTLSConfig()
+	verify := cfg.VerifyPeerCertificate
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if err := c.VerifyPeerCertificate(rawCerts, verifiedChains); err != nil {
+			return err
+		}
+
+		if verify != nil {
+			return verify(rawCerts, verifiedChains)
+		}
+
+		return nil
+	}
+
+	if c.nextRefresh(s.crlRefresh) > 0 {
+		s.background = append(s.background, func(ctx context.Context) {
+			c.refresh(ctx, s.crlRefresh)
+		})
+	}
+
+	return nil
+}
+
+// ownTLSConfig returns the TLS configuration of the server, creating it if nil,
+// or cloning it the first time so that a configuration shared by the caller is never altered.
+func (s *GracefulServer) ownTLSConfig() *tls.Config {
+	if s.TLSConfig == nil {
+		s.TLSConfig = &tls.Config{}
+	} else if s.TLSConfig != s.ownedTLSConfig {
+		s.TLSConfig = s.TLSConfig.Clone()
+	}
+
+	s.ownedTLSConfig = s.TLSConfig
+
+	return s.TLSConfig
+}
+
+// loadKeyPairFS loads a PEM encoded certificate and private key from the file system.
+func loadKeyPairFS(fsys fs.FS, certPath, keyPath string) (tls.Certificate, error) {
+	certPEM, err := fs.ReadFile(fsys, certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err := fs.ReadFile(fsys, keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
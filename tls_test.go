@@ -0,0 +1,319 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateTestKeyPair returns a PEM encoded self-signed certificate and key for the host.
+func generateTestKeyPair(t *testing.T, host string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	certPEM, keyPEM, err := GenerateKeyPair(host)
+	require.NoError(t, err)
+
+	return certPEM, keyPEM
+}
+
+// writeTestKeyPair writes a new self-signed certificate and key for the host to the files.
+func writeTestKeyPair(t *testing.T, host, certFile, keyFile string) {
+	t.Helper()
+
+	certPEM, keyPEM := generateTestKeyPair(t, host)
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+}
+
+// testKeyPairFiles writes a new self-signed certificate and key for localhost to temporary files.
+func testKeyPairFiles(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestKeyPair(t, "localhost", certFile, keyFile)
+
+	return certFile, keyFile
+}
+
+func TestGracefulServer_ownTLSConfig(t *testing.T) {
+	t.Run("create a missing config", func(t *testing.T) {
+		s := Bind("", nil)
+
+		cfg := s.ownTLSConfig()
+		require.NotNil(t, cfg)
+		assert.Same(t, cfg, s.TLSConfig)
+		assert.Same(t, cfg, s.ownTLSConfig())
+	})
+
+	t.Run("clone a config set by the caller once", func(t *testing.T) {
+		shared := &tls.Config{MinVersion: tls.VersionTLS13}
+
+		s := Bind("", nil)
+		s.TLSConfig = shared
+
+		cfg := s.ownTLSConfig()
+		cfg.ServerName = "example.com"
+
+		assert.NotSame(t, shared, cfg)
+		assert.Empty(t, shared.ServerName)
+		assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+		assert.Same(t, cfg, s.ownTLSConfig())
+	})
+}
+
+func leafCommonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+func TestWithCertReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestKeyPair(t, "first.example.com", certFile, keyFile)
+
+	clock := newManualClock()
+
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithCertReload(certFile, keyFile), WithClock(clock)})
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+	require.Len(t, s.background, 1)
+
+	getCertificate := s.TLSConfig.GetCertificate
+	cert, err := getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "first.example.com", leafCommonName(t, cert))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[0](ctx)
+
+	writeTestKeyPair(t, "second.example.com", certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	clock.fire()
+
+	require.Eventually(t, func() bool {
+		cert, err := getCertificate(&tls.ClientHelloInfo{})
+		return err == nil && leafCommonName(t, cert) == "second.example.com"
+	}, time.Second, 10*time.Millisecond)
+
+	// A broken certificate is ignored.
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	clock.fire()
+	clock.fire()
+
+	cert, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "second.example.com", leafCommonName(t, cert))
+}
+
+// writeSecretVersion writes a key pair as a new version of a Kubernetes secret volume, atomically
+// swapping its ..data symlink as the kubelet does, with the files set to the given time.
+func writeSecretVersion(t *testing.T, dir, version, host string, modTime time.Time) {
+	t.Helper()
+
+	versionDir := filepath.Join(dir, version)
+	require.NoError(t, os.Mkdir(versionDir, 0o700))
+	writeTestKeyPair(t, host, filepath.Join(versionDir, "tls.crt"), filepath.Join(versionDir, "tls.key"))
+	require.NoError(t, os.Chtimes(filepath.Join(versionDir, "tls.crt"), modTime, modTime))
+	require.NoError(t, os.Chtimes(filepath.Join(versionDir, "tls.key"), modTime, modTime))
+
+	require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+}
+
+func TestWithCertReload_SecretVolume(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("secret volumes are not mounted on windows")
+	}
+
+	dir := t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeSecretVersion(t, dir, "..2024_01_01", "first.example.com", modTime)
+	require.NoError(t, os.Symlink(filepath.Join("..data", "tls.crt"), filepath.Join(dir, "tls.crt")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "tls.key"), filepath.Join(dir, "tls.key")))
+
+	clock := newManualClock()
+
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithCertReload(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")), WithClock(clock)})
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+
+	getCertificate := s.TLSConfig.GetCertificate
+	cert, err := getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "first.example.com", leafCommonName(t, cert))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[0](ctx)
+
+	// The new version has the same time as the previous one: only the swap tells them apart.
+	writeSecretVersion(t, dir, "..2024_02_01", "other.example.com", modTime)
+	clock.fire()
+
+	require.Eventually(t, func() bool {
+		cert, err := getCertificate(&tls.ClientHelloInfo{})
+		return err == nil && leafCommonName(t, cert) == "other.example.com"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWithCertReload_MissingFiles(t *testing.T) {
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithCertReload("missing.pem", "missing.key")})
+
+	_, _, err := s.initializeTLS("", "")
+	require.Error(t, err)
+}
+
+func TestWithCertReloadOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent on windows")
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestKeyPair(t, "first.example.com", certFile, keyFile)
+
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithCertReloadOnSignal()})
+
+	gotCertFile, gotKeyFile, err := s.initializeTLS(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Empty(t, gotCertFile)
+	assert.Empty(t, gotKeyFile)
+	require.Len(t, s.background, 1)
+
+	// Prevent the default action of SIGHUP until the reloader is notified.
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGHUP)
+	defer signal.Stop(ignored)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.background[0](ctx)
+
+	writeTestKeyPair(t, "second.example.com", certFile, keyFile)
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+
+	getCertificate := s.TLSConfig.GetCertificate
+	require.Eventually(t, func() bool {
+		require.NoError(t, p.Signal(syscall.SIGHUP))
+
+		cert, err := getCertificate(&tls.ClientHelloInfo{})
+		return err == nil && leafCommonName(t, cert) == "second.example.com"
+	}, time.Second, 50*time.Millisecond)
+}
+
+func TestWithCertificateFromFS(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "embedded.example.com")
+	fsys := fstest.MapFS{
+		"certs/cert.pem": &fstest.MapFile{Data: certPEM},
+		"certs/key.pem":  &fstest.MapFile{Data: keyPEM},
+	}
+
+	tests := []struct {
+		name     string
+		certPath string
+		keyPath  string
+		wantErr  bool
+	}{
+		{
+			name:     "load certificate",
+			certPath: "certs/cert.pem",
+			keyPath:  "certs/key.pem",
+		},
+		{
+			name:     "missing certificate",
+			certPath: "certs/missing.pem",
+			keyPath:  "certs/key.pem",
+			wantErr:  true,
+		},
+		{
+			name:     "missing key",
+			certPath: "certs/cert.pem",
+			keyPath:  "certs/missing.pem",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Bind("", nil)
+			s.initialize([]GracefulServerOption{WithCertificateFromFS(fsys, tt.certPath, tt.keyPath)})
+
+			_, _, err := s.initializeTLS("", "")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, s.TLSConfig.Certificates, 1)
+			assert.Equal(t, "embedded.example.com", leafCommonName(t, &s.TLSConfig.Certificates[0]))
+		})
+	}
+}
+
+func TestWithGetCertificate(t *testing.T) {
+	certPEM, keyPEM := generateTestKeyPair(t, "localhost")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	serverNames := make(chan string, 1)
+	s := Bind("localhost:0", &delayedHandler{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeTLSWithShutdown(ctx, "", "", WithGetCertificate(func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			serverNames <- hello.ServerName
+			return &cert, nil
+		}))
+	}()
+
+	<-s.Ready()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"},
+	}}
+
+	r, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	_ = r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "localhost", <-serverNames)
+
+	client.CloseIdleConnections()
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.ErrorIs(t, ValidateOptions(WithGetCertificate(nil)), ErrInvalidOption)
+}
@@ -0,0 +1,58 @@
+package gracefulhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WithTunnelTracking tracks the connections hijacked by the handlers, such as the CONNECT tunnels of
+// a forward proxy or the upgraded connections passed through by [httputil.ReverseProxy], e.g. WebSockets,
+// which [http.Server.Shutdown] neither waits for nor closes, as [GracefulServer.TrackHijacked] does, without
+// registering them one by one. Once the drain begins, the tunnels are given the linger time to complete, then
+// closed, or are closed only when the graceful timeout expires if the linger time is zero. The tunnels closed
+// beforehand are no longer tracked. The HTTP/2 tunnels, which are streams rather than hijacked connections,
+// are drained by [http.Server.Shutdown] already.
+func WithTunnelTracking(linger time.Duration) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if linger < 0 {
+			s.invalidOption("WithTunnelTracking", "negative linger time %v", linger)
+			return
+		}
+
+		s.trackTunnels = true
+		s.tunnelLinger = linger
+	}
+}
+
+// tunnelTrackingHandler tracks the connections hijacked by the handler.
+func (s *GracefulServer) tunnelTrackingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&responseWriter{
+			ResponseWriter: w,
+			afterHijack:    s.trackTunnel,
+		}, r)
+	})
+}
+
+// trackTunnel registers the hijacked connection, closed once the linger time has elapsed since the drain
+// began, if any, and untracks it once closed.
+func (s *GracefulServer) trackTunnel(conn net.Conn) net.Conn {
+	var closeFn func(ctx context.Context) error
+	if linger := s.tunnelLinger; linger > 0 {
+		closeFn = func(ctx context.Context) error {
+			select {
+			case <-s.clock.After(linger):
+			case <-ctx.Done():
+			}
+
+			return nil
+		}
+	}
+
+	c := &untrackingConn{Conn: conn}
+	c.untrack = s.TrackHijacked(conn, closeFn)
+
+	return c
+}
@@ -0,0 +1,107 @@
+package gracefulhttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectHandler echoes the data sent through the CONNECT tunnels.
+func connectHandler(w http.ResponseWriter, r *http.Request) {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	_, _ = rw.WriteString("HTTP/1.1 200 Connection Established\r\n\r\n")
+	_ = rw.Flush()
+	_, _ = io.Copy(conn, rw)
+}
+
+// openTunnel opens a CONNECT tunnel through the server.
+func openTunnel(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	_, err = io.WriteString(conn, "CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n")
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	r, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, r.StatusCode)
+
+	return conn, br
+}
+
+func TestWithTunnelTracking(t *testing.T) {
+	t.Run("close the tunnels once the linger time elapsed", func(t *testing.T) {
+		s := Bind("localhost:0", http.HandlerFunc(connectHandler))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithShutdownTimeout(time.Minute), WithTunnelTracking(50*time.Millisecond))
+		}()
+		<-s.Ready()
+
+		conn, br := openTunnel(t, s.ListenerAddr().String())
+		_, err := io.WriteString(conn, "ping\n")
+		require.NoError(t, err)
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "ping\n", line)
+		assert.Len(t, s.trackedHijacked(), 1)
+
+		start := time.Now()
+		cancel()
+
+		_, err = br.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		require.NoError(t, <-done)
+		assert.Zero(t, s.Stats().ForcedShutdowns)
+		assert.Empty(t, s.trackedHijacked())
+	})
+
+	t.Run("untrack the tunnels closed beforehand", func(t *testing.T) {
+		s := Bind("localhost:0", http.HandlerFunc(connectHandler))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithTunnelTracking(0))
+		}()
+		<-s.Ready()
+
+		conn, _ := openTunnel(t, s.ListenerAddr().String())
+		assert.Len(t, s.trackedHijacked(), 1)
+		require.NoError(t, conn.Close())
+
+		assert.Eventually(t, func() bool {
+			return len(s.trackedHijacked()) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+
+		require.NoError(t, <-done)
+	})
+
+	assert.ErrorIs(t, ValidateOptions(WithTunnelTracking(-time.Second)), ErrInvalidOption)
+}
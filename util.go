@@ -0,0 +1,15 @@
+package gracefulhttp
+
+import (
+	"net"
+)
+
+// stripPort removes the port, if any, from the host.
+func stripPort(host string) string {
+	h, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return host
+	}
+
+	return h
+}
@@ -0,0 +1,107 @@
+package gracefulhttp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidOption is wrapped by the errors reporting an option given an invalid value,
+// such as a negative timeout, which is otherwise replaced with a default or ignored.
+var ErrInvalidOption = errors.New("gracefulhttp: invalid option")
+
+// ErrConflictingOptions is wrapped by the errors reporting an option discarding the settings
+// of a previous one, e.g. [WithTLSConfig] after [WithCloudflareTLSConfig], as the last one wins.
+var ErrConflictingOptions = errors.New("gracefulhttp: conflicting options")
+
+// WithStrictOptions reports the options given an invalid value as an error wrapping [ErrInvalidOption]
+// when serving, instead of replacing the value with a default, and the conflicting ones as an error
+// wrapping [ErrConflictingOptions]. It applies to all the options, regardless of their order.
+func WithStrictOptions() GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.strictOptions = true
+	}
+}
+
+// WithOnConfigWarning sets a callback invoked when serving with the error of each option given an invalid value,
+// wrapping [ErrInvalidOption], and of each conflicting option, wrapping [ErrConflictingOptions], so that
+// a typo like a negative timeout does not go unnoticed while the server keeps serving with the default value.
+// The callback is not invoked in strict mode, where these errors are returned instead.
+func WithOnConfigWarning(fn func(err error)) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.configWarning = fn
+	}
+}
+
+// ValidateOptions reports the options given an invalid value and the conflicting ones, as [WithStrictOptions]
+// would when serving, and the errors of the options that cannot be applied, such as those of [FromEnv].
+func ValidateOptions(opts ...GracefulServerOption) error {
+	s := Bind("", nil)
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return errors.Join(slices.Concat(s.optionErrs, s.invalidOpts, s.conflicts)...)
+}
+
+// invalidOption records that an option was given an invalid value.
+func (s *GracefulServer) invalidOption(option, format string, args ...any) {
+	s.invalidOpts = append(s.invalidOpts, fmt.Errorf("%w: %s: %s", ErrInvalidOption, option, fmt.Sprintf(format, args...)))
+}
+
+// optionsError returns the errors of the options that cannot be applied and, in strict mode,
+// those of the options given an invalid value or conflicting. Otherwise, the latter are reported
+// as warnings to the callback and logged, if possible.
+func (s *GracefulServer) optionsError() error {
+	if s.strictOptions {
+		return errors.Join(slices.Concat(s.optionErrs, s.invalidOpts, s.conflicts)...)
+	}
+
+	for _, err := range slices.Concat(s.invalidOpts, s.conflicts) {
+		if s.configWarning != nil {
+			s.configWarning(err)
+		}
+
+		if s.logger != nil {
+			s.logger.Warn(err.Error())
+		}
+	}
+
+	return errors.Join(s.optionErrs...)
+}
+
+// claimSetting records that an option sets a group of settings (e.g. "tls.params"),
+// reporting a conflict if another option set them before.
+func (s *GracefulServer) claimSetting(setting, option string) {
+	if prev, ok := s.claims[setting]; ok && prev != option {
+		s.conflicts = append(s.conflicts, fmt.Errorf("%w: %s overrides the %s settings of %s", ErrConflictingOptions, option, setting, prev))
+	}
+
+	s.overrideSetting(setting, option)
+}
+
+// overrideSetting records that an option sets a group of settings, overriding on purpose
+// those set by another option before.
+func (s *GracefulServer) overrideSetting(setting, option string) {
+	if s.claims == nil {
+		s.claims = make(map[string]string)
+	}
+
+	s.claims[setting] = option
+}
+
+// replaceSettings records that an option replaces all the settings of a group (e.g. "tls"),
+// reporting a conflict for each setting of the group set by another option before.
+func (s *GracefulServer) replaceSettings(group, option string) {
+	for _, setting := range slices.Sorted(maps.Keys(s.claims)) {
+		prev := s.claims[setting]
+		if !strings.HasPrefix(setting, group+".") || prev == option {
+			continue
+		}
+
+		s.conflicts = append(s.conflicts, fmt.Errorf("%w: %s discards the %s settings of %s", ErrConflictingOptions, option, setting, prev))
+		delete(s.claims, setting)
+	}
+}
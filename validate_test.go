@@ -0,0 +1,177 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []GracefulServerOption
+		wantErr string
+	}{
+		{
+			name: "valid options",
+			opts: []GracefulServerOption{WithShutdownTimeout(time.Second), WithCloudflareTLSConfig()},
+		},
+		{
+			name:    "negative timeout",
+			opts:    []GracefulServerOption{WithShutdownTimeout(-time.Second)},
+			wantErr: "WithShutdownTimeout: negative duration -1s",
+		},
+		{
+			name:    "nil tls config",
+			opts:    []GracefulServerOption{WithTLSConfig(nil)},
+			wantErr: "WithTLSConfig: nil configuration",
+		},
+		{
+			name:    "unknown tls profile",
+			opts:    []GracefulServerOption{WithMozillaTLSProfile(MozillaTLSProfile(42))},
+			wantErr: "WithMozillaTLSProfile: unknown profile 42",
+		},
+		{
+			name:    "negative limit",
+			opts:    []GracefulServerOption{WithMaxInFlightRequests(-1, 0)},
+			wantErr: "WithMaxInFlightRequests",
+		},
+		{
+			name:    "non-positive crl refresh",
+			opts:    []GracefulServerOption{WithClientCRL(0, "crl.pem")},
+			wantErr: "WithClientCRL: non-positive refresh 0s",
+		},
+		{
+			name:    "no crl sources",
+			opts:    []GracefulServerOption{WithClientCRL(time.Hour)},
+			wantErr: "WithClientCRL: no sources",
+		},
+		{
+			name:    "no certificates",
+			opts:    []GracefulServerOption{WithCertificates(nil)},
+			wantErr: "WithCertificates: no certificates",
+		},
+		{
+			name:    "empty key file",
+			opts:    []GracefulServerOption{WithCertificates(map[string]CertPair{"example.com": {CertFile: "cert.pem"}})},
+			wantErr: `WithCertificates: empty certificate or key file for host "example.com"`,
+		},
+		{
+			name:    "non-positive ticket rotation",
+			opts:    []GracefulServerOption{WithSessionTicketRotation(0)},
+			wantErr: "WithSessionTicketRotation: non-positive interval 0s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOptions(tt.opts...)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidOption)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWithStrictOptions(t *testing.T) {
+	t.Run("replace invalid values by default", func(t *testing.T) {
+		s := Bind("", nil)
+		require.NoError(t, s.initialize([]GracefulServerOption{WithShutdownTimeout(-time.Second)}))
+
+		assert.Equal(t, defaultGracefulTimeout, s.gracefulTimeout)
+	})
+
+	t.Run("warn about invalid values", func(t *testing.T) {
+		var warnings []error
+		s := Bind("", nil)
+		require.NoError(t, s.initialize([]GracefulServerOption{
+			WithShutdownTimeout(-5 * time.Second),
+			WithOnConfigWarning(func(err error) {
+				warnings = append(warnings, err)
+			}),
+		}))
+
+		require.Len(t, warnings, 1)
+		assert.ErrorIs(t, warnings[0], ErrInvalidOption)
+		assert.ErrorContains(t, warnings[0], "WithShutdownTimeout")
+	})
+
+	t.Run("report invalid values", func(t *testing.T) {
+		s := Bind("localhost:0", nil)
+		err := s.ListenAndServeWithShutdown(context.Background(), WithShutdownTimeout(-time.Second), WithStrictOptions())
+
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+}
+
+func TestValidateOptions_Conflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []GracefulServerOption
+		wantErr string
+	}{
+		{
+			name:    "tls config after hardening",
+			opts:    []GracefulServerOption{WithCloudflareTLSConfig(), WithTLSConfig(&tls.Config{})},
+			wantErr: "WithTLSConfig discards the tls.params settings of WithCloudflareTLSConfig",
+		},
+		{
+			name: "hardening after tls config",
+			opts: []GracefulServerOption{WithTLSConfig(&tls.Config{}), WithCloudflareTLSConfig()},
+		},
+		{
+			name:    "two tls profiles",
+			opts:    []GracefulServerOption{WithCloudflareTLSConfig(), WithMozillaTLSProfile(MozillaModern)},
+			wantErr: "WithMozillaTLSProfile overrides the tls.params settings of WithCloudflareTLSConfig",
+		},
+		{
+			name:    "listen config after keep-alive",
+			opts:    []GracefulServerOption{WithTCPKeepAlive(time.Minute, true), WithListenConfig(net.ListenConfig{})},
+			wantErr: "WithListenConfig discards the listen.keepalive settings of WithTCPKeepAlive",
+		},
+		{
+			name: "same option twice",
+			opts: []GracefulServerOption{WithCloudflareTLSConfig(), WithCloudflareTLSConfig()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOptions(tt.opts...)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrConflictingOptions)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGracefulServer_ConflictingOptions(t *testing.T) {
+	opts := []GracefulServerOption{WithCloudflareTLSConfig(), WithTLSConfig(&tls.Config{})}
+
+	t.Run("log the conflicts by default", func(t *testing.T) {
+		var buf lockedBuffer
+		s := Bind("", nil)
+		require.NoError(t, s.initialize(append(opts, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))))
+
+		assert.Contains(t, buf.String(), "WithTLSConfig discards the tls.params settings")
+	})
+
+	t.Run("report the conflicts in strict mode", func(t *testing.T) {
+		s := Bind("", nil)
+		err := s.initialize(append(opts, WithStrictOptions()))
+
+		assert.ErrorIs(t, err, ErrConflictingOptions)
+	})
+}
@@ -0,0 +1,62 @@
+package gracefulhttp
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// HostConfig configures a virtual host served through [WithVirtualHosts].
+type HostConfig struct {
+	// Handler serves the requests of the host, the handler of the server if nil.
+	Handler http.Handler
+	// Cert is the certificate of the host. If empty, the certificate is obtained through the [CertManager],
+	// e.g. via ACME by [GracefulServer.ListenAndServeAutocertWithShutdown], which accepts the host.
+	Cert CertPair
+}
+
+// WithVirtualHosts serves several hosts, which may be wildcards (e.g. "*.example.com"), each one with its own
+// handler and certificate, as [WithHostHandlers] and [WithCertificates] do together, so that a small
+// multi-tenant deployment is set up in one place. The hosts without a certificate are added to the host
+// policy of [GracefulServer.ListenAndServeAutocertWithShutdown], obtaining theirs via ACME, except for the
+// wildcards, which require a [CertManager] solving the DNS-01 challenges, such as a CertMagic configuration.
+func WithVirtualHosts(hosts map[string]HostConfig) GracefulServerOption {
+	return func(s *GracefulServer) {
+		handlers := maps.Clone(s.hostHandlers)
+		if handlers == nil {
+			handlers = make(map[string]http.Handler, len(hosts))
+		}
+
+		pairs := maps.Clone(s.sniCertPairs)
+		if pairs == nil {
+			pairs = make(map[string]CertPair, len(hosts))
+		}
+
+		for host, cfg := range hosts {
+			host = strings.ToLower(host)
+			if cfg.Handler != nil {
+				handlers[host] = cfg.Handler
+			}
+
+			if cfg.Cert != (CertPair{}) {
+				pairs[host] = cfg.Cert
+			} else if !strings.HasPrefix(host, "*.") {
+				s.acmeHosts = append(s.acmeHosts, host)
+			}
+		}
+
+		s.hostHandlers = handlers
+		s.sniCertPairs = pairs
+	}
+}
+
+// acmeHostPolicy accepts the hosts and the virtual hosts whose certificate is obtained via ACME.
+func (s *GracefulServer) acmeHostPolicy(hosts []string) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		return autocert.HostWhitelist(slices.Concat(hosts, s.acmeHosts)...)(ctx, host)
+	}
+}
@@ -0,0 +1,75 @@
+package gracefulhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithVirtualHosts(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		})
+	}
+
+	dir := t.TempDir()
+	pair := CertPair{
+		CertFile: filepath.Join(dir, "a.crt"),
+		KeyFile:  filepath.Join(dir, "a.key"),
+	}
+	writeTestKeyPair(t, "a.example.com", pair.CertFile, pair.KeyFile)
+
+	fallbackPEM, fallbackKey := generateTestKeyPair(t, "acme")
+
+	s := Bind("", named("default"))
+	require.NoError(t, s.initialize([]GracefulServerOption{
+		WithVirtualHosts(map[string]HostConfig{
+			"A.example.com": {Handler: named("a"), Cert: pair},
+			"b.example.com": {Handler: named("b")},
+			"*.example.org": {Handler: named("org")},
+		}),
+		WithCertificateManager(&staticCertManager{certPEM: fallbackPEM, keyPEM: fallbackKey}),
+		WithACMEChallengeAddr(""),
+	}))
+
+	_, _, err := s.initializeTLS("", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		host     string
+		want     string
+		wantCert string
+	}{
+		{host: "a.example.com", want: "a", wantCert: "a.example.com"},
+		{host: "b.example.com", want: "b", wantCert: "acme"},
+		{host: "www.example.org", want: "org", wantCert: "acme"},
+		{host: "c.example.com", want: "default", wantCert: "acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Body.String())
+
+			cert, err := s.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.host})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCert, leafCommonName(t, cert))
+		})
+	}
+
+	policy := s.acmeHostPolicy([]string{"c.example.com"})
+	assert.NoError(t, policy(context.Background(), "b.example.com"))
+	assert.NoError(t, policy(context.Background(), "c.example.com"))
+	assert.Error(t, policy(context.Background(), "a.example.com"))
+	assert.Error(t, policy(context.Background(), "www.example.org"))
+}
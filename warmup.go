@@ -0,0 +1,26 @@
+package gracefulhttp
+
+import (
+	"context"
+)
+
+// WithWarmup adds a hook run before the listeners are created, e.g. to prime the caches or establish
+// the connection pools, so that no traffic is accepted before the server is warm. The context is the
+// serving one. If the hook fails, the serving method returns its error without ever listening.
+// The hooks run in the order they were added.
+func WithWarmup(fn func(ctx context.Context) error) GracefulServerOption {
+	return func(s *GracefulServer) {
+		s.warmups = append(s.warmups, fn)
+	}
+}
+
+// warmUp runs the warmup hooks, stopping at the first failure.
+func (s *GracefulServer) warmUp(ctx context.Context) error {
+	for _, fn := range s.warmups {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithWarmup(t *testing.T) {
+	t.Run("warm up before listening", func(t *testing.T) {
+		s := Bind("localhost:0", nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx, WithWarmup(func(context.Context) error {
+				assert.Nil(t, s.ListenerAddr())
+				return nil
+			}))
+		}()
+
+		<-s.Ready()
+		cancel()
+
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("never listen if the warmup fails", func(t *testing.T) {
+		warmupErr := errors.New("cache unavailable")
+		s := Bind("localhost:0", nil)
+
+		err := s.ListenAndServeWithShutdown(context.Background(),
+			WithWarmup(func(context.Context) error { return warmupErr }),
+			WithWarmup(func(context.Context) error {
+				t.Error("warmup run after a failure")
+				return nil
+			}),
+		)
+
+		assert.ErrorIs(t, err, warmupErr)
+		assert.Nil(t, s.ListenerAddr())
+	})
+}
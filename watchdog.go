@@ -0,0 +1,60 @@
+package gracefulhttp
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// WithExitWatchdog sets a last resort against a forced close which never completes, e.g. blocked by
+// a wedged listener or a hook: if the connections are not closed, and the hooks not run, within the budget
+// once the forced close begins, fn is invoked. A nil fn logs the failure and exits the process with status 1,
+// so that its termination is never blocked indefinitely. A non-positive budget disables the watchdog.
+func WithExitWatchdog(budget time.Duration, fn func()) GracefulServerOption {
+	return func(s *GracefulServer) {
+		if budget < 0 {
+			s.invalidOption("WithExitWatchdog", "negative budget %v", budget)
+		}
+
+		s.exitBudget = max(budget, 0)
+		s.onExitWatchdog = fn
+	}
+}
+
+// watchForceClose runs the forced close, invoking the watchdog callback if it does not
+// complete within the budget, if any.
+func (s *GracefulServer) watchForceClose(forceClose func() error) error {
+	if s.exitBudget <= 0 {
+		return forceClose()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+		case <-s.clock.After(s.exitBudget):
+			s.exitWatchdog()
+		}
+	}()
+
+	return forceClose()
+}
+
+// exitWatchdog invokes the watchdog callback, or logs the failure and exits the process if not set.
+func (s *GracefulServer) exitWatchdog() {
+	if s.onExitWatchdog != nil {
+		s.onExitWatchdog()
+		return
+	}
+
+	const msg = "gracefulhttp: forced close not completed, exiting"
+	if s.logger != nil {
+		s.logger.Error(msg, "budget", s.exitBudget)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s after %v\n", msg, s.exitBudget)
+	}
+
+	os.Exit(1)
+}
@@ -0,0 +1,36 @@
+package gracefulhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExitWatchdog(t *testing.T) {
+	fired := make(chan struct{})
+	s := Bind("localhost:0", nil)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx,
+			WithExitWatchdog(10*time.Millisecond, func() { close(fired) }),
+			// A hook wedged until the watchdog fires.
+			WithOnForceClose(func([]ConnInfo) { <-fired }),
+		)
+	}()
+
+	<-s.Ready()
+	cancel(ErrImmediateStop)
+
+	assert.NoError(t, <-done)
+}
+
+func TestGracefulServer_watchForceClose(t *testing.T) {
+	s := Bind("", nil)
+	s.initialize([]GracefulServerOption{WithExitWatchdog(time.Hour, func() { t.Error("watchdog fired") })})
+
+	assert.NoError(t, s.watchForceClose(func() error { return nil }))
+}
@@ -0,0 +1,160 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWorkerFailed is wrapped by the error of a worker registered through [GracefulServer.RegisterWorker]
+	// failing before the drain begins, which shuts the server down.
+	ErrWorkerFailed = errors.New("gracefulhttp: worker failed")
+	// ErrWorkerTimeout is reported when a worker has not returned within its timeout once canceled.
+	ErrWorkerTimeout = errors.New("gracefulhttp: worker stop timeout expired")
+)
+
+// worker is a background worker registered through [GracefulServer.RegisterWorker].
+type worker struct {
+	name    string
+	timeout time.Duration
+	fn      func(ctx context.Context) error
+
+	// done is closed once the worker of the current run has returned, if started.
+	done chan struct{}
+	err  error
+}
+
+// RegisterWorker registers a background worker sharing the lifecycle of the server, such as a periodic job or
+// a message consumer: it is started once the server is ready, and its context is canceled as soon as the drain
+// begins. The cleanup hooks run only once the workers have returned, each one waited for at most its timeout,
+// if positive, after the cancellation. A worker failing before the drain shuts the server down, reporting
+// an error wrapping [ErrWorkerFailed]. The failures are reported by the serving method, including the errors other
+// than [context.Canceled] returned once canceled, and the workers not returning in time, with [ErrWorkerTimeout].
+func (s *GracefulServer) RegisterWorker(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.workers = append(s.workers, &worker{name: name, timeout: timeout, fn: fn})
+}
+
+// initializeWorkers starts the workers in the background, if any.
+func (s *GracefulServer) initializeWorkers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.workers) == 0 {
+		return
+	}
+
+	for _, w := range s.workers {
+		w.done = nil
+	}
+
+	s.background = append(s.background, s.runWorkers)
+}
+
+// runWorkers starts the workers once the server is ready, unless the drain has begun already,
+// and cancels them as soon as the drain begins.
+func (s *GracefulServer) runWorkers(ctx context.Context) {
+	select {
+	case <-s.Ready():
+	case <-ctx.Done():
+		return
+	}
+
+	workerCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	// The workers are started under the lock, unless the drain has begun, so that stopWorkers,
+	// which runs once it has, waits exactly for the ones started.
+	s.mu.Lock()
+	if !isClosed(s.drain) {
+		for _, w := range s.workers {
+			w.done = make(chan struct{})
+			go s.runWorker(workerCtx, w)
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.Draining():
+	case <-ctx.Done():
+	}
+}
+
+// runWorker runs the worker, shutting the server down if it fails before being canceled.
+func (s *GracefulServer) runWorker(ctx context.Context, w *worker) {
+	defer close(w.done)
+
+	err := w.fn(ctx)
+	if err == nil || (ctx.Err() != nil && errors.Is(err, context.Canceled)) {
+		return
+	}
+
+	if ctx.Err() != nil {
+		w.err = fmt.Errorf("gracefulhttp: worker %s: %w", w.name, err)
+		s.reportError(PhaseServe, w.err)
+		return
+	}
+
+	w.err = fmt.Errorf("%w: %s: %w", ErrWorkerFailed, w.name, err)
+	s.reportError(PhaseServe, w.err)
+	s.cancelServing(ErrWorkerFailed)
+}
+
+// isClosed reports whether the channel, if any, is closed.
+func isClosed(ch chan struct{}) bool {
+	if ch == nil {
+		return false
+	}
+
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopWorkers waits for the started workers, canceled once the drain began, each one within its timeout,
+// returning their failures.
+func (s *GracefulServer) stopWorkers() error {
+	s.mu.Lock()
+	var started []*worker
+	for _, w := range s.workers {
+		if w.done != nil {
+			started = append(started, w)
+		}
+	}
+	s.mu.Unlock()
+
+	errs := make([]error, len(started))
+
+	var wg sync.WaitGroup
+	for i, w := range started {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var timeout <-chan time.Time
+			if w.timeout > 0 {
+				timeout = s.clock.After(w.timeout)
+			}
+
+			select {
+			case <-w.done:
+				errs[i] = phaseError(PhaseServe, w.err)
+			case <-timeout:
+				errs[i] = fmt.Errorf("%w: %s", ErrWorkerTimeout, w.name)
+				s.reportError(PhaseDrain, errs[i])
+				errs[i] = phaseError(PhaseDrain, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package gracefulhttp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulServer_RegisterWorker(t *testing.T) {
+	t.Run("run between the readiness and the cleanup", func(t *testing.T) {
+		s := Bind("localhost:0", nil)
+
+		var mu sync.Mutex
+		var steps []string
+		record := func(step string) {
+			mu.Lock()
+			defer mu.Unlock()
+			steps = append(steps, step)
+		}
+
+		started := make(chan struct{})
+		s.RegisterWorker("consumer", time.Minute, func(ctx context.Context) error {
+			select {
+			case <-s.Ready():
+				record("ready")
+			default:
+				record("not ready")
+			}
+			close(started)
+
+			<-ctx.Done()
+			assert.True(t, s.isDraining())
+			time.Sleep(50 * time.Millisecond)
+			record("worker stopped")
+
+			return ctx.Err()
+		})
+		s.RegisterCleanup("db", 0, func(context.Context) error {
+			record("cleanup")
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx)
+		}()
+
+		<-started
+		cancel()
+
+		require.NoError(t, <-done)
+		assert.Equal(t, []string{"ready", "worker stopped", "cleanup"}, steps)
+	})
+
+	t.Run("shut down when a worker fails", func(t *testing.T) {
+		workerErr := errors.New("consumer lost")
+
+		s := Bind("localhost:0", nil)
+		s.RegisterWorker("consumer", 0, func(context.Context) error {
+			return workerErr
+		})
+
+		err := s.ListenAndServeWithShutdown(context.Background())
+		assert.ErrorIs(t, err, workerErr)
+		assert.ErrorIs(t, err, ErrWorkerFailed)
+
+		var gerr *GracefulError
+		require.ErrorAs(t, err, &gerr)
+		assert.Equal(t, PhaseServe, gerr.Phase)
+	})
+
+	t.Run("report the workers not stopping in time", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		started := make(chan struct{})
+		s := Bind("localhost:0", nil)
+		s.RegisterWorker("stuck", 50*time.Millisecond, func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServeWithShutdown(ctx)
+		}()
+
+		<-started
+		cancel()
+
+		err := <-done
+		assert.ErrorIs(t, err, ErrWorkerTimeout)
+		assert.ErrorContains(t, err, "stuck")
+	})
+}